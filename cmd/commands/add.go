@@ -5,6 +5,7 @@ package commands
 
 import (
 	"path/filepath"
+	"strings"
 
 	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
 	"github.com/open-cmsis-pack/cpackget/cmd/installer"
@@ -37,10 +38,16 @@ var addCmdFlags struct {
 
 	// insecureSkipVerify skips TLS certificate verification for HTTPS downloads
 	insecureSkipVerify bool
+
+	// dryRun prints what would be done without changing the pack root
+	dryRun bool
+
+	// lockFileName is a lock file written by "cpackget lock", whose packs get installed
+	lockFileName string
 }
 
 var AddCmd = &cobra.Command{
-	Use:   "add [<pack> | -f <packs list>]",
+	Use:   "add [<pack> | -f <packs list> | --locked <lock file>]",
 	Short: "Add Open-CMSIS-Pack packages",
 	Long: `
 Add a pack using the following "<pack>" specification or using packs provided by "-f <packs list>":
@@ -68,24 +75,45 @@ Add a pack using the following "<pack>" specification or using packs provided by
 
   The file can be a local file or a file hosted somewhere else on the Internet.
   If it's hosted somewhere, cpackget will first download it then extract all pack files into "CMSIS_PACK_ROOT/<vendor>/<packName>/<x.y.z>/"
-  If "-f" is used, cpackget will call "cpackget pack add" on each URL specified in the <packs list> file.`,
+  If "-f" is used, cpackget will call "cpackget pack add" on each URL specified in the <packs list> file.
+
+  Use "--dry-run" to print the packs that would be downloaded and extracted, the licenses that
+  would be prompted and the requirements that would be installed, without changing the pack root.
+
+  $ cpackget add --locked cpackget.lock.yaml
+
+  Use this syntax to install the exact pack versions listed in a lock file written by "cpackget lock".
+  The pack files are downloaded from the locked URLs and the installation fails if the SHA-256
+  digest of any of them differs from the locked one.`,
 	Args: cobra.MinimumNArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		utils.SetEncodedProgress(addCmdFlags.encodedProgress)
 		utils.SetSkipTouch(addCmdFlags.skipTouch)
 
-		createPackRoot = true
+		createPackRoot = !addCmdFlags.dryRun
 		err := configureInstaller(cmd, args)
 		if err != nil {
 			return err
 		}
 
+		if addCmdFlags.lockFileName != "" {
+			if len(args) > 0 || addCmdFlags.packsListFileName != "" || addCmdFlags.dryRun {
+				log.Error("\"--locked\" cannot be combined with packs to add, \"-f\" or \"--dry-run\"")
+				return errs.ErrIncorrectCmdArgs
+			}
+			installer.UnlockPackRoot()
+			err := installer.AddLockedPacks(addCmdFlags.lockFileName, !addCmdFlags.skipEula, addCmdFlags.insecureSkipVerify, false, viper.GetInt("timeout"))
+			installer.LockPackRoot()
+			return err
+		}
+
 		files, err := utils.GetListFiles(addCmdFlags.packsListFileName)
 		if err != nil {
 			return err
 		}
 		args = append(args, files...)
+		stripCredentials(args)
 
 		if len(args) == 0 {
 			log.Warn("Missing a pack-path or list with pack urls specified via -f/--packs-list-filename")
@@ -100,7 +128,39 @@ Add a pack using the following "<pack>" specification or using packs provided by
 		var lastErr error
 
 		log.Debugf("Specified packs %v", args)
+		if addCmdFlags.dryRun {
+			plan := installer.NewPlan()
+			for _, packPath := range args {
+				var err error
+				if filepath.Ext(packPath) == utils.PdscExtension {
+					err = installer.PlanAddPdsc(plan, packPath)
+				} else {
+					err = installer.PlanAddPack(plan, packPath, !addCmdFlags.skipEula, addCmdFlags.extractEula, addCmdFlags.forceReinstall, addCmdFlags.noRequirements, addCmdFlags.insecureSkipVerify, false)
+				}
+				if err != nil {
+					lastErr = err
+					if !errs.AlreadyLogged(err) {
+						log.Error(err)
+					}
+				}
+			}
+			plan.Log()
+			return lastErr
+		}
+
 		installer.UnlockPackRoot()
+		defer installer.LockPackRoot()
+
+		// All packs get added, or none of them
+		if err := installer.BeginTransaction("add " + strings.Join(args, " ")); err != nil {
+			return err
+		}
+		if !addCmdFlags.extractEula {
+			err := installer.PrefetchPacks(args, false, addCmdFlags.forceReinstall, addCmdFlags.insecureSkipVerify, false, viper.GetInt("concurrent-downloads"), viper.GetInt("timeout"))
+			if err != nil {
+				return installer.EndTransaction(err)
+			}
+		}
 		for _, packPath := range args {
 			var err error
 			if filepath.Ext(packPath) == utils.PdscExtension {
@@ -113,10 +173,10 @@ Add a pack using the following "<pack>" specification or using packs provided by
 				if !errs.AlreadyLogged(err) {
 					log.Error(err)
 				}
+				break
 			}
 		}
-		installer.LockPackRoot()
-		return lastErr
+		return installer.EndTransaction(lastErr)
 	},
 }
 
@@ -128,13 +188,7 @@ func init() {
 	AddCmd.Flags().StringVarP(&addCmdFlags.packsListFileName, "packs-list-filename", "f", "", "specifies a file listing packs urls, one per line")
 	AddCmd.Flags().BoolVar(&addCmdFlags.skipTouch, "skip-touch", false, "do not touch pack.idx")
 	AddCmd.Flags().BoolVarP(&addCmdFlags.encodedProgress, "encoded-progress", "E", false, "Reports encoded progress for files and download when used by other tools")
+	AddCmd.Flags().BoolVar(&addCmdFlags.dryRun, "dry-run", false, "prints what would be done without changing the pack root")
+	AddCmd.Flags().StringVar(&addCmdFlags.lockFileName, "locked", "", "installs the exact pack versions listed in a lock file")
 	AddCmd.Flags().BoolVar(&addCmdFlags.insecureSkipVerify, "insecure-skip-verify", false, "skip verification of server's TLS certificate when downloading packs over HTTPS")
-
-	AddCmd.SetHelpFunc(func(command *cobra.Command, strings []string) {
-		// Small workaround to keep the linter happy, not
-		// really necessary to test this
-		err := command.Flags().MarkHidden("concurrent-downloads")
-		log.Debug(err)
-		command.Parent().HelpFunc()(command, strings)
-	})
 }
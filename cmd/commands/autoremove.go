@@ -0,0 +1,53 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands
+
+import (
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var autoremoveCmdFlags struct {
+	// purge stores the value of "--purge" flag for the "autoremove" command
+	purge bool
+}
+
+var AutoremoveCmd = &cobra.Command{
+	Use:   "autoremove",
+	Short: "Remove dependency packs no longer required",
+	Long: `
+Remove packs that were installed only to satisfy the requirements of other packs,
+and that are no longer required by any explicitly installed pack.
+
+  $ cpackget autoremove
+
+Packs added with "cpackget add" are explicitly installed, while the packs
+cpackget installs to fulfill their requirements are dependencies. Adding a
+dependency pack with "cpackget add" marks it as explicitly installed.
+Packs installed with earlier versions of cpackget count as explicitly installed.
+
+Cache files (i.e. under CMSIS_PACK_ROOT/.Download/)
+are *NOT* removed. If cache files need to be actually removed,
+please use "--purge".`,
+	Args:              cobra.ExactArgs(0),
+	PersistentPreRunE: configureInstaller,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installer.UnlockPackRoot()
+		err := installer.Autoremove(autoremoveCmdFlags.purge, false)
+		installer.LockPackRoot()
+		return err
+	},
+}
+
+func init() {
+	AutoremoveCmd.Flags().BoolVarP(&autoremoveCmdFlags.purge, "purge", "p", false, "forces deletion of cached pack files")
+
+	AutoremoveCmd.SetHelpFunc(func(command *cobra.Command, strings []string) {
+		err := command.Flags().MarkHidden("concurrent-downloads")
+		_ = command.Flags().MarkHidden("timeout")
+		log.Debug(err)
+		command.Parent().HelpFunc()(command, strings)
+	})
+}
@@ -0,0 +1,74 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+)
+
+var autoremoveCmdTests = []TestCase{
+	{
+		name:        "test help command",
+		args:        []string{"help", "autoremove"},
+		expectedErr: nil,
+	},
+	{
+		name:           "test autoremove with args",
+		args:           []string{"autoremove", "Vendor.Pack"},
+		createPackRoot: true,
+		expectedErr:    errors.New("accepts 0 arg(s), received 1"),
+	},
+	{
+		name:           "test autoremove without dependency packs",
+		args:           []string{"autoremove"},
+		createPackRoot: true,
+		expectedStdout: []string{"(no orphaned dependency packs to remove)"},
+		setUpFunc:      setUpPackWithDependent,
+	},
+	{
+		name:           "test autoremove keeps required dependency packs",
+		args:           []string{"autoremove"},
+		createPackRoot: true,
+		expectedStdout: []string{"(no orphaned dependency packs to remove)"},
+		setUpFunc:      setUpDependencyPack,
+	},
+	{
+		name:           "test autoremove removes orphaned dependency packs",
+		args:           []string{"autoremove"},
+		createPackRoot: true,
+		expectedStdout: []string{"Removing Vendor::Pack@1.2.3, which is no longer required by any explicitly installed pack"},
+		setUpFunc: func(t *TestCase) {
+			setUpDependencyPack(t)
+			packRoot := os.Getenv("CMSIS_PACK_ROOT")
+			t.assert.Nil(os.RemoveAll(filepath.Join(packRoot, "Vendor", "Dependent")))
+			t.assert.Nil(os.Remove(filepath.Join(packRoot, ".Local", "Vendor.Dependent.pdsc")))
+		},
+	},
+	{
+		name:           "test removing a pack with its dependencies",
+		args:           []string{"rm", "--with-deps", "Vendor.Dependent.1.0.0"},
+		createPackRoot: true,
+		expectedStdout: []string{"Removing Vendor::Pack@1.2.3, which is no longer required by any explicitly installed pack"},
+		setUpFunc:      setUpDependencyPack,
+	},
+}
+
+// setUpDependencyPack installs Vendor.Dependent.1.0.0 and Vendor.Pack.1.2.3, installed as its dependency
+func setUpDependencyPack(t *TestCase) {
+	setUpPackWithDependent(t)
+	dependencies := xml.NewPidxXML(filepath.Join(os.Getenv("CMSIS_PACK_ROOT"), ".Local", installer.DependenciesIndexName), false)
+	t.assert.Nil(dependencies.Read())
+	t.assert.Nil(dependencies.AddPdsc(xml.PdscTag{Vendor: "Vendor", Name: "Pack", Version: "1.2.3"}))
+	t.assert.Nil(dependencies.Write())
+}
+
+func TestAutoremoveCmd(t *testing.T) {
+	runTests(t, autoremoveCmdTests)
+}
@@ -31,6 +31,10 @@ func init() {
 		err := command.Flags().MarkHidden("pack-root")
 		_ = command.Flags().MarkHidden("concurrent-downloads")
 		_ = command.Flags().MarkHidden("timeout")
+		_ = command.Flags().MarkHidden("lock-timeout")
+		_ = command.Flags().MarkHidden("retries")
+		_ = command.Flags().MarkHidden("offline")
+		_ = command.Flags().MarkHidden("ca-certificates")
 		log.Debug(err)
 		command.Parent().HelpFunc()(command, strings)
 	})
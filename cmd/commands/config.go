@@ -0,0 +1,703 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	viperType "github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// ProjectConfigFileName is the configuration file of a project, looked for
+// in the working directory and then in its parents
+const ProjectConfigFileName = ".cpackget.yaml"
+
+// SystemConfigFile is the configuration file shared by all users of the machine
+var SystemConfigFile = defaultSystemConfigFile()
+
+// UserConfigFile is the configuration file of the current user, overriding the system one
+var UserConfigFile = defaultUserConfigFile()
+
+func defaultSystemConfigFile() string {
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, "cpackget", "config.yaml")
+	}
+	return filepath.Join("/etc", "cpackget", "config.yaml")
+}
+
+func defaultUserConfigFile() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "cpackget", "config.yaml")
+}
+
+// findProjectConfigFile returns the closest ProjectConfigFileName in dir or its parents, "" if there is none
+func findProjectConfigFile(dir string) string {
+	for {
+		path := filepath.Join(dir, ProjectConfigFileName)
+		if utils.FileExists(path) {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// configValueType tells how the value of a configuration key is checked and written
+type configValueType int
+
+const (
+	configString configValueType = iota
+	configUint
+	configBool
+	configURL
+
+	// configStringList is a list in the files, and separated by commas in the environment variables
+	configStringList
+
+	// configHostMap maps hosts to their settings, and can only be set in the files, see configHostMaps
+	configHostMap
+
+	// configRegistryList lists registries, and can only be set in the files, see configRegistry
+	configRegistryList
+)
+
+// configKey is a setting that can be given in the configuration files
+type configKey struct {
+	name      string
+	valueType configValueType
+
+	// flag is the command line flag overriding the setting, if any: either a global
+	// flag or a flag of the commands having it
+	flag string
+
+	// extraEnv lists environment variables overriding the setting on top of CPACKGET_<NAME>
+	extraEnv []string
+
+	description string
+}
+
+// configKeys lists all settings that can be given in the configuration files
+var configKeys = []configKey{
+	{name: "pack-root", valueType: configString, flag: "pack-root", extraEnv: []string{"CMSIS_PACK_ROOT"}, description: "pack root folder"},
+	{name: "concurrent-downloads", valueType: configUint, flag: "concurrent-downloads", description: "number of concurrent batch downloads, 0 disabling concurrency"},
+	{name: "timeout", valueType: configUint, flag: "timeout", description: "maximum duration (in seconds) of a download, 0 disabling the timeout"},
+	{name: "lock-timeout", valueType: configUint, flag: "lock-timeout", description: "maximum duration (in seconds) to wait for other cpackget runs on the pack root, 0 waiting indefinitely"},
+	{name: "retries", valueType: configUint, flag: "retries", description: "number of times a download failing on a transient error is retried"},
+	{name: "offline", valueType: configBool, flag: "offline", description: "never access the network"},
+	{name: "insecure-skip-verify", valueType: configBool, flag: "insecure-skip-verify", description: "skip verification of server's TLS certificate"},
+	{name: "index-url", valueType: configString, description: "public index used by \"init\" when none is given, and to initialize the default pack root"},
+	{name: "proxy", valueType: configURL, description: "proxy all requests go through, instead of the one of the HTTP(S)_PROXY environment variables"},
+	{name: "netrc", valueType: configString, extraEnv: []string{"NETRC"}, description: "netrc file giving the credentials of hosts, ~/.netrc by default"},
+	{name: "credentials", valueType: configHostMap, description: "credentials of hosts, by host name with an optional port: username and password, or token"},
+	{name: "ca-certificates", valueType: configStringList, flag: "ca-certificates", description: "PEM files of certificate authorities trusted on top of the ones of the system"},
+	{name: "client-certificates", valueType: configHostMap, description: "client certificates of hosts, by host name with an optional port: PEM cert and key files"},
+	{name: "registries", valueType: configRegistryList, description: "registries packs are resolved from, in priority order, before the public index unless listed as \"public\": name, url, and allow and deny patterns of vendors"},
+	{name: "policies.agree-embedded-license", valueType: configBool, flag: "agree-embedded-license", description: "agree with the embedded license of the packs added or updated"},
+}
+
+// findConfigKey returns the configuration key with the given name
+func findConfigKey(name string) (*configKey, error) {
+	for i := range configKeys {
+		if configKeys[i].name == name {
+			return &configKeys[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%q: %w", name, errs.ErrUnknownConfigKey)
+}
+
+// envVars returns the environment variables overriding the setting, the first one set winning
+func (k *configKey) envVars() []string {
+	if k.valueType == configHostMap || k.valueType == configRegistryList {
+		return nil
+	}
+	env := "CPACKGET_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(k.name))
+	return append([]string{env}, k.extraEnv...)
+}
+
+// parse checks value, as read from a configuration file, an environment variable or
+// the command line, returning it as it should be written in a configuration file
+func (k *configKey) parse(value string) (any, error) {
+	var parsed any
+	var err error
+	switch k.valueType {
+	case configUint:
+		parsed, err = strconv.ParseUint(value, 10, 0)
+	case configBool:
+		parsed, err = strconv.ParseBool(value)
+	case configURL:
+		if value != "" {
+			var URL *url.URL
+			URL, err = url.Parse(value)
+			if err == nil && (URL.Scheme == "" || URL.Host == "") {
+				err = errors.New("not an absolute URL")
+			}
+		}
+		parsed = value
+	case configStringList:
+		parsed = splitConfigList(value)
+	case configHostMap, configRegistryList:
+		err = errors.New("only set in the configuration files")
+	default:
+		parsed = value
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%q for %q: %w", value, k.name, errs.ErrInvalidConfigValue)
+	}
+	return parsed, nil
+}
+
+// configLayer is one of the configuration files, each overriding the previous ones
+type configLayer struct {
+	// name of the layer: system, user or project
+	name string
+
+	// path of the configuration file, "" if there is none
+	path string
+
+	// values read from the file
+	values *viperType.Viper
+
+	// hostMaps read from the file. They are kept out of values as host names,
+	// containing dots, would be taken for nested keys.
+	hostMaps configHostMaps
+}
+
+// configHostMaps are the settings of configHostMap type
+type configHostMaps struct {
+	Credentials        map[string]utils.Credentials      `yaml:"credentials"`
+	ClientCertificates map[string]clientCertificateFiles `yaml:"client-certificates"`
+}
+
+// clientCertificateFiles are the PEM files of a client certificate and of its private key
+type clientCertificateFiles struct {
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+// hosts returns the hosts having the given setting
+func (m *configHostMaps) hosts(name string) []string {
+	switch name {
+	case "credentials":
+		return slices.Collect(maps.Keys(m.Credentials))
+	case "client-certificates":
+		return slices.Collect(maps.Keys(m.ClientCertificates))
+	}
+	return nil
+}
+
+// configLayers are the configuration files read by NewCli, from lowest to highest precedence
+var configLayers []configLayer
+
+// configLoadErr is the error reading the configuration files, reported when running a command
+var configLoadErr error
+
+// loadConfig reads the system, user and project configuration files in v, binds the settings
+// to their environment variables and returns the files read
+func loadConfig(v *viperType.Viper) ([]configLayer, error) {
+	projectConfigFile := ""
+	if wd, err := os.Getwd(); err == nil {
+		projectConfigFile = findProjectConfigFile(wd)
+	}
+	layers := []configLayer{
+		{name: "system", path: SystemConfigFile},
+		{name: "user", path: UserConfigFile},
+		{name: "project", path: projectConfigFile},
+	}
+
+	for _, key := range configKeys {
+		if env := key.envVars(); len(env) > 0 {
+			_ = v.BindEnv(append([]string{key.name}, env...)...)
+		}
+		if v.Get(key.name) == nil && key.valueType != configHostMap && key.valueType != configRegistryList {
+			// Settings not bound to a global flag still need a default value, see applyConfigToFlags
+			switch key.valueType {
+			case configUint:
+				v.SetDefault(key.name, 0)
+			case configBool:
+				v.SetDefault(key.name, false)
+			default:
+				v.SetDefault(key.name, "")
+			}
+		}
+	}
+
+	var err error
+	for i := range layers {
+		layer := &layers[i]
+		layer.values = viperType.New()
+		if err != nil || layer.path == "" || !utils.FileExists(layer.path) {
+			continue
+		}
+
+		layer.values.SetConfigFile(layer.path)
+		layer.values.SetConfigType("yaml")
+		if readErr := layer.values.ReadInConfig(); readErr != nil {
+			err = fmt.Errorf("%q: %w", layer.path, readErr)
+			continue
+		}
+		for _, name := range layer.values.AllKeys() {
+			if strings.HasPrefix(name, "credentials.") || strings.HasPrefix(name, "client-certificates.") {
+				continue
+			}
+			if _, unknownErr := findConfigKey(name); unknownErr != nil {
+				log.Warnf("Ignoring %q in %q: %v", name, layer.path, errs.ErrUnknownConfigKey)
+			}
+		}
+		if err = readConfigHostMaps(layer); err != nil {
+			continue
+		}
+		settings := layer.values.AllSettings()
+		delete(settings, "credentials")
+		delete(settings, "client-certificates")
+		resolveConfigPaths(layer, settings)
+		err = v.MergeConfigMap(settings)
+	}
+	return layers, err
+}
+
+// resolveConfigPaths makes the relative paths of the files given in the configuration file of layer,
+// read in settings, relative to the folder of the file rather than to the working directory
+func resolveConfigPaths(layer *configLayer, settings map[string]any) {
+	dir := filepath.Dir(layer.path)
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	if netrc, ok := settings["netrc"].(string); ok {
+		settings["netrc"] = resolve(netrc)
+	}
+	if caCertificates, found := settings["ca-certificates"]; found {
+		files := splitConfigList(fmt.Sprint(caCertificates))
+		if list, ok := caCertificates.([]any); ok {
+			files = []string{}
+			for _, file := range list {
+				files = append(files, fmt.Sprint(file))
+			}
+		}
+		for i := range files {
+			files[i] = resolve(files[i])
+		}
+		settings["ca-certificates"] = files
+	}
+	for host, files := range layer.hostMaps.ClientCertificates {
+		layer.hostMaps.ClientCertificates[host] = clientCertificateFiles{Cert: resolve(files.Cert), Key: resolve(files.Key)}
+	}
+}
+
+// readConfigHostMaps reads the settings of configHostMap type in the configuration file of layer
+func readConfigHostMaps(layer *configLayer) error {
+	contents, err := os.ReadFile(layer.path) // #nosec
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(contents, &layer.hostMaps); err != nil {
+		log.Error(err)
+		return fmt.Errorf("%q: %w", layer.path, errs.ErrInvalidConfigValue)
+	}
+	return nil
+}
+
+// splitConfigList splits the value of a configStringList setting given as a string
+func splitConfigList(value string) []string {
+	list := []string{}
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getConfigStrings returns the value of a configStringList setting
+func getConfigStrings(name string) []string {
+	if value, ok := viper.Get(name).(string); ok {
+		return splitConfigList(value)
+	}
+	return viper.GetStringSlice(name)
+}
+
+// configRegistry is a registry, as set in the configuration files
+type configRegistry struct {
+	Name  string   `mapstructure:"name"`
+	URL   string   `mapstructure:"url"`
+	Allow []string `mapstructure:"allow"`
+	Deny  []string `mapstructure:"deny"`
+}
+
+// getConfigRegistries returns the registries set in the configuration files, in priority order
+func getConfigRegistries() ([]installer.Registry, error) {
+	var configRegistries []configRegistry
+	if err := viper.UnmarshalKey("registries", &configRegistries); err != nil {
+		log.Error(err)
+		return nil, fmt.Errorf("%q: %w", "registries", errs.ErrInvalidConfigValue)
+	}
+	registries := []installer.Registry{}
+	for _, registry := range configRegistries {
+		registries = append(registries, installer.Registry{
+			Name:  registry.Name,
+			URL:   registry.URL,
+			Allow: registry.Allow,
+			Deny:  registry.Deny,
+		})
+	}
+	return registries, nil
+}
+
+// configSource tells where the effective value of the setting comes from
+func configSource(cmd *cobra.Command, key *configKey) string {
+	if key.flag != "" {
+		if flag := cmd.Flags().Lookup(key.flag); flag != nil && flag.Changed {
+			return "flag --" + key.flag
+		}
+	}
+	for _, env := range key.envVars() {
+		if os.Getenv(env) != "" {
+			return "env " + env
+		}
+	}
+	for i := len(configLayers) - 1; i >= 0; i-- {
+		if configLayers[i].values.IsSet(key.name) || (key.valueType == configHostMap && len(configLayers[i].hostMaps.hosts(key.name)) > 0) {
+			return configLayers[i].name + " " + configLayers[i].path
+		}
+	}
+	return "default"
+}
+
+// checkConfig checks the effective value of all settings
+func checkConfig(cmd *cobra.Command) error {
+	if configLoadErr != nil {
+		return configLoadErr
+	}
+	for i := range configKeys {
+		key := &configKeys[i]
+		if key.valueType == configHostMap || key.valueType == configStringList {
+			// Checked when read
+			continue
+		}
+		if key.valueType == configRegistryList {
+			if _, err := getConfigRegistries(); err != nil {
+				return fmt.Errorf("%w (from %s)", err, configSource(cmd, key))
+			}
+			continue
+		}
+		if _, err := key.parse(viper.GetString(key.name)); err != nil {
+			return fmt.Errorf("%w (from %s)", err, configSource(cmd, key))
+		}
+	}
+	return nil
+}
+
+// getConfigCredentials returns the credentials of each host set in the configuration files
+func getConfigCredentials() map[string]utils.Credentials {
+	credentials := map[string]utils.Credentials{}
+	for _, layer := range configLayers {
+		maps.Copy(credentials, layer.hostMaps.Credentials)
+	}
+	return credentials
+}
+
+// configureCredentials sets the credentials of the hosts from the netrc file, overridden by the
+// configuration files. Environment variables and the credentials in the URLs override both.
+func configureCredentials() error {
+	utils.ResetCredentials()
+	netrc := viper.GetString("netrc")
+	if netrc == "" {
+		netrc = utils.DefaultNetrcFile()
+	}
+	if netrc != "" {
+		if err := utils.LoadNetrc(netrc); err != nil {
+			return err
+		}
+	}
+
+	for host, hostCredentials := range getConfigCredentials() {
+		utils.SetCredentials(host, hostCredentials)
+	}
+	return nil
+}
+
+// configureTLS sets the certificate authorities and client certificates of options
+func configureTLS(options *utils.HTTPClientOptions) error {
+	options.RootCAs = nil
+	if files := getConfigStrings("ca-certificates"); len(files) > 0 {
+		rootCAs, err := utils.LoadCACertificates(files)
+		if err != nil {
+			return err
+		}
+		options.RootCAs = rootCAs
+	}
+
+	clientCertificateFiles := map[string]clientCertificateFiles{}
+	for _, layer := range configLayers {
+		maps.Copy(clientCertificateFiles, layer.hostMaps.ClientCertificates)
+	}
+	options.ClientCertificates = map[string]tls.Certificate{}
+	for host, files := range clientCertificateFiles {
+		certificate, err := utils.LoadClientCertificate(files.Cert, files.Key)
+		if err != nil {
+			return err
+		}
+		options.ClientCertificates[strings.ToLower(host)] = certificate
+	}
+	return nil
+}
+
+// applyConfigToFlags gives the flags of cmd not set on the command line the value of their setting.
+// Global flags are bound to their setting already.
+func applyConfigToFlags(cmd *cobra.Command) {
+	for _, key := range configKeys {
+		if key.flag == "" || cmd.Root().PersistentFlags().Lookup(key.flag) != nil {
+			continue
+		}
+		if flag := cmd.Flags().Lookup(key.flag); flag != nil && !flag.Changed {
+			// Setting the value directly does not mark the flag as changed
+			_ = flag.Value.Set(viper.GetString(key.name))
+		}
+	}
+}
+
+// setConfigFileValue sets key to value in the configuration file in path, keeping the rest of the file as is
+func setConfigFileValue(path, key string, value any) error {
+	doc := &yaml.Node{}
+	contents, err := os.ReadFile(path) // #nosec
+	if err == nil {
+		if err := yaml.Unmarshal(contents, doc); err != nil {
+			return fmt.Errorf("%q: %w", path, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return err
+	}
+
+	parent := doc.Content[0]
+	names := strings.Split(key, ".")
+	for i, name := range names {
+		if parent.Kind != yaml.MappingNode {
+			if parent.Tag != "!!null" {
+				return fmt.Errorf("%q: %q is not a mapping", path, strings.Join(names[:i], "."))
+			}
+			parent.Kind = yaml.MappingNode
+			parent.Tag = "!!map"
+			parent.Value = ""
+		}
+
+		var child *yaml.Node
+		for j := 0; j+1 < len(parent.Content); j += 2 {
+			if parent.Content[j].Value == name {
+				child = parent.Content[j+1]
+			}
+		}
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+			parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, child)
+		}
+		if i == len(names)-1 {
+			// Keep the comments around the value
+			valueNode.HeadComment = child.HeadComment
+			valueNode.LineComment = child.LineComment
+			valueNode.FootComment = child.FootComment
+			*child = *valueNode
+		}
+		parent = child
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buffer.Bytes(), 0644) // #nosec G306 -- the system configuration is read by all users
+}
+
+// printConfig prints the effective value and source of the given settings
+func printConfig(cmd *cobra.Command, keys []*configKey) error {
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "KEY\tVALUE\tSOURCE")
+	for _, key := range keys {
+		value := viper.GetString(key.name)
+		switch key.valueType {
+		case configStringList:
+			value = strings.Join(getConfigStrings(key.name), ",")
+		case configHostMap:
+			// Only tell which hosts have the setting
+			hosts := []string{}
+			for _, layer := range configLayers {
+				hosts = append(hosts, layer.hostMaps.hosts(key.name)...)
+			}
+			slices.Sort(hosts)
+			value = strings.Join(slices.Compact(hosts), ",")
+		case configRegistryList:
+			// Only tell the names of the registries
+			names := []string{}
+			registries, _ := getConfigRegistries()
+			for _, registry := range registries {
+				names = append(names, registry.Name)
+			}
+			value = strings.Join(names, ",")
+		}
+		if value == "" {
+			value = `""`
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", key.name, value, configSource(cmd, key))
+	}
+	return writer.Flush()
+}
+
+var configSetCmdFlags struct {
+	// system writes the setting in the configuration file shared by all users
+	system bool
+
+	// user writes the setting in the configuration file of the current user, the default
+	user bool
+
+	// project writes the setting in the configuration file of the project
+	project bool
+}
+
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change the configuration",
+	Long: `Shows and changes the settings read from the configuration files, each overriding the previous ones:
+  - system: ` + SystemConfigFile + `
+  - user: ` + UserConfigFile + `
+  - project: the closest ` + ProjectConfigFileName + ` in the working directory or its parents
+Settings in the files are overridden by the CPACKGET_<KEY> environment variables, e.g. CPACKGET_LOCK_TIMEOUT
+for "lock-timeout" or CPACKGET_POLICIES_AGREE_EMBEDDED_LICENSE for "policies.agree-embedded-license",
+which are in turn overridden by the command line flags.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Show the effective value of a setting and where it comes from",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if configLoadErr != nil {
+			return configLoadErr
+		}
+		key, err := findConfigKey(args[0])
+		if err != nil {
+			return err
+		}
+		return printConfig(cmd, []*configKey{key})
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value> [--system|--user|--project]",
+	Short: "Set a setting in a configuration file, the one of the current user by default",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := findConfigKey(args[0])
+		if err != nil {
+			return err
+		}
+		value, err := key.parse(args[1])
+		if err != nil {
+			return err
+		}
+
+		path := UserConfigFile
+		if configSetCmdFlags.system {
+			path = SystemConfigFile
+		}
+		if configSetCmdFlags.project {
+			path = configLayers[len(configLayers)-1].path
+			if path == "" {
+				wd, err := os.Getwd()
+				if err != nil {
+					return err
+				}
+				path = filepath.Join(wd, ProjectConfigFileName)
+			}
+		}
+		if path == "" {
+			return fmt.Errorf("no configuration file of the current user: %w", errs.ErrIncorrectCmdArgs)
+		}
+
+		if err := setConfigFileValue(path, key.name, value); err != nil {
+			return err
+		}
+		log.Infof("Set %q to %q in %q", key.name, args[1], path)
+		return nil
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show the effective value of all settings and where they come from",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if configLoadErr != nil {
+			return configLoadErr
+		}
+		keys := make([]*configKey, 0, len(configKeys))
+		for i := range configKeys {
+			keys = append(keys, &configKeys[i])
+		}
+		slices.SortFunc(keys, func(a, b *configKey) int { return strings.Compare(a.name, b.name) })
+		return printConfig(cmd, keys)
+	},
+}
+
+func init() {
+	ConfigCmd.Long += "\n\nSettings:"
+	for _, key := range configKeys {
+		ConfigCmd.Long += fmt.Sprintf("\n  %s: %s", key.name, key.description)
+	}
+
+	configSetCmd.Flags().BoolVar(&configSetCmdFlags.system, "system", false, "writes the setting in the configuration file shared by all users")
+	configSetCmd.Flags().BoolVar(&configSetCmdFlags.user, "user", false, "writes the setting in the configuration file of the current user (default)")
+	configSetCmd.Flags().BoolVar(&configSetCmdFlags.project, "project", false, "writes the setting in the "+ProjectConfigFileName+" of the project, in the working directory if there is none")
+	configSetCmd.MarkFlagsMutuallyExclusive("system", "user", "project")
+
+	ConfigCmd.AddCommand(configGetCmd, configSetCmd, configListCmd)
+}
@@ -0,0 +1,291 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/commands"
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/stretchr/testify/assert"
+)
+
+// configTestDirs writes the given system, user and project configuration files, and makes
+// a subdirectory of the project the working directory
+func configTestDirs(t *testing.T, system, user, project string) (projectDir string) {
+	dir := t.TempDir()
+	configFiles := map[string]string{
+		filepath.Join(dir, "system.yaml"):                             system,
+		filepath.Join(dir, "user", "config.yaml"):                     user,
+		filepath.Join(dir, "project", commands.ProjectConfigFileName): project,
+	}
+	for path, contents := range configFiles {
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0700))
+		if contents != "" {
+			assert.Nil(t, os.WriteFile(path, []byte(contents), 0600))
+		}
+	}
+
+	currSystemConfigFile, currUserConfigFile := commands.SystemConfigFile, commands.UserConfigFile
+	commands.SystemConfigFile = filepath.Join(dir, "system.yaml")
+	commands.UserConfigFile = filepath.Join(dir, "user", "config.yaml")
+	t.Cleanup(func() {
+		commands.SystemConfigFile, commands.UserConfigFile = currSystemConfigFile, currUserConfigFile
+	})
+
+	projectDir = filepath.Join(dir, "project")
+	workDir := filepath.Join(projectDir, "sub", "dir")
+	assert.Nil(t, os.MkdirAll(workDir, 0700))
+	t.Chdir(workDir)
+	return projectDir
+}
+
+// runConfigCmd runs cpackget with the given arguments, returning what it printed
+func runConfigCmd(args ...string) (string, error) {
+	cmd := commands.NewCli()
+	stdout := bytes.NewBufferString("")
+	cmd.SetOut(stdout)
+	cmd.SetErr(stdout)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	resetFlags(cmd)
+	return stdout.String(), err
+}
+
+// configLine returns the value and source printed for key
+func configLine(output, key string) (value, source string) {
+	for line := range strings.Lines(output) {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == key {
+			return fields[1], strings.Join(fields[2:], " ")
+		}
+	}
+	return "", ""
+}
+
+func TestConfigCmd(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("CMSIS_PACK_ROOT", "")
+	t.Setenv("CPACKGET_RETRIES", "")
+
+	t.Run("test settings override each other", func(t *testing.T) {
+		projectDir := configTestDirs(t,
+			"timeout: 10\nretries: 5\nlock-timeout: 1\n",
+			"timeout: 20\nlock-timeout: 2\n",
+			"timeout: 30\n",
+		)
+		projectFile := filepath.Join(projectDir, commands.ProjectConfigFileName)
+
+		output, err := runConfigCmd("config", "list")
+		assert.Nil(err)
+		value, source := configLine(output, "timeout")
+		assert.Equal("30", value)
+		assert.Equal("project "+projectFile, source)
+		value, source = configLine(output, "lock-timeout")
+		assert.Equal("2", value)
+		assert.Equal("user "+commands.UserConfigFile, source)
+		value, source = configLine(output, "retries")
+		assert.Equal("5", value)
+		assert.Equal("system "+commands.SystemConfigFile, source)
+		value, source = configLine(output, "concurrent-downloads")
+		assert.Equal("20", value)
+		assert.Equal("default", source)
+
+		t.Setenv("CPACKGET_RETRIES", "7")
+		output, err = runConfigCmd("config", "get", "retries")
+		assert.Nil(err)
+		value, source = configLine(output, "retries")
+		assert.Equal("7", value)
+		assert.Equal("env CPACKGET_RETRIES", source)
+	})
+
+	t.Run("test credentials are not shown", func(t *testing.T) {
+		configTestDirs(t, "", "", `credentials:
+  packs.acme.com:
+    token: secret-token
+  intranet:8080:
+    username: me
+    password: secret-password
+`)
+		output, err := runConfigCmd("config", "get", "credentials")
+		assert.Nil(err)
+		value, _ := configLine(output, "credentials")
+		assert.Equal("intranet:8080,packs.acme.com", value)
+		assert.NotContains(output, "secret")
+
+		_, err = runConfigCmd("config", "set", "credentials", "secret")
+		assert.Equal(errs.ErrInvalidConfigValue, errors.Unwrap(err))
+	})
+
+	t.Run("test certificate settings", func(t *testing.T) {
+		projectDir := configTestDirs(t, "", "", `client-certificates:
+  packs.acme.com:
+    cert: acme.crt
+    key: acme.key
+`)
+		projectFile := filepath.Join(projectDir, commands.ProjectConfigFileName)
+
+		_, err := runConfigCmd("config", "set", "ca-certificates", "proxy.pem,corporate.pem", "--project")
+		assert.Nil(err)
+		contents, err := os.ReadFile(projectFile)
+		assert.Nil(err)
+		assert.Contains(string(contents), "ca-certificates:\n  - proxy.pem\n  - corporate.pem")
+
+		output, err := runConfigCmd("config", "list")
+		assert.Nil(err)
+		value, source := configLine(output, "ca-certificates")
+		assert.Equal(filepath.Join(projectDir, "proxy.pem")+","+filepath.Join(projectDir, "corporate.pem"), value)
+		assert.Equal("project "+projectFile, source)
+		value, _ = configLine(output, "client-certificates")
+		assert.Equal("packs.acme.com", value)
+
+		t.Setenv("CPACKGET_CA_CERTIFICATES", "other.pem")
+		output, err = runConfigCmd("config", "get", "ca-certificates")
+		assert.Nil(err)
+		value, source = configLine(output, "ca-certificates")
+		assert.Equal("other.pem", value)
+		assert.Equal("env CPACKGET_CA_CERTIFICATES", source)
+
+		// The certificate files do not exist
+		_, err = runConfigCmd("init", "index.pidx")
+		assert.NotNil(err)
+	})
+
+	t.Run("test files are relative to the configuration file setting them", func(t *testing.T) {
+		indexPath, err := filepath.Abs(pidxFilePath)
+		assert.Nil(err)
+		projectDir := configTestDirs(t, "", "", `pack-root: packs
+index-url: `+indexPath+`
+netrc: netrc
+ca-certificates: [certs/ca.pem]
+client-certificates:
+  packs.acme.com:
+    cert: certs/client.pem
+    key: certs/client.key
+`)
+
+		certificate, certPEM, err := installer.GenerateSelfSignedCertificate([]string{"localhost"})
+		assert.Nil(err)
+		keyDER, err := x509.MarshalECPrivateKey(certificate.PrivateKey.(*ecdsa.PrivateKey))
+		assert.Nil(err)
+		certsDir := filepath.Join(projectDir, "certs")
+		assert.Nil(os.MkdirAll(certsDir, 0700))
+		assert.Nil(os.WriteFile(filepath.Join(certsDir, "ca.pem"), certPEM, 0600))
+		assert.Nil(os.WriteFile(filepath.Join(certsDir, "client.pem"), certPEM, 0600))
+		assert.Nil(os.WriteFile(filepath.Join(certsDir, "client.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+		output, err := runConfigCmd("config", "get", "netrc")
+		assert.Nil(err)
+		value, _ := configLine(output, "netrc")
+		assert.Equal(filepath.Join(projectDir, "netrc"), value)
+
+		// The certificates are found from the subdirectory of the project being the working directory
+		_, err = runConfigCmd("init")
+		installer.ReleasePackRootLock()
+		assert.Nil(err)
+	})
+
+	t.Run("test registries settings", func(t *testing.T) {
+		projectDir := configTestDirs(t, "", "", `registries:
+  - name: acme
+    url: https://packs.acme.com/index.pidx
+    allow: [Acme]
+  - name: public
+    deny: [Acme]
+`)
+		projectFile := filepath.Join(projectDir, commands.ProjectConfigFileName)
+
+		output, err := runConfigCmd("config", "get", "registries")
+		assert.Nil(err)
+		value, source := configLine(output, "registries")
+		assert.Equal("acme,public", value)
+		assert.Equal("project "+projectFile, source)
+
+		_, err = runConfigCmd("config", "set", "registries", "acme")
+		assert.Equal(errs.ErrInvalidConfigValue, errors.Unwrap(err))
+
+		// The registries are checked by the commands
+		configTestDirs(t, "", "", "registries:\n  - name: acme\n")
+		_, err = runConfigCmd("init", "index.pidx")
+		assert.True(errors.Is(err, errs.ErrInvalidRegistry))
+	})
+
+	t.Run("test getting an unknown setting", func(t *testing.T) {
+		configTestDirs(t, "", "", "")
+		_, err := runConfigCmd("config", "get", "does-not-exist")
+		assert.Equal(errs.ErrUnknownConfigKey, errors.Unwrap(err))
+	})
+
+	t.Run("test setting values", func(t *testing.T) {
+		projectDir := configTestDirs(t, "", "", "# Settings of the project\ntimeout: 30 # seconds\n")
+		projectFile := filepath.Join(projectDir, commands.ProjectConfigFileName)
+
+		_, err := runConfigCmd("config", "set", "timeout", "40", "--project")
+		assert.Nil(err)
+		_, err = runConfigCmd("config", "set", "policies.agree-embedded-license", "true", "--project")
+		assert.Nil(err)
+		_, err = runConfigCmd("config", "set", "retries", "1")
+		assert.Nil(err)
+
+		contents, err := os.ReadFile(projectFile)
+		assert.Nil(err)
+		assert.Contains(string(contents), "# Settings of the project")
+		assert.Contains(string(contents), "timeout: 40 # seconds")
+		assert.Contains(string(contents), "policies:\n  agree-embedded-license: true")
+
+		output, err := runConfigCmd("config", "list")
+		assert.Nil(err)
+		value, source := configLine(output, "policies.agree-embedded-license")
+		assert.Equal("true", value)
+		assert.Equal("project "+projectFile, source)
+		value, source = configLine(output, "retries")
+		assert.Equal("1", value)
+		assert.Equal("user "+commands.UserConfigFile, source)
+	})
+
+	t.Run("test setting invalid values", func(t *testing.T) {
+		configTestDirs(t, "", "", "")
+		_, err := runConfigCmd("config", "set", "timeout", "soon")
+		assert.Equal(errs.ErrInvalidConfigValue, errors.Unwrap(err))
+		_, err = runConfigCmd("config", "set", "proxy", "my-proxy")
+		assert.Equal(errs.ErrInvalidConfigValue, errors.Unwrap(err))
+		_, err = runConfigCmd("config", "set", "does-not-exist", "1")
+		assert.Equal(errs.ErrUnknownConfigKey, errors.Unwrap(err))
+		assert.NoFileExists(commands.UserConfigFile)
+	})
+
+	t.Run("test commands fail on invalid settings", func(t *testing.T) {
+		configTestDirs(t, "", "", "timeout: soon\n")
+		_, err := runConfigCmd("init", "index.pidx")
+		assert.True(errors.Is(err, errs.ErrInvalidConfigValue))
+		assert.Contains(err.Error(), commands.ProjectConfigFileName)
+	})
+
+	t.Run("test commands fail on malformed configuration files", func(t *testing.T) {
+		configTestDirs(t, "", "timeout: [\n", "")
+		_, err := runConfigCmd("config", "list")
+		assert.NotNil(err)
+		assert.Contains(err.Error(), commands.UserConfigFile)
+	})
+
+	t.Run("test init uses the pack root and index url of the configuration", func(t *testing.T) {
+		indexPath, err := filepath.Abs(pidxFilePath)
+		assert.Nil(err)
+		projectDir := configTestDirs(t, "", "", "pack-root: packs\nindex-url: "+indexPath+"\n")
+
+		_, err = runConfigCmd("init")
+		installer.ReleasePackRootLock()
+		assert.Nil(err)
+		assert.FileExists(filepath.Join(projectDir, "sub", "dir", "packs", ".Web", installer.PublicIndexName))
+	})
+}
@@ -35,7 +35,7 @@ The url is optional. Ex "cpackget connection https://www.keil.com/pack"`,
 
 		var indexPath string
 		if len(args) > 0 {
-			indexPath = args[0]
+			indexPath = utils.StripCredentials(args[0])
 		}
 		createPackRoot = false
 		var err error
@@ -0,0 +1,71 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands
+
+import (
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/spf13/cobra"
+)
+
+var indexCreateCmdFlags struct {
+	// output is the index file written
+	output string
+
+	// vendor is the vendor of the index
+	vendor string
+
+	// url is the URL the index gets published at
+	url string
+
+	// deprecations is the file setting the deprecated and replacement attributes of packs
+	deprecations string
+
+	// insecureSkipVerify skips the verification of the TLS certificate of the servers of the PDSC files
+	insecureSkipVerify bool
+}
+
+var IndexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage pack index files",
+	Long:  `Manages pack index (.pidx) files, e.g. the one of a registry`,
+}
+
+var indexCreateCmd = &cobra.Command{
+	Use:   "create <directory | pdsc file | url>... [-o <index file>]",
+	Short: "Create an index file listing the packs of PDSC files",
+	Long: `Creates an index file listing the latest release of the packs described by PDSC files, found
+in the given directories and their subdirectories, or downloaded from the given URLs:
+
+  $ cpackget index create ./registry -o ./registry/index.pidx
+
+The url of each pack is the <url> of its PDSC file, or where it got downloaded from. The vendor and url of the
+index default to the ones of the packs, when they all share them, and can be set with "--vendor" and "--url".
+
+Deprecated packs are set in a YAML file given with "--deprecations":
+
+  TheVendor::OldPack:
+    deprecated: 2025-06-30
+    replacement: TheVendor.NewPack
+
+The index is checked against the types of PackIndex.xsd before being written,
+and its urls have to be absolute.`,
+	Args:              cobra.MinimumNArgs(1),
+	PersistentPreRunE: configureInstallerGlobalCmd,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stripCredentials(args)
+		indexURL := utils.StripCredentials(indexCreateCmdFlags.url)
+		return installer.CreateIndex(args, indexCreateCmdFlags.output, indexCreateCmdFlags.vendor, indexURL, indexCreateCmdFlags.deprecations, indexCreateCmdFlags.insecureSkipVerify, viper.GetInt("timeout"))
+	},
+}
+
+func init() {
+	indexCreateCmd.Flags().StringVarP(&indexCreateCmdFlags.output, "output", "o", installer.PublicIndexName, "index file to write")
+	indexCreateCmd.Flags().StringVar(&indexCreateCmdFlags.vendor, "vendor", "", "vendor of the index, the one of the packs by default")
+	indexCreateCmd.Flags().StringVar(&indexCreateCmdFlags.url, "url", "", "url the index is published at, the one of the packs by default")
+	indexCreateCmd.Flags().StringVar(&indexCreateCmdFlags.deprecations, "deprecations", "", "YAML file setting the deprecated and replacement attributes of packs, by Vendor::Pack")
+	indexCreateCmd.Flags().BoolVar(&indexCreateCmdFlags.insecureSkipVerify, "insecure-skip-verify", false, "skip verification of server's TLS certificate when downloading PDSC files over HTTPS")
+
+	IndexCmd.AddCommand(indexCreateCmd)
+}
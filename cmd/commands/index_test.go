@@ -0,0 +1,57 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+)
+
+var createdIndexPath = filepath.Join(os.TempDir(), "cpackget-test-index.pidx")
+
+var indexCmdTests = []TestCase{
+	{
+		name:        "test help command",
+		args:        []string{"help", "index", "create"},
+		expectedErr: nil,
+	},
+	{
+		name:        "test creating an index without pdsc files",
+		args:        []string{"index", "create"},
+		expectedErr: errors.New("requires at least 1 arg(s), only received 0"),
+	},
+	{
+		name: "test creating an index from a directory",
+		args: []string{"index", "create", filepath.Join(testingDir, "..", "devpack"), "-o", createdIndexPath, "--url", "https://the.vendor/"},
+		validationFunc: func(t *testing.T) {
+			pidxXML := xml.NewPidxXML(createdIndexPath, false)
+			if err := pidxXML.Read(); err != nil {
+				t.Fatal(err)
+			}
+			tags := pidxXML.FindPdscNameTags(xml.PdscTag{Vendor: "TheVendor", Name: "DevPack"})
+			if len(tags) != 1 || tags[0].Version != "1.2.4" || pidxXML.URL != "https://the.vendor/" {
+				t.Errorf("unexpected index %q: %v", createdIndexPath, tags)
+			}
+		},
+		tearDownFunc: func() {
+			os.Remove(createdIndexPath)
+		},
+	},
+	{
+		name:         "test creating an index from a missing directory",
+		args:         []string{"index", "create", "does-not-exist", "-o", filepath.Join(os.TempDir(), installer.PublicIndexName)},
+		expectedErr:  errs.ErrFileNotFound,
+		expErrUnwrap: true,
+	},
+}
+
+func TestIndexCmd(t *testing.T) {
+	runTests(t, indexCmdTests)
+}
@@ -0,0 +1,43 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands
+
+import (
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var InfoCmd = &cobra.Command{
+	Use:   "info <pack>",
+	Short: "Show details of a pack",
+	Long: `
+Show what cpackget knows about a pack, without installing it:
+
+  $ cpackget info Vendor.Pack
+  $ cpackget info Vendor::Pack
+
+  The information is read from the pack's PDSC file found under
+  "CMSIS_PACK_ROOT/.Web/", "CMSIS_PACK_ROOT/.Local/" or "CMSIS_PACK_ROOT/.Download/".
+  It includes all releases with their dates and URLs, the license file,
+  requirements, deprecation and replacement, installed versions and
+  cached pack files.
+
+No files are downloaded. Run "cpackget update-index" beforehand
+to refresh the PDSC files of public packs.`,
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: configureInstaller,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return installer.ShowPackInfo(args[0])
+	},
+}
+
+func init() {
+	InfoCmd.SetHelpFunc(func(command *cobra.Command, strings []string) {
+		err := command.Flags().MarkHidden("concurrent-downloads")
+		_ = command.Flags().MarkHidden("timeout")
+		log.Debug(err)
+		command.Parent().HelpFunc()(command, strings)
+	})
+}
@@ -0,0 +1,58 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+)
+
+var infoCmdTests = []TestCase{
+	{
+		name:        "test help command",
+		args:        []string{"help", "info"},
+		expectedErr: nil,
+	},
+	{
+		name:           "test info with no args",
+		args:           []string{"info"},
+		createPackRoot: true,
+		expectedErr:    errors.New("accepts 1 arg(s), received 0"),
+	},
+	{
+		name:           "test info of a pack without pdsc file",
+		args:           []string{"info", "Vendor.Pack"},
+		createPackRoot: true,
+		expectedErr:    errs.ErrPdscFileNotFound,
+		expErrUnwrap:   true,
+	},
+	{
+		name:           "test info of an installed pack",
+		args:           []string{"info", "Vendor::Pack"},
+		createPackRoot: true,
+		expectedStdout: []string{"Vendor::Pack", "1.2.3 (2021-10-17)", "(installed)", "License: LICENSE.txt"},
+		setUpFunc: func(t *TestCase) {
+			packRoot := os.Getenv("CMSIS_PACK_ROOT")
+			t.assert.Nil(os.MkdirAll(filepath.Join(packRoot, "Vendor", "Pack", "1.2.3"), 0700))
+			t.assert.Nil(os.WriteFile(filepath.Join(packRoot, ".Local", "Vendor.Pack.pdsc"), []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<package>
+  <vendor>Vendor</vendor>
+  <name>Pack</name>
+  <url>https://vendor.com/</url>
+  <license>LICENSE.txt</license>
+  <releases>
+    <release version="1.2.3" date="2021-10-17">Initial release.</release>
+  </releases>
+</package>`), 0600))
+		},
+	},
+}
+
+func TestInfoCmd(t *testing.T) {
+	runTests(t, infoCmdTests)
+}
@@ -4,6 +4,7 @@
 package commands
 
 import (
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
 	"github.com/open-cmsis-pack/cpackget/cmd/installer"
 	"github.com/open-cmsis-pack/cpackget/cmd/utils"
 	log "github.com/sirupsen/logrus"
@@ -28,7 +29,7 @@ var initCmdFlags struct {
 }
 
 var InitCmd = &cobra.Command{
-	Use:   "init [--pack-root <pack root>] <index-url>",
+	Use:   "init [--pack-root <pack root>] [<index-url>]",
 	Short: "Initializes a pack root folder",
 	Long: `Initializes a pack root folder specified by -R/--pack-root command line
 or via the CMSIS_PACK_ROOT environment variable with the following contents:
@@ -36,16 +37,24 @@ or via the CMSIS_PACK_ROOT environment variable with the following contents:
   - .Local/
   - .Web/
   - .Web/index.pidx (downloaded from <index-url>)
-The index-url is mandatory. Ex "cpackget init --pack-root path/to/mypackroot https://www.keil.com/pack/index.pidx"`,
-	Args: cobra.ExactArgs(1),
+The index-url is mandatory, unless set as "index-url" in the configuration. It can
+also be a vendor index (.vidx) file, in which case the index files of the vendors
+it lists get merged into .Web/index.pidx, or the file:// URL of a registry directory
+written by "publish".
+Ex "cpackget init --pack-root path/to/mypackroot https://www.keil.com/pack/index.pidx"`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		packRoot := viper.GetString("pack-root")
 		utils.SetEncodedProgress(initCmdFlags.encodedProgress)
 		utils.SetSkipTouch(initCmdFlags.skipTouch)
 
-		indexPath := args[0]
-
-		log.Debugf("Initializing a new pack root in \"%v\" using index url \"%v\"", packRoot, indexPath)
+		indexPath := viper.GetString("index-url")
+		if len(args) > 0 {
+			indexPath = args[0]
+		}
+		if indexPath == "" {
+			return errs.ErrMissingIndexURL
+		}
 
 		createPackRoot = true
 		err := configureInstaller(cmd, args)
@@ -53,6 +62,10 @@ The index-url is mandatory. Ex "cpackget init --pack-root path/to/mypackroot htt
 			return err
 		}
 
+		// Credentials in the URL are kept for its host, over the configured ones
+		indexPath = utils.StripCredentials(indexPath)
+		log.Debugf("Initializing a new pack root in \"%v\" using index url \"%v\"", packRoot, indexPath)
+
 		installer.UnlockPackRoot()
 		defer installer.LockPackRoot()
 		if err := installer.ReadIndexFiles(); err != nil {
@@ -60,7 +73,11 @@ The index-url is mandatory. Ex "cpackget init --pack-root path/to/mypackroot htt
 		}
 
 		err = installer.UpdatePublicIndex(indexPath, true, initCmdFlags.downloadPdscFiles, false, !initCmdFlags.includeDeprecated, true, true, initCmdFlags.insecureSkipVerify, viper.GetInt("concurrent-downloads"), viper.GetInt("timeout"))
-		return err
+		if err != nil {
+			return err
+		}
+
+		return installer.UpdateRegistries(true, initCmdFlags.insecureSkipVerify, viper.GetInt("timeout"))
 	},
 }
 
@@ -4,7 +4,6 @@
 package commands_test
 
 import (
-	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -25,7 +24,7 @@ var initCmdTests = []TestCase{
 	{
 		name:        "test no parameter given",
 		args:        []string{"init"},
-		expectedErr: errors.New("accepts 1 arg(s), received 0"),
+		expectedErr: errs.ErrMissingIndexURL,
 	},
 	{
 		name:        "test help command",
@@ -5,6 +5,7 @@ package commands
 
 import (
 	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -24,39 +25,104 @@ var listCmdFlags struct {
 
 	// listFilter is a set of words by which to filter listed packs
 	listFilter string
+
+	// outputFormat selects a machine-readable output: json, yaml or table
+	outputFormat string
+
+	// graphFormat prints the requirements as a graph instead: dot or json
+	graphFormat string
+
+	// includeMissing also walks the requirements of packs that are not installed
+	includeMissing bool
+}
+
+// configureListOutput sets the output format before configuring the installer,
+// so that the listing is not mixed with log lines
+func configureListOutput(cmd *cobra.Command, args []string) error {
+	if err := utils.SetOutputFormat(listCmdFlags.outputFormat); err != nil {
+		return err
+	}
+	return configureInstaller(cmd, args)
+}
+
+// structuredOutput tells whether cmd, a list command, prints records or a graph instead of log lines
+func structuredOutput(cmd *cobra.Command) bool {
+	isList := cmd.Name() == "list" || (cmd.HasParent() && cmd.Parent().Name() == "list")
+	return isList && (utils.GetOutputFormat() != "" || listCmdFlags.graphFormat != "")
 }
 
 var ListCmd = &cobra.Command{
-	Use:               "list [--cached|--public|--updates|--deprecated] [--filter <expression>]",
+	Use:               "list [--cached|--public|--updates|--deprecated] [--filter <expression>] [--output json|yaml|table]",
 	Short:             "List installed packs",
 	Long:              "List all installed packs and optionally cached packs or those for which updates are available",
 	Args:              cobra.MaximumNArgs(0),
-	PersistentPreRunE: configureInstaller,
+	PersistentPreRunE: configureListOutput,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return installer.ListInstalledPacks(listCmdFlags.listCached, listCmdFlags.listPublic, listCmdFlags.listUpdates, listCmdFlags.listDeprecated, false, false, listCmdFlags.listFilter)
 	},
 }
 
 var listRequiredCmd = &cobra.Command{
-	Use:               "required",
-	Short:             "List dependencies of installed packs",
-	Long:              "List dependencies of all installed packs, public and local",
+	Use:   "required [--graph dot|json [--include-missing]]",
+	Short: "List dependencies of installed packs",
+	Long: `
+List dependencies of all installed packs, public and local.
+
+  $ cpackget list required --graph dot
+
+  Prints the full transitive graph of requirements instead, with the version
+  ranges and whether each one is satisfied, unsatisfied or missing.
+  Supported formats are "dot" (Graphviz) and "json".
+
+  $ cpackget list required --graph json --include-missing
+
+  Also walks the requirements of the packs that are not installed yet,
+  as long as their PDSC files are available locally.`,
 	Args:              cobra.MaximumNArgs(0),
-	PersistentPreRunE: configureInstaller,
+	PersistentPreRunE: configureListOutput,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if listCmdFlags.graphFormat != "" {
+			return installer.ListRequirementsGraph(listCmdFlags.graphFormat, listCmdFlags.includeMissing, false)
+		}
 		return installer.ListInstalledPacks(listCmdFlags.listCached, listCmdFlags.listPublic, listCmdFlags.listUpdates, listCmdFlags.listDeprecated, true, false, listCmdFlags.listFilter)
 	},
 }
 
+var listDependentsCmd = &cobra.Command{
+	Use:   "dependents <pack>",
+	Short: "List installed packs depending on a pack",
+	Long: `
+List all installed packs that require the given pack, directly or through other installed packs:
+
+  $ cpackget list dependents ARM::CMSIS
+
+  Each entry tells whether the installed versions still satisfy the requirement.
+
+  $ cpackget list dependents ARM::CMSIS@5.9.0
+
+  If a version is given, it is checked against each requirement instead,
+  e.g. to find out what would break after a downgrade.`,
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: configureListOutput,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return installer.ListDependents(args[0], false)
+	},
+}
+
 func init() {
 	ListCmd.Flags().BoolVarP(&listCmdFlags.listCached, "cached", "c", false, "list only cached packs")
 	ListCmd.Flags().BoolVarP(&listCmdFlags.listPublic, "public", "p", false, "list packs in the public index")
 	ListCmd.Flags().BoolVarP(&listCmdFlags.listUpdates, "updates", "u", false, "list packs which have newer versions")
 	ListCmd.Flags().BoolVarP(&listCmdFlags.listDeprecated, "deprecated", "d", false, "list only deprecated packs")
 	ListCmd.Flags().StringVarP(&listCmdFlags.listFilter, "filter", "f", "", "filter results (case sensitive, accepts several expressions)")
+	ListCmd.PersistentFlags().StringVarP(&listCmdFlags.outputFormat, "output", "o", "", "print results in a machine-readable format: json, yaml or table")
+	listRequiredCmd.Flags().StringVarP(&listCmdFlags.graphFormat, "graph", "g", "", "print the requirements as a graph: dot or json")
+	listRequiredCmd.Flags().BoolVar(&listCmdFlags.includeMissing, "include-missing", false, "with --graph, also walk the requirements of packs not installed")
 	ListCmd.AddCommand(listRequiredCmd)
+	ListCmd.AddCommand(listDependentsCmd)
 
 	listRequiredCmd.SetHelpFunc(ListCmd.HelpFunc())
+	listDependentsCmd.SetHelpFunc(ListCmd.HelpFunc())
 	ListCmd.SetHelpFunc(func(command *cobra.Command, strings []string) {
 		err := command.Flags().MarkHidden("concurrent-downloads")
 		_ = command.Flags().MarkHidden("timeout")
@@ -4,12 +4,18 @@
 package commands_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/open-cmsis-pack/cpackget/cmd/commands"
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
 	"github.com/open-cmsis-pack/cpackget/cmd/installer"
 	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	"github.com/stretchr/testify/assert"
 )
 
 var listCmdTests = []TestCase{
@@ -51,6 +57,96 @@ var listCmdTests = []TestCase{
 			t.assert.Nil(localRepository.Write())
 		},
 	},
+	{
+		name:           "test listing installed packs as json",
+		args:           []string{"list", "--output", "json"},
+		createPackRoot: true,
+		expectedStdout: []string{`"vendor": "Vendor"`, `"name": "Pack"`, `"version": "1.2.3"`, `"state": "installed"`},
+		setUpFunc: func(t *TestCase) {
+			packRoot := os.Getenv("CMSIS_PACK_ROOT")
+			packFolder := filepath.Join(packRoot, "Vendor", "Pack", "1.2.3")
+			t.assert.Nil(os.MkdirAll(packFolder, 0700))
+			t.assert.Nil(os.WriteFile(filepath.Join(packFolder, "Vendor.Pack.pdsc"), []byte(""), 0600))
+		},
+	},
+	{
+		name:           "test listing installed packs as yaml",
+		args:           []string{"list", "-o", "yaml"},
+		createPackRoot: true,
+		expectedStdout: []string{"- vendor: Vendor", "  name: Pack", "  state: installed"},
+		setUpFunc: func(t *TestCase) {
+			packRoot := os.Getenv("CMSIS_PACK_ROOT")
+			packFolder := filepath.Join(packRoot, "Vendor", "Pack", "1.2.3")
+			t.assert.Nil(os.MkdirAll(packFolder, 0700))
+			t.assert.Nil(os.WriteFile(filepath.Join(packFolder, "Vendor.Pack.pdsc"), []byte(""), 0600))
+		},
+	},
+	{
+		name:           "test listing installed packs with unknown output format",
+		args:           []string{"list", "--output", "xml"},
+		createPackRoot: true,
+		expectedErr:    errs.ErrUnknownOutputFormat,
+		expErrUnwrap:   true,
+	},
+	{
+		name:           "test listing dependents with no pack given",
+		args:           []string{"list", "dependents"},
+		createPackRoot: true,
+		expectedErr:    errors.New("accepts 1 arg(s), received 0"),
+	},
+	{
+		name:           "test listing dependents",
+		args:           []string{"list", "dependents", "Vendor::Pack"},
+		createPackRoot: true,
+		expectedStdout: []string{"Listing packs depending on Vendor::Pack", "Vendor::Dependent@1.0.0 requires Vendor::Pack@>=1.2.3 (satisfied)"},
+		setUpFunc: func(t *TestCase) {
+			packRoot := os.Getenv("CMSIS_PACK_ROOT")
+			packFolder := filepath.Join(packRoot, "Vendor", "Pack", "1.2.3")
+			t.assert.Nil(os.MkdirAll(packFolder, 0700))
+			t.assert.Nil(os.WriteFile(filepath.Join(packFolder, "Vendor.Pack.pdsc"), []byte(""), 0600))
+			packFolder = filepath.Join(packRoot, "Vendor", "Dependent", "1.0.0")
+			t.assert.Nil(os.MkdirAll(packFolder, 0700))
+			t.assert.Nil(os.WriteFile(filepath.Join(packFolder, "Vendor.Dependent.pdsc"), []byte(`<package>
+  <vendor>Vendor</vendor>
+  <name>Dependent</name>
+  <requirements>
+    <packages>
+      <package vendor="Vendor" name="Pack" version="1.2.3"/>
+    </packages>
+  </requirements>
+</package>`), 0600))
+		},
+	},
+	{
+		name:           "test listing required packs as a graph",
+		args:           []string{"list", "required", "--graph", "dot"},
+		createPackRoot: true,
+		expectedStdout: []string{"digraph requirements {", `"Vendor::Dependent@1.0.0" -> "Vendor::Pack@1.2.3" [label=">=1.2.3"];`},
+		setUpFunc: func(t *TestCase) {
+			packRoot := os.Getenv("CMSIS_PACK_ROOT")
+			packFolder := filepath.Join(packRoot, "Vendor", "Pack", "1.2.3")
+			t.assert.Nil(os.MkdirAll(packFolder, 0700))
+			t.assert.Nil(os.WriteFile(filepath.Join(packFolder, "Vendor.Pack.pdsc"), []byte(""), 0600))
+			packFolder = filepath.Join(packRoot, "Vendor", "Dependent", "1.0.0")
+			t.assert.Nil(os.MkdirAll(packFolder, 0700))
+			t.assert.Nil(os.WriteFile(filepath.Join(packFolder, "Vendor.Dependent.pdsc"), []byte(`<package>
+  <vendor>Vendor</vendor>
+  <name>Dependent</name>
+  <requirements>
+    <packages>
+      <package vendor="Vendor" name="Pack" version="1.2.3"/>
+    </packages>
+  </requirements>
+</package>`), 0600))
+		},
+	},
+	{
+		name:           "test listing required packs as a graph in an unknown format",
+		args:           []string{"list", "required", "--graph", "svg"},
+		createPackRoot: true,
+		expectedErr:    errs.ErrUnknownGraphFormat,
+		expErrUnwrap:   true,
+	},
 	/*  TODO
 	{
 		name:           "test listing required packs",
@@ -74,3 +170,38 @@ var listCmdTests = []TestCase{
 func TestListCmd(t *testing.T) {
 	runTests(t, listCmdTests)
 }
+
+func TestListCmdStructuredOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	localTestingDir := "test_listing_packs_as_json_with_log_lines"
+	assert.Nil(installer.SetPackRoot(localTestingDir, true))
+	installer.UnlockPackRoot()
+	defer os.RemoveAll(localTestingDir)
+	packFolder := filepath.Join(localTestingDir, "Vendor", "Pack", "1.2.3")
+	assert.Nil(os.MkdirAll(packFolder, 0700))
+	assert.Nil(os.WriteFile(filepath.Join(packFolder, "Vendor.Pack.pdsc"), []byte(""), 0600))
+	t.Setenv("CMSIS_PACK_ROOT", localTestingDir)
+
+	// Rebuilding the missing cache index logs a line, which goes to stderr for the output to be parsed
+	for _, args := range [][]string{
+		{"list", "--output", "json"},
+		{"list", "required", "--graph", "json"},
+	} {
+		_ = os.Remove(filepath.Join(localTestingDir, ".Web", "cache.pidx"))
+
+		cmd := commands.NewCli()
+		stdout := bytes.NewBufferString("")
+		stderr := bytes.NewBufferString("")
+		cmd.SetOut(stdout)
+		cmd.SetErr(stderr)
+		cmd.SetArgs(args)
+		assert.Nil(cmd.Execute())
+		resetFlags(cmd)
+
+		var output any
+		assert.Nil(json.Unmarshal(stdout.Bytes(), &output), stdout.String())
+		assert.Contains(stdout.String(), "Vendor")
+		assert.Contains(stderr.String(), "I: (no packs cached)")
+	}
+}
@@ -0,0 +1,57 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands
+
+import (
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var lockCmdFlags struct {
+	// insecureSkipVerify skips TLS certificate verification for HTTPS downloads
+	insecureSkipVerify bool
+}
+
+var LockCmd = &cobra.Command{
+	Use:   "lock [<lock file>]",
+	Short: "Write a lock file of the installed packs",
+	Long: `
+Write a lock file listing every installed pack with its exact version, the URL
+its pack file is downloaded from and the SHA-256 digest of the pack file cached
+in CMSIS_PACK_ROOT/.Download/:
+
+  $ cpackget lock
+
+The lock file defaults to "` + installer.LockFileName + `" in the current directory.
+Install the very same packs in another pack root with:
+
+  $ cpackget add --locked ` + installer.LockFileName + `
+
+Packs installed via PDSC files are not locked.`,
+	Args:              cobra.MaximumNArgs(1),
+	PersistentPreRunE: configureInstaller,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lockFileName := installer.LockFileName
+		if len(args) > 0 {
+			lockFileName = args[0]
+		}
+		// Resolving the URLs might need PDSC files missing from ".Web/"
+		installer.UnlockPackRoot()
+		err := installer.LockPacks(lockFileName, lockCmdFlags.insecureSkipVerify, false)
+		installer.LockPackRoot()
+		return err
+	},
+}
+
+func init() {
+	LockCmd.Flags().BoolVar(&lockCmdFlags.insecureSkipVerify, "insecure-skip-verify", false, "skip verification of server's TLS certificate when downloading PDSC files over HTTPS")
+
+	LockCmd.SetHelpFunc(func(command *cobra.Command, strings []string) {
+		err := command.Flags().MarkHidden("concurrent-downloads")
+		_ = command.Flags().MarkHidden("timeout")
+		log.Debug(err)
+		command.Parent().HelpFunc()(command, strings)
+	})
+}
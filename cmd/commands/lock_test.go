@@ -0,0 +1,46 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+)
+
+var lockCmdTests = []TestCase{
+	{
+		name:        "test help command",
+		args:        []string{"help", "lock"},
+		expectedErr: nil,
+	},
+	{
+		name:           "test lock with too many args",
+		args:           []string{"lock", "a.lock.yaml", "b.lock.yaml"},
+		createPackRoot: true,
+		expectedErr:    errors.New("accepts at most 1 arg(s), received 2"),
+	},
+	{
+		name:           "test locking an empty pack root",
+		args:           []string{"lock", filepath.Join(os.TempDir(), "cpackget-test.lock.yaml")},
+		createPackRoot: true,
+		expectedStdout: []string{"Locked 0 pack(s)"},
+		tearDownFunc: func() {
+			os.Remove(filepath.Join(os.TempDir(), "cpackget-test.lock.yaml"))
+		},
+	},
+	{
+		name:           "test adding locked packs along with other packs",
+		args:           []string{"add", "--locked", "cpackget.lock.yaml", "Vendor.Pack"},
+		createPackRoot: true,
+		expectedErr:    errs.ErrIncorrectCmdArgs,
+	},
+}
+
+func TestLockCmd(t *testing.T) {
+	runTests(t, lockCmdTests)
+}
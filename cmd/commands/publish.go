@@ -0,0 +1,113 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/cryptography"
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/spf13/cobra"
+)
+
+var publishCmdFlags struct {
+	// url is the URL the registry is served at
+	url string
+
+	// checksum writes the .checksum file of the published pack
+	checksum bool
+
+	// hashAlgorithm is the cryptographic hash function of the .checksum file
+	hashAlgorithm string
+
+	// certOnly skips private key usage
+	certOnly bool
+
+	// certPath points to the signer's certificate
+	certPath string
+
+	// keyPath points to the signer's private key
+	keyPath string
+
+	// pgp mode embeds a PGP signature instead
+	pgp bool
+
+	// skipCertValidation skips sanity/safety checks on the provided certificate
+	skipCertValidation bool
+
+	// skipInfo skips displaying certificate info
+	skipInfo bool
+}
+
+var PublishCmd = &cobra.Command{
+	Use:   "publish <local .pack> <registry directory>",
+	Short: "Publish a pack to a registry directory",
+	Long: `Publishes a pack to a registry directory, created if missing:
+
+  $ cpackget publish Vendor.Pack.1.2.3.pack ./registry --url https://packs.vendor.com/
+
+The pack is checked like when being added, then copied into the directory along with its
+unversioned PDSC file, which <url> gets set to the one of the registry. The entry of the
+pack in the "index.pidx" file of the registry gets updated, or inserted. A registry does
+not go back to an older version of a pack.
+
+The url of the registry defaults to the one of its index, or to the file:// URL of the
+directory. The directory can be served by any static web server, or used as is:
+
+  $ cpackget init file:///path/to/registry
+
+With "--checksum", the .checksum file of the pack is written next to it, see "checksum-create".
+With "-k/--private-key" or "-c/--certificate", its signed copy (.pack.signed) is written next
+to it as well, see "signature-create" for the signature modes.`,
+	Args:              cobra.ExactArgs(2),
+	PersistentPreRunE: configureInstallerGlobalCmd,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sign := publishCmdFlags.keyPath != "" || publishCmdFlags.certPath != ""
+		if sign {
+			if err := checkSignatureFlags(publishCmdFlags.certPath, publishCmdFlags.keyPath, publishCmdFlags.certOnly, publishCmdFlags.pgp, publishCmdFlags.skipCertValidation, publishCmdFlags.skipInfo); err != nil {
+				return err
+			}
+		}
+		if publishCmdFlags.checksum && !slices.Contains(cryptography.Hashes[:], publishCmdFlags.hashAlgorithm) {
+			return errs.ErrHashNotSupported
+		}
+
+		registryDir := args[1]
+		packPath, err := installer.PublishPack(args[0], registryDir, utils.StripCredentials(publishCmdFlags.url))
+		if err != nil {
+			return err
+		}
+
+		// The artifacts of a pack published again get replaced
+		if publishCmdFlags.checksum {
+			checksumPath := strings.TrimSuffix(packPath, utils.PackExtension) + "." + strings.ReplaceAll(publishCmdFlags.hashAlgorithm, "-", "") + ".checksum"
+			_ = os.Remove(checksumPath)
+			if err := cryptography.GenerateChecksum(packPath, registryDir, publishCmdFlags.hashAlgorithm); err != nil {
+				return err
+			}
+		}
+		if sign {
+			_ = os.Remove(filepath.Join(registryDir, filepath.Base(packPath)+".signed"))
+			return cryptography.SignPack(packPath, publishCmdFlags.certPath, publishCmdFlags.keyPath, registryDir, Version, publishCmdFlags.certOnly, publishCmdFlags.skipCertValidation, publishCmdFlags.skipInfo)
+		}
+		return nil
+	},
+}
+
+func init() {
+	PublishCmd.Flags().StringVar(&publishCmdFlags.url, "url", "", "url the registry is served at, the one of its index by default")
+	PublishCmd.Flags().BoolVar(&publishCmdFlags.checksum, "checksum", false, "write the .checksum file of the pack")
+	PublishCmd.Flags().StringVarP(&publishCmdFlags.hashAlgorithm, "hash-function", "a", cryptography.Hashes[0], "hash function of the .checksum file")
+	PublishCmd.Flags().BoolVar(&publishCmdFlags.certOnly, "cert-only", false, "certificate-only signature mode")
+	PublishCmd.Flags().StringVarP(&publishCmdFlags.certPath, "certificate", "c", "", "path of the signer's certificate")
+	PublishCmd.Flags().StringVarP(&publishCmdFlags.keyPath, "private-key", "k", "", "path of the signer's private key")
+	PublishCmd.Flags().BoolVar(&publishCmdFlags.pgp, "pgp", false, "PGP signature mode")
+	PublishCmd.Flags().BoolVar(&publishCmdFlags.skipCertValidation, "skip-validation", false, "do not validate certificate")
+	PublishCmd.Flags().BoolVar(&publishCmdFlags.skipInfo, "skip-info", false, "do not display certificate information")
+}
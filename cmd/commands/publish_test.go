@@ -0,0 +1,68 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	"github.com/stretchr/testify/assert"
+)
+
+var publishedRegistryDir = filepath.Join(os.TempDir(), "cpackget-test-registry")
+
+var publishCmdTests = []TestCase{
+	{
+		name:        "test help command",
+		args:        []string{"help", "publish"},
+		expectedErr: nil,
+	},
+	{
+		name:        "test publishing without a registry directory",
+		args:        []string{"publish", packFilePath},
+		expectedErr: errors.New("accepts 2 arg(s), received 1"),
+	},
+	{
+		name:        "test publishing with an unsupported hash function",
+		args:        []string{"publish", packFilePath, publishedRegistryDir, "--checksum", "-a", "sha1"},
+		expectedErr: errs.ErrHashNotSupported,
+	},
+	{
+		name:        "test publishing with cert-only and key flags",
+		args:        []string{"publish", packFilePath, publishedRegistryDir, "--cert-only", "--private-key", "foo"},
+		expectedErr: errs.ErrIncorrectCmdArgs,
+	},
+	{
+		name: "test publishing a pack with its checksum",
+		args: []string{"publish", packFilePath, publishedRegistryDir, "--url", "https://packs.the.vendor/", "--checksum"},
+		validationFunc: func(t *testing.T) {
+			assert.FileExists(t, filepath.Join(publishedRegistryDir, "TheVendor.PublicLocalPack.1.2.3.pack"))
+			assert.FileExists(t, filepath.Join(publishedRegistryDir, "TheVendor.PublicLocalPack.pdsc"))
+			assert.FileExists(t, filepath.Join(publishedRegistryDir, "TheVendor.PublicLocalPack.1.2.3.sha256.checksum"))
+
+			pidxXML := xml.NewPidxXML(filepath.Join(publishedRegistryDir, installer.PublicIndexName), false)
+			assert.Nil(t, pidxXML.Read())
+			tags := pidxXML.FindPdscNameTags(xml.PdscTag{Vendor: "TheVendor", Name: "PublicLocalPack"})
+			if assert.Len(t, tags, 1) {
+				assert.Equal(t, "https://packs.the.vendor/", tags[0].URL)
+			}
+		},
+	},
+	{
+		name: "test publishing a pack again replaces its checksum",
+		args: []string{"publish", packFilePath, publishedRegistryDir, "--checksum"},
+		tearDownFunc: func() {
+			os.RemoveAll(publishedRegistryDir)
+		},
+	},
+}
+
+func TestPublishCmd(t *testing.T) {
+	runTests(t, publishCmdTests)
+}
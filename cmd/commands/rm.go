@@ -5,6 +5,7 @@ package commands
 
 import (
 	"path/filepath"
+	"strings"
 
 	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
 	"github.com/open-cmsis-pack/cpackget/cmd/installer"
@@ -17,6 +18,18 @@ var rmCmdFlags struct {
 	// purge stores the value of "--purge" flag for the "pack rm" command
 	purge bool
 
+	// force removes packs even if other installed packs require them
+	force bool
+
+	// cascade also removes the installed packs requiring the removed ones
+	cascade bool
+
+	// withDeps also removes the dependency packs no longer required
+	withDeps bool
+
+	// dryRun prints what would be done without changing the pack root
+	dryRun bool
+
 	// skipTouch does not touch pack.idx after adding
 	skipTouch bool
 }
@@ -50,14 +63,55 @@ Remove a pack using the reference "Vendor.Pack[.x.y.z]", "Vendor::Pack[@x.y.z]"
 The version "x.y.z" is optional.
 Cache files (i.e. under CMSIS_PACK_ROOT/.Download/)
 are *NOT* removed. If cache files need to be actually removed,
-please use "--purge".`,
+please use "--purge".
+
+A pack that other installed packs require is not removed, as that
+would leave their requirements unsatisfied. Use "--force" to remove
+it anyway, or "--cascade" to also remove the packs depending on it.
+
+Packs installed only to satisfy the requirements of the removed pack
+are kept, unless "--with-deps" is used: in that case they are also
+removed, as long as no explicitly installed pack still requires them.
+
+Use "--dry-run" to print the packs and cache files that would be removed,
+without changing the pack root.`,
 	Args:              cobra.MinimumNArgs(1),
 	PersistentPreRunE: configureInstaller,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		utils.SetSkipTouch(rmCmdFlags.skipTouch)
-		log.Infof("Removing %v", args)
 		var lastErr error
+		if rmCmdFlags.dryRun {
+			plan := installer.NewPlan()
+			for _, packPath := range args {
+				var err error
+				if filepath.Ext(packPath) == utils.PdscExtension {
+					err = installer.PlanRemovePdsc(plan, packPath)
+					if err == errs.ErrPdscEntryNotFound {
+						err = errs.ErrPackNotInstalled
+					}
+				} else {
+					err = installer.PlanRemovePack(plan, packPath, rmCmdFlags.purge, rmCmdFlags.force, rmCmdFlags.cascade, rmCmdFlags.withDeps)
+				}
+				if err != nil {
+					if err != errs.ErrAlreadyLogged {
+						log.Error(err)
+						err = errs.ErrAlreadyLogged
+					}
+					lastErr = err
+				}
+			}
+			plan.Log()
+			return lastErr
+		}
+
+		log.Infof("Removing %v", args)
 		installer.UnlockPackRoot()
+		defer installer.LockPackRoot()
+
+		// All packs get removed, or none of them
+		if err := installer.BeginTransaction("rm " + strings.Join(args, " ")); err != nil {
+			return err
+		}
 		for _, packPath := range args {
 			var err error
 			if filepath.Ext(packPath) == utils.PdscExtension {
@@ -66,7 +120,7 @@ please use "--purge".`,
 					err = errs.ErrPackNotInstalled
 				}
 			} else {
-				_, err = installer.RemovePack(packPath, rmCmdFlags.purge, false)
+				_, err = installer.RemovePack(packPath, rmCmdFlags.purge, rmCmdFlags.force, rmCmdFlags.cascade, rmCmdFlags.withDeps, false)
 			}
 			if err != nil {
 				if err != errs.ErrAlreadyLogged {
@@ -74,17 +128,19 @@ please use "--purge".`,
 					err = errs.ErrAlreadyLogged
 				}
 				lastErr = err
-
+				break
 			}
 		}
-		installer.LockPackRoot()
-
-		return lastErr
+		return installer.EndTransaction(lastErr)
 	},
 }
 
 func init() {
 	RmCmd.Flags().BoolVarP(&rmCmdFlags.purge, "purge", "p", false, "forces deletion of cached pack files")
+	RmCmd.Flags().BoolVar(&rmCmdFlags.force, "force", false, "removes packs even if other installed packs require them")
+	RmCmd.Flags().BoolVar(&rmCmdFlags.cascade, "cascade", false, "also removes installed packs that require the removed ones")
+	RmCmd.Flags().BoolVar(&rmCmdFlags.withDeps, "with-deps", false, "also removes dependency packs no longer required by explicitly installed packs")
+	RmCmd.Flags().BoolVar(&rmCmdFlags.dryRun, "dry-run", false, "prints what would be done without changing the pack root")
 	RmCmd.Flags().BoolVar(&rmCmdFlags.skipTouch, "skip-touch", false, "do not touch pack.idx")
 
 	RmCmd.SetHelpFunc(func(command *cobra.Command, strings []string) {
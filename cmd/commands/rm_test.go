@@ -70,6 +70,67 @@ var rmCmdTests = []TestCase{
 			t.assert.Nil(localRepository.Write())
 		},
 	},
+	{
+		name:           "test removing pack required by another pack",
+		args:           []string{"rm", "Vendor.Pack.1.2.3"},
+		createPackRoot: true,
+		expectedStdout: []string{"Cannot remove Vendor.Pack, it is required by:", "Vendor::Dependent@1.0.0 (requires Vendor::Pack@>=1.2.3)", "--force"},
+		expectedErr:    errs.ErrAlreadyLogged,
+		setUpFunc:      setUpPackWithDependent,
+	},
+	{
+		name:           "test force removing pack required by another pack",
+		args:           []string{"rm", "--force", "Vendor.Pack.1.2.3"},
+		createPackRoot: true,
+		expectedErr:    nil,
+		setUpFunc:      setUpPackWithDependent,
+	},
+	{
+		name:           "test cascade removing pack required by another pack",
+		args:           []string{"rm", "--cascade", "Vendor.Pack.1.2.3"},
+		createPackRoot: true,
+		expectedStdout: []string{"Removing Vendor.Dependent.1.0.0, which depends on Vendor.Pack"},
+		expectedErr:    nil,
+		setUpFunc:      setUpPackWithDependent,
+	},
+	{
+		name:           "test dry run removing pack required by another pack",
+		args:           []string{"rm", "--dry-run", "Vendor.Pack.1.2.3"},
+		createPackRoot: true,
+		expectedStdout: []string{"Cannot remove Vendor.Pack, it is required by:"},
+		expectedErr:    errs.ErrAlreadyLogged,
+		setUpFunc:      setUpPackWithDependent,
+	},
+	{
+		name:           "test dry run cascade removing pack required by another pack",
+		args:           []string{"rm", "--dry-run", "--cascade", "Vendor.Pack.1.2.3"},
+		createPackRoot: true,
+		expectedStdout: []string{"Planned actions:", "- remove Vendor::Dependent@1.0.0 (depends on Vendor.Pack)", "- remove Vendor::Pack@1.2.3"},
+		expectedErr:    nil,
+		setUpFunc:      setUpPackWithDependent,
+	},
+}
+
+// setUpPackWithDependent installs Vendor.Pack.1.2.3 and Vendor.Dependent.1.0.0, which requires it
+func setUpPackWithDependent(t *TestCase) {
+	packRoot := os.Getenv("CMSIS_PACK_ROOT")
+	packFolder := filepath.Join(packRoot, "Vendor", "Pack", "1.2.3")
+	t.assert.Nil(os.MkdirAll(packFolder, 0700))
+	t.assert.Nil(os.WriteFile(filepath.Join(packFolder, "Vendor.Pack.pdsc"), []byte(""), 0600))
+	t.assert.Nil(os.WriteFile(filepath.Join(packRoot, ".Local", "Vendor.Pack.pdsc"), []byte(""), 0600))
+	pdsc := []byte(`<package>
+  <vendor>Vendor</vendor>
+  <name>Dependent</name>
+  <requirements>
+    <packages>
+      <package vendor="Vendor" name="Pack" version="1.2.3"/>
+    </packages>
+  </requirements>
+</package>`)
+	packFolder = filepath.Join(packRoot, "Vendor", "Dependent", "1.0.0")
+	t.assert.Nil(os.MkdirAll(packFolder, 0700))
+	t.assert.Nil(os.WriteFile(filepath.Join(packFolder, "Vendor.Dependent.pdsc"), pdsc, 0600))
+	t.assert.Nil(os.WriteFile(filepath.Join(packRoot, ".Local", "Vendor.Dependent.pdsc"), pdsc, 0600))
 }
 
 func TestRmCmd(t *testing.T) {
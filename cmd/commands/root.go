@@ -7,8 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/open-cmsis-pack/cpackget/cmd/installer"
@@ -23,7 +26,10 @@ var AllCommands = []*cobra.Command{
 	InitCmd,
 	AddCmd,
 	RmCmd,
+	AutoremoveCmd,
 	ListCmd,
+	InfoCmd,
+	LockCmd,
 	UpdateIndexCmd,
 	UpdateCmd,
 	ChecksumCreateCmd,
@@ -31,13 +37,25 @@ var AllCommands = []*cobra.Command{
 	SignatureCreateCmd,
 	SignatureVerifyCmd,
 	ConnectionCmd,
+	ConfigCmd,
+	IndexCmd,
+	PublishCmd,
+	ServeCmd,
 }
 
+// packRootWriters are the commands changing the pack root, which lock it for the time they run
+var packRootWriters = []string{"init", "add", "rm", "autoremove", "update", "update-index", "lock"}
+
 // createPackRoot is a flag that determines if the pack root should be created or not
 var createPackRoot bool
 
 var viper *viperType.Viper
 
+func init() {
+	// Let other cpackget runs at the pack root once the command is done, even if it failed
+	cobra.OnFinalize(installer.ReleasePackRootLock)
+}
+
 func configureInstallerGlobalCmd(cmd *cobra.Command, args []string) error {
 	verbosiness := viper.GetBool("verbose")
 	quiet := viper.GetBool("quiet")
@@ -47,6 +65,11 @@ func configureInstallerGlobalCmd(cmd *cobra.Command, args []string) error {
 
 	log.SetLevel(log.InfoLevel)
 	log.SetOutput(cmd.OutOrStdout())
+	utils.SetOutputWriter(cmd.OutOrStdout())
+	if structuredOutput(cmd) {
+		// Keep the log lines out of the output, for it to be parsed
+		log.SetOutput(cmd.ErrOrStderr())
+	}
 
 	if quiet {
 		log.SetLevel(log.ErrorLevel)
@@ -56,7 +79,43 @@ func configureInstallerGlobalCmd(cmd *cobra.Command, args []string) error {
 		log.SetLevel(log.DebugLevel)
 	}
 
-	return nil
+	if err := checkConfig(cmd); err != nil {
+		return err
+	}
+	applyConfigToFlags(cmd)
+	if err := configureCredentials(); err != nil {
+		return err
+	}
+
+	utils.SetDownloadRetries(viper.GetInt("retries"))
+	utils.SetOffline(viper.GetBool("offline"))
+
+	// Keep enough connections open for concurrent downloads from the same host
+	httpClientOptions := utils.GetHTTPClientOptions()
+	httpClientOptions.MaxIdleConnsPerHost = max(viper.GetInt("concurrent-downloads"), http.DefaultMaxIdleConnsPerHost)
+	httpClientOptions.Proxy = nil
+	if proxy := viper.GetString("proxy"); proxy != "" {
+		// Already checked by checkConfig
+		httpClientOptions.Proxy, _ = url.Parse(proxy)
+	}
+	if err := configureTLS(&httpClientOptions); err != nil {
+		return err
+	}
+	utils.SetHTTPClientOptions(httpClientOptions)
+
+	registries, err := getConfigRegistries()
+	if err != nil {
+		return err
+	}
+	return installer.SetRegistries(registries)
+}
+
+// stripCredentials removes the credentials from the URLs in args, keeping them for their host,
+// so that they end up neither in the logs nor in the pack root
+func stripCredentials(args []string) {
+	for i := range args {
+		args[i] = utils.StripCredentials(args[i])
+	}
 }
 
 // configureInstaller configures cpackget installer for adding or removing pack/pdsc
@@ -69,6 +128,11 @@ func configureInstaller(cmd *cobra.Command, args []string) error {
 	targetPackRoot := viper.GetString("pack-root")
 	checkConnection := viper.GetBool("check-connection") // TODO: never set
 
+	// Dry runs only read the pack root: they neither initialize, lock nor recover it
+	if isDryRun(cmd) {
+		return installer.SetPackRoot(targetPackRoot, false)
+	}
+
 	if targetPackRoot == installer.GetDefaultCmsisPackRoot() {
 		// If using the default pack root path and the public index is not found, initialize it
 		if !checkConnection && !utils.FileExists(filepath.Join(targetPackRoot, ".Web", installer.PublicIndexName)) {
@@ -76,10 +140,17 @@ func configureInstaller(cmd *cobra.Command, args []string) error {
 			if err != nil {
 				return err
 			}
+			if err := installer.AcquirePackRootLock(viper.GetInt("lock-timeout")); err != nil {
+				return err
+			}
 			// Exclude index updating commands to not double update
 			if cmd.Name() != "init" && cmd.Name() != "index" && cmd.Name() != "update-index" && cmd.Name() != "list" {
 				installer.UnlockPackRoot()
-				err = installer.UpdatePublicIndex(installer.ActualPublicIndex, true, false, false, true, false, true, false, 0, 0)
+				indexURL := viper.GetString("index-url")
+				if indexURL == "" {
+					indexURL = installer.ActualPublicIndex
+				}
+				err = installer.UpdatePublicIndex(indexURL, true, false, false, true, false, true, false, 0, 0)
 				if err != nil {
 					return err
 				}
@@ -102,7 +173,24 @@ func configureInstaller(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return nil
+	// Commands only reading the pack root work on the ones they cannot write to, e.g. shared ones
+	if !slices.Contains(packRootWriters, cmd.Name()) {
+		return nil
+	}
+
+	// Wait for other cpackget runs on the same pack root, released once the command is done
+	if err := installer.AcquirePackRootLock(viper.GetInt("lock-timeout")); err != nil {
+		return err
+	}
+
+	// Roll back whatever a previous, interrupted run left half done
+	return installer.RecoverTransaction()
+}
+
+// isDryRun tells whether cmd was asked to only print what it would do
+func isDryRun(cmd *cobra.Command) bool {
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	return err == nil && dryRun
 }
 
 var flags struct {
@@ -177,12 +265,23 @@ func NewCli() *cobra.Command {
 	rootCmd.PersistentFlags().StringP("pack-root", "R", defaultPackRoot, "Specifies pack root folder. Defaults to CMSIS_PACK_ROOT environment variable")
 	rootCmd.PersistentFlags().UintP("concurrent-downloads", "C", 20, "Number of concurrent batch downloads. Set to 0 to disable concurrency")
 	rootCmd.PersistentFlags().UintP("timeout", "T", 0, "Set maximum duration (in seconds) of a download. Disabled by default")
+	rootCmd.PersistentFlags().Uint("lock-timeout", 0, "Set maximum duration (in seconds) to wait for other cpackget runs on the pack root. Waits indefinitely by default")
+	rootCmd.PersistentFlags().Bool("offline", false, "Never access the network: packs are only resolved from the pack root (.Web, .Local and .Download) and operations needing to download a file fail")
+	rootCmd.PersistentFlags().StringSlice("ca-certificates", nil, "PEM files of certificate authorities to trust on top of the ones of the system, e.g. the one of a TLS-intercepting proxy")
+	rootCmd.PersistentFlags().Uint("retries", 3, "Number of times a download failing on a transient error is retried, with an increasing delay. Set to 0 to disable retries")
 	_ = viper.BindPFlag("concurrent-downloads", rootCmd.PersistentFlags().Lookup("concurrent-downloads"))
 	_ = viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	_ = viper.BindPFlag("lock-timeout", rootCmd.PersistentFlags().Lookup("lock-timeout"))
+	_ = viper.BindPFlag("retries", rootCmd.PersistentFlags().Lookup("retries"))
+	_ = viper.BindPFlag("offline", rootCmd.PersistentFlags().Lookup("offline"))
+	_ = viper.BindPFlag("ca-certificates", rootCmd.PersistentFlags().Lookup("ca-certificates"))
 	_ = viper.BindPFlag("pack-root", rootCmd.PersistentFlags().Lookup("pack-root"))
 	_ = viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 	_ = viper.BindPFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
 
+	// Settings not given on the command line come from the environment, then from the configuration files
+	configLayers, configLoadErr = loadConfig(viper)
+
 	for _, cmd := range AllCommands {
 		rootCmd.AddCommand(cmd)
 	}
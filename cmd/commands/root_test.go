@@ -5,6 +5,7 @@ package commands_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -16,9 +17,11 @@ import (
 	"testing"
 
 	"github.com/open-cmsis-pack/cpackget/cmd/commands"
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
 	"github.com/open-cmsis-pack/cpackget/cmd/installer"
 	"github.com/open-cmsis-pack/cpackget/cmd/utils"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 )
@@ -130,6 +133,65 @@ var rootCmdTests = []TestCase{
 	},
 }
 
+// lockPackRoot writes the lock file of the pack root as if held by another cpackget run still going on
+func lockPackRoot(t *TestCase) {
+	hostname, _ := os.Hostname()
+	contents, _ := json.Marshal(map[string]any{"pid": os.Getppid(), "hostname": hostname, "command": "cpackget add", "acquired": "2024-01-01T00:00:00Z"})
+	t.assert.Nil(os.WriteFile(filepath.Join(os.Getenv("CMSIS_PACK_ROOT"), ".Local", installer.PackRootLockFileName), contents, 0600))
+}
+
+var packRootLockCmdTests = []TestCase{
+	{
+		name:           "test listing packs while another run holds the pack root",
+		args:           []string{"list"},
+		createPackRoot: true,
+		env:            map[string]string{"CPACKGET_LOCK_TIMEOUT": "1"},
+		setUpFunc:      lockPackRoot,
+		tearDownFunc: func() {
+			os.Unsetenv("CPACKGET_LOCK_TIMEOUT")
+		},
+	},
+	{
+		name:           "test showing a pack while another run holds the pack root",
+		args:           []string{"info", "TheVendor.PublicLocalPack"},
+		createPackRoot: true,
+		env:            map[string]string{"CPACKGET_LOCK_TIMEOUT": "1"},
+		setUpFunc:      lockPackRoot,
+		expectedErr:    errs.ErrPdscFileNotFound,
+		expErrUnwrap:   true,
+		tearDownFunc: func() {
+			os.Unsetenv("CPACKGET_LOCK_TIMEOUT")
+		},
+	},
+	{
+		name:           "test removing a pack while another run holds the pack root",
+		args:           []string{"rm", "TheVendor.PublicLocalPack"},
+		createPackRoot: true,
+		env:            map[string]string{"CPACKGET_LOCK_TIMEOUT": "1"},
+		setUpFunc:      lockPackRoot,
+		expectedErr:    errs.ErrPackRootLocked,
+		expErrUnwrap:   true,
+		tearDownFunc: func() {
+			os.Unsetenv("CPACKGET_LOCK_TIMEOUT")
+		},
+	},
+	{
+		name:           "test dry run updating packs while another run holds the pack root",
+		args:           []string{"update", "--dry-run"},
+		createPackRoot: true,
+		env:            map[string]string{"CPACKGET_LOCK_TIMEOUT": "1"},
+		setUpFunc:      lockPackRoot,
+		expectedStdout: []string{"Dry run: nothing to do"},
+		tearDownFunc: func() {
+			os.Unsetenv("CPACKGET_LOCK_TIMEOUT")
+		},
+	},
+}
+
+func TestPackRootLockCmd(t *testing.T) {
+	runTests(t, packRootLockCmdTests)
+}
+
 func runTests(t *testing.T, tests []TestCase) {
 	assert := assert.New(t)
 
@@ -191,14 +253,7 @@ func runTests(t *testing.T, tests []TestCase) {
 			// Otherwise, the first time a command uses a flag,
 			// it will taint the others.
 			// Ref: https://github.com/spf13/cobra/issues/1488
-			for _, c := range cmd.Commands() {
-				c.Flags().VisitAll(func(f *pflag.Flag) {
-					if f.Changed {
-						_ = f.Value.Set(f.DefValue)
-						f.Changed = false
-					}
-				})
-			}
+			resetFlags(cmd)
 
 			// Reset the environment variables to their original values
 			// after the command execution
@@ -234,6 +289,19 @@ func runTests(t *testing.T, tests []TestCase) {
 	}
 }
 
+// resetFlags resets the flags of all subcommands of cmd
+func resetFlags(cmd *cobra.Command) {
+	for _, c := range cmd.Commands() {
+		c.Flags().VisitAll(func(f *pflag.Flag) {
+			if f.Changed {
+				_ = f.Value.Set(f.DefValue)
+				f.Changed = false
+			}
+		})
+		resetFlags(c)
+	}
+}
+
 func TestRootCmd(t *testing.T) {
 	runTests(t, rootCmdTests)
 }
@@ -0,0 +1,42 @@
+//go:build !windows
+
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands_test
+
+import (
+	"os"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+)
+
+var readOnlyPackRootCmdTests = []TestCase{
+	{
+		name:           "test listing packs of a read-only pack root",
+		args:           []string{"list", "--cached"},
+		createPackRoot: true,
+		setUpFunc: func(t *TestCase) {
+			utils.SetReadOnlyR(os.Getenv("CMSIS_PACK_ROOT"))
+		},
+	},
+	{
+		name:           "test showing a pack of a read-only pack root",
+		args:           []string{"info", "Vendor.Pack"},
+		createPackRoot: true,
+		expectedErr:    errs.ErrPdscFileNotFound,
+		expErrUnwrap:   true,
+		setUpFunc: func(t *TestCase) {
+			utils.SetReadOnlyR(os.Getenv("CMSIS_PACK_ROOT"))
+		},
+	},
+}
+
+func TestReadOnlyPackRootCmd(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("the permissions of the pack root do not apply to root")
+	}
+	runTests(t, readOnlyPackRootCmdTests)
+}
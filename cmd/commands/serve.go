@@ -0,0 +1,185 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ServeBasicAuthEnvVar gives the credentials required by "serve", as "<username>:<password>", when
+// not given with "--basic-auth", which other users of the machine may see
+const ServeBasicAuthEnvVar = "CPACKGET_SERVE_BASIC_AUTH"
+
+var serveCmdFlags struct {
+	// dir is the registry directory to serve
+	dir string
+
+	// addr is the address to listen on, as host:port
+	addr string
+
+	// basicAuth is the username and password required from clients, as <username>:<password>
+	basicAuth string
+
+	// tlsCert is the certificate file of the server, or where to write the self-signed one
+	tlsCert string
+
+	// tlsKey is the private key file of the server
+	tlsKey string
+
+	// selfSigned generates a self-signed certificate
+	selfSigned bool
+}
+
+var ServeCmd = &cobra.Command{
+	Use:   "serve [--dir <registry directory>] [--addr <host:port>]",
+	Short: "Serve a registry directory over HTTP(S)",
+	Long: `Serves the index file, PDSC files and packs of a registry directory, e.g. one written
+by "publish", over HTTP, or HTTPS, until interrupted:
+
+  $ cpackget serve --dir ./registry --addr :8080
+  $ cpackget init http://localhost:8080/index.pidx
+
+Range requests, for downloads to resume, and conditional requests are supported. Directories
+are not listed, and hidden files are not served. The URLs of the index and PDSC files must be
+the ones the registry is served at, see "publish --url".
+
+Clients must authenticate with the username and password given as "<username>:<password>" by
+"--basic-auth", or the ` + ServeBasicAuthEnvVar + ` environment variable, if any. See "Authenticating to
+private pack servers" in the README for clients to pass them. Unless listening on a loopback address,
+e.g. "--addr localhost:8080", credentials require HTTPS.
+
+HTTPS is served with the certificate and private key given by "--tls-cert" and "--tls-key", or
+with a certificate generated by "--self-signed" for localhost and the name of the machine,
+written to the "--tls-cert" file if given, for clients to trust with "--ca-certificates".`,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: configureInstallerGlobalCmd,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		server := &installer.RegistryServer{Dir: serveCmdFlags.dir}
+		if !utils.DirExists(server.Dir) {
+			return fmt.Errorf("%q: %w", server.Dir, errs.ErrDirectoryNotFound)
+		}
+		if !utils.FileExists(filepath.Join(server.Dir, installer.PublicIndexName)) {
+			log.Warnf("%q has no %s", server.Dir, installer.PublicIndexName)
+		}
+
+		basicAuth := serveCmdFlags.basicAuth
+		if basicAuth == "" {
+			basicAuth = os.Getenv(ServeBasicAuthEnvVar)
+		}
+		if basicAuth != "" {
+			var found bool
+			if server.Username, server.Password, found = strings.Cut(basicAuth, ":"); !found || server.Username == "" {
+				log.Error("Specify the credentials as <username>:<password>")
+				return errs.ErrIncorrectCmdArgs
+			}
+		}
+
+		certificate, err := serveCertificate()
+		if err != nil {
+			return err
+		}
+		server.Certificate = certificate
+
+		if server.Username != "" && server.Certificate == nil && !isLoopbackAddr(serveCmdFlags.addr) {
+			log.Error("Credentials would be sent in clear text: serve HTTPS with --tls-cert and --tls-key, or --self-signed, or listen on a loopback address")
+			return errs.ErrIncorrectCmdArgs
+		}
+
+		listener, err := net.Listen("tcp", serveCmdFlags.addr)
+		if err != nil {
+			return err
+		}
+		scheme := "http"
+		if server.Certificate != nil {
+			scheme = "https"
+		}
+		host, port, _ := net.SplitHostPort(listener.Addr().String())
+		if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+			host = "localhost"
+		}
+		log.Infof("Serving %q at %s://%s/, press Ctrl+C to stop", server.Dir, scheme, net.JoinHostPort(host, port))
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return server.Serve(ctx, listener)
+	},
+}
+
+// isLoopbackAddr tells whether addr, as <host>:<port>, only listens on a loopback address
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serveCertificate returns the certificate the server is given, or generates, if any
+func serveCertificate() (*tls.Certificate, error) {
+	if serveCmdFlags.selfSigned {
+		if serveCmdFlags.tlsKey != "" {
+			log.Error("--tls-key should not be provided with a self-signed certificate")
+			return nil, errs.ErrIncorrectCmdArgs
+		}
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if hostname, err := os.Hostname(); err == nil {
+			hosts = append(hosts, hostname)
+		}
+		if host, _, err := net.SplitHostPort(serveCmdFlags.addr); err == nil && host != "" {
+			hosts = append(hosts, host)
+		}
+		certificate, certPEM, err := installer.GenerateSelfSignedCertificate(hosts)
+		if err != nil {
+			return nil, err
+		}
+		if serveCmdFlags.tlsCert != "" {
+			if err := os.WriteFile(serveCmdFlags.tlsCert, certPEM, 0600); err != nil {
+				return nil, err
+			}
+			log.Infof("Wrote the self-signed certificate to %q, for clients to trust with --ca-certificates", serveCmdFlags.tlsCert)
+		}
+		return &certificate, nil
+	}
+
+	if serveCmdFlags.tlsCert == "" && serveCmdFlags.tlsKey == "" {
+		return nil, nil
+	}
+	if serveCmdFlags.tlsCert == "" || serveCmdFlags.tlsKey == "" {
+		log.Error("Specify both --tls-cert and --tls-key, or --self-signed")
+		return nil, errs.ErrIncorrectCmdArgs
+	}
+	certificate, err := tls.LoadX509KeyPair(serveCmdFlags.tlsCert, serveCmdFlags.tlsKey)
+	if err != nil {
+		log.Error(err)
+		return nil, fmt.Errorf("%q: %w", serveCmdFlags.tlsCert, errs.ErrInvalidCertificateFile)
+	}
+	return &certificate, nil
+}
+
+func init() {
+	ServeCmd.Flags().StringVar(&serveCmdFlags.dir, "dir", ".", "registry directory to serve")
+	ServeCmd.Flags().StringVar(&serveCmdFlags.addr, "addr", ":8080", "address to listen on, as <host>:<port>")
+	ServeCmd.Flags().StringVar(&serveCmdFlags.basicAuth, "basic-auth", "", "credentials required from clients, as <username>:<password>")
+	ServeCmd.Flags().StringVar(&serveCmdFlags.tlsCert, "tls-cert", "", "PEM certificate file of the server, or where to write the self-signed one")
+	ServeCmd.Flags().StringVar(&serveCmdFlags.tlsKey, "tls-key", "", "PEM private key file of the server")
+	ServeCmd.Flags().BoolVar(&serveCmdFlags.selfSigned, "self-signed", false, "serve HTTPS with a generated self-signed certificate")
+}
@@ -0,0 +1,71 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package commands_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/commands"
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+)
+
+var serveCmdTests = []TestCase{
+	{
+		name:        "test help command",
+		args:        []string{"help", "serve"},
+		expectedErr: nil,
+	},
+	{
+		name:        "test serving with arguments",
+		args:        []string{"serve", testingDir},
+		expectedErr: errors.New(`unknown command "` + testingDir + `" for "cpackget serve"`),
+	},
+	{
+		name:         "test serving a missing directory",
+		args:         []string{"serve", "--dir", "does-not-exist"},
+		expectedErr:  errs.ErrDirectoryNotFound,
+		expErrUnwrap: true,
+	},
+	{
+		name:        "test serving with malformed credentials",
+		args:        []string{"serve", "--dir", testingDir},
+		env:         map[string]string{commands.ServeBasicAuthEnvVar: "no-password"},
+		expectedErr: errs.ErrIncorrectCmdArgs,
+		tearDownFunc: func() {
+			os.Unsetenv(commands.ServeBasicAuthEnvVar)
+		},
+	},
+	{
+		name:        "test serving with credentials over HTTP",
+		args:        []string{"serve", "--dir", testingDir, "--addr", ":0"},
+		env:         map[string]string{commands.ServeBasicAuthEnvVar: "user:password"},
+		expectedErr: errs.ErrIncorrectCmdArgs,
+		tearDownFunc: func() {
+			os.Unsetenv(commands.ServeBasicAuthEnvVar)
+		},
+	},
+	{
+		name:        "test serving a self-signed certificate with a key",
+		args:        []string{"serve", "--dir", testingDir, "--self-signed", "--tls-key", "server.key"},
+		expectedErr: errs.ErrIncorrectCmdArgs,
+	},
+	{
+		name:        "test serving a certificate without its key",
+		args:        []string{"serve", "--dir", testingDir, "--tls-cert", "server.crt"},
+		expectedErr: errs.ErrIncorrectCmdArgs,
+	},
+	{
+		name:         "test serving an invalid certificate",
+		args:         []string{"serve", "--dir", testingDir, "--tls-cert", filepath.Join(testingDir, "SamplePublicIndex.pidx"), "--tls-key", filepath.Join(testingDir, "SamplePublicIndex.pidx")},
+		expectedErr:  errs.ErrInvalidCertificateFile,
+		expErrUnwrap: true,
+	},
+}
+
+func TestServeCmd(t *testing.T) {
+	runTests(t, serveCmdTests)
+}
@@ -65,6 +65,10 @@ func init() {
 		err := command.Flags().MarkHidden("pack-root")
 		_ = command.Flags().MarkHidden("concurrent-downloads")
 		_ = command.Flags().MarkHidden("timeout")
+		_ = command.Flags().MarkHidden("lock-timeout")
+		_ = command.Flags().MarkHidden("retries")
+		_ = command.Flags().MarkHidden("offline")
+		_ = command.Flags().MarkHidden("ca-certificates")
 		log.Debug(err)
 		command.Parent().HelpFunc()(command, strings)
 	})
@@ -99,40 +103,8 @@ The referenced pack must be in its original/compressed form (.pack), and be pres
 	Args:              cobra.ExactArgs(1),
 	PersistentPreRunE: configureInstallerGlobalCmd,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if signatureCreateflags.keyPath == "" {
-			if !signatureCreateflags.certOnly {
-				log.Error("Specify private key file with the -k/--key flag")
-				return errs.ErrIncorrectCmdArgs
-			}
-		} else {
-			if signatureCreateflags.certOnly {
-				log.Error("-k/--key should not be provided in certificate-only mode")
-				return errs.ErrIncorrectCmdArgs
-			}
-		}
-		if signatureCreateflags.certPath == "" {
-			if !signatureCreateflags.pgp {
-				log.Error("Specify PEM certificate with the -c/--certificate flag")
-				return errs.ErrIncorrectCmdArgs
-			}
-		}
-		if signatureCreateflags.pgp {
-			if signatureCreateflags.certOnly {
-				log.Error("Both PGP and cert-only modes specified")
-				return errs.ErrIncorrectCmdArgs
-			}
-			if signatureCreateflags.certPath != "" {
-				log.Error("PGP signature scheme does not need a x509 certificate")
-				return errs.ErrIncorrectCmdArgs
-			}
-			if signatureCreateflags.skipCertValidation {
-				log.Error("PGP signature scheme does not validate certificates (--skip-validation)")
-				return errs.ErrIncorrectCmdArgs
-			}
-			if signatureCreateflags.skipInfo {
-				log.Error("PGP signature scheme does not display certificate info (--skip-info)")
-				return errs.ErrIncorrectCmdArgs
-			}
+		if err := checkSignatureFlags(signatureCreateflags.certPath, signatureCreateflags.keyPath, signatureCreateflags.certOnly, signatureCreateflags.pgp, signatureCreateflags.skipCertValidation, signatureCreateflags.skipInfo); err != nil {
+			return err
 		}
 		return cryptography.SignPack(args[0], signatureCreateflags.certPath, signatureCreateflags.keyPath, signatureCreateflags.outputDir, Version, signatureCreateflags.certOnly, signatureCreateflags.skipCertValidation, signatureCreateflags.skipInfo)
 	},
@@ -173,3 +145,43 @@ The referenced pack must be in its original/compressed form (.pack), and be pres
 		return cryptography.VerifyPackSignature(args[0], signatureVerifyflags.pgpKey, Version, signatureVerifyflags.export, signatureVerifyflags.skipCertValidation, signatureVerifyflags.skipInfo)
 	},
 }
+
+// checkSignatureFlags makes sure the flags given to sign a pack select one signature mode
+func checkSignatureFlags(certPath, keyPath string, certOnly, pgp, skipCertValidation, skipInfo bool) error {
+	if keyPath == "" {
+		if !certOnly {
+			log.Error("Specify private key file with the -k/--key flag")
+			return errs.ErrIncorrectCmdArgs
+		}
+	} else {
+		if certOnly {
+			log.Error("-k/--key should not be provided in certificate-only mode")
+			return errs.ErrIncorrectCmdArgs
+		}
+	}
+	if certPath == "" {
+		if !pgp {
+			log.Error("Specify PEM certificate with the -c/--certificate flag")
+			return errs.ErrIncorrectCmdArgs
+		}
+	}
+	if pgp {
+		if certOnly {
+			log.Error("Both PGP and cert-only modes specified")
+			return errs.ErrIncorrectCmdArgs
+		}
+		if certPath != "" {
+			log.Error("PGP signature scheme does not need a x509 certificate")
+			return errs.ErrIncorrectCmdArgs
+		}
+		if skipCertValidation {
+			log.Error("PGP signature scheme does not validate certificates (--skip-validation)")
+			return errs.ErrIncorrectCmdArgs
+		}
+		if skipInfo {
+			log.Error("PGP signature scheme does not display certificate info (--skip-info)")
+			return errs.ErrIncorrectCmdArgs
+		}
+	}
+	return nil
+}
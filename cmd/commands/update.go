@@ -4,6 +4,8 @@
 package commands
 
 import (
+	"strings"
+
 	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
 	"github.com/open-cmsis-pack/cpackget/cmd/installer"
 	"github.com/open-cmsis-pack/cpackget/cmd/utils"
@@ -29,6 +31,9 @@ var updateCmdFlags struct {
 
 	// insecureSkipVerify skips TLS certificate verification for HTTPS downloads
 	insecureSkipVerify bool
+
+	// dryRun prints what would be done without changing the pack root
+	dryRun bool
 }
 
 var UpdateCmd = &cobra.Command{
@@ -47,14 +52,17 @@ Update a pack using the following "<pack>" specification or using packs provided
 
   The pack can be local file or hosted somewhere else on the Internet.
   If it's hosted somewhere, cpackget will first download it then extract all pack files into "CMSIS_PACK_ROOT/<vendor>/<packName>/<x.y.z>/"
-  If "-f" is used, cpackget will call "cpackget update pack" on each URL specified in the <packs list> file.`,
+  If "-f" is used, cpackget will call "cpackget update pack" on each URL specified in the <packs list> file.
+
+  Use "--dry-run" to print the packs that would be downloaded and extracted, the licenses that
+  would be prompted and the requirements that would be installed, without changing the pack root.`,
 	Args: cobra.MinimumNArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		utils.SetEncodedProgress(updateCmdFlags.encodedProgress)
 		utils.SetSkipTouch(updateCmdFlags.skipTouch)
 
-		createPackRoot = true
+		createPackRoot = !updateCmdFlags.dryRun
 		err := configureInstaller(cmd, args)
 		if err != nil {
 			return err
@@ -65,6 +73,7 @@ Update a pack using the following "<pack>" specification or using packs provided
 			return err
 		}
 		args = append(args, files...)
+		stripCredentials(args)
 
 		var lastErr error
 
@@ -72,8 +81,14 @@ Update a pack using the following "<pack>" specification or using packs provided
 			if updateCmdFlags.packsListFileName != "" {
 				return nil // nothing to do
 			}
+			if updateCmdFlags.dryRun {
+				plan := installer.NewPlan()
+				err := installer.PlanUpdatePack(plan, "", !updateCmdFlags.skipEula, updateCmdFlags.noRequirements, updateCmdFlags.insecureSkipVerify, false)
+				plan.Log()
+				return err
+			}
 			installer.UnlockPackRoot()
-			err := installer.UpdatePack("", !updateCmdFlags.skipEula, updateCmdFlags.noRequirements, false, updateCmdFlags.insecureSkipVerify, false, viper.GetInt("timeout"))
+			err := installer.UpdatePack("", !updateCmdFlags.skipEula, updateCmdFlags.noRequirements, false, updateCmdFlags.insecureSkipVerify, false, viper.GetInt("concurrent-downloads"), viper.GetInt("timeout"))
 			if err != nil {
 				lastErr = err
 				if !errs.AlreadyLogged(err) {
@@ -85,18 +100,44 @@ Update a pack using the following "<pack>" specification or using packs provided
 		}
 
 		log.Debugf("Specified packs %v", args)
+		if updateCmdFlags.dryRun {
+			plan := installer.NewPlan()
+			for _, packPath := range args {
+				err := installer.PlanUpdatePack(plan, packPath, !updateCmdFlags.skipEula, updateCmdFlags.noRequirements, updateCmdFlags.insecureSkipVerify, false)
+				if err != nil {
+					lastErr = err
+					if !errs.AlreadyLogged(err) {
+						log.Error(err)
+					}
+					break
+				}
+			}
+			plan.Log()
+			return lastErr
+		}
+
 		installer.UnlockPackRoot()
+		defer installer.LockPackRoot()
+
+		// All packs get updated, or none of them
+		if err := installer.BeginTransaction("update " + strings.Join(args, " ")); err != nil {
+			return err
+		}
+		err = installer.PrefetchPacks(args, true, false, updateCmdFlags.insecureSkipVerify, false, viper.GetInt("concurrent-downloads"), viper.GetInt("timeout"))
+		if err != nil {
+			return installer.EndTransaction(err)
+		}
 		for _, packPath := range args {
-			err := installer.UpdatePack(packPath, !updateCmdFlags.skipEula, updateCmdFlags.noRequirements, false, updateCmdFlags.insecureSkipVerify, false, viper.GetInt("timeout"))
+			err := installer.UpdatePack(packPath, !updateCmdFlags.skipEula, updateCmdFlags.noRequirements, false, updateCmdFlags.insecureSkipVerify, false, viper.GetInt("concurrent-downloads"), viper.GetInt("timeout"))
 			if err != nil {
 				lastErr = err
 				if !errs.AlreadyLogged(err) {
 					log.Error(err)
 				}
+				break
 			}
 		}
-		installer.LockPackRoot()
-		return lastErr
+		return installer.EndTransaction(lastErr)
 	},
 }
 
@@ -106,13 +147,6 @@ func init() {
 	UpdateCmd.Flags().StringVarP(&updateCmdFlags.packsListFileName, "packs-list-filename", "f", "", "specifies a file listing packs urls, one per line")
 	UpdateCmd.Flags().BoolVar(&updateCmdFlags.skipTouch, "skip-touch", false, "do not touch pack.idx")
 	UpdateCmd.Flags().BoolVarP(&updateCmdFlags.encodedProgress, "encoded-progress", "E", false, "Reports encoded progress for files and download when used by other tools")
+	UpdateCmd.Flags().BoolVar(&updateCmdFlags.dryRun, "dry-run", false, "prints what would be done without changing the pack root")
 	UpdateCmd.Flags().BoolVar(&updateCmdFlags.insecureSkipVerify, "insecure-skip-verify", false, "skip verification of server's TLS certificate when downloading packs over HTTPS")
-
-	UpdateCmd.SetHelpFunc(func(command *cobra.Command, strings []string) {
-		// Small workaround to keep the linter happy, not
-		// really necessary to test this
-		err := command.Flags().MarkHidden("concurrent-downloads")
-		log.Debug(err)
-		command.Parent().HelpFunc()(command, strings)
-	})
 }
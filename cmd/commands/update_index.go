@@ -51,12 +51,17 @@ var UpdateIndexCmd = &cobra.Command{
 		}
 
 		err = installer.UpdatePublicIndex("", updateIndexCmdFlags.sparse, false, updateIndexCmdFlags.downloadUpdatePdscFiles, !updateIndexCmdFlags.includeDeprecated, true, true, updateIndexCmdFlags.insecureSkipVerify, viper.GetInt("concurrent-downloads"), viper.GetInt("timeout"))
-		return err
+		if err != nil {
+			return err
+		}
+
+		return installer.UpdateRegistries(updateIndexCmdFlags.sparse, updateIndexCmdFlags.insecureSkipVerify, viper.GetInt("timeout"))
 	},
 }
 
 func getLongUpdateDescription() string {
 	return `Updates the public index in ` + os.Getenv("CMSIS_PACK_ROOT") + "/.Web/" + installer.PublicIndexName + " using the URL in <url> tag inside " + installer.PublicIndexName + `.
+If the public index got merged from a vendor index (.vidx) file, the .vidx file and the vendor indexes it lists are used instead.
 By default it will also check if all PDSC files under .Web/ need update as well. This can be disabled via the "--sparse" flag.`
 }
 
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/stretchr/testify/assert"
 )
 
 var updateCmdTests = []TestCase{
@@ -23,6 +24,27 @@ var updateCmdTests = []TestCase{
 		createPackRoot: true,
 		expectedErr:    nil,
 	},
+	{
+		name:           "test dry run updating all packs",
+		args:           []string{"update", "--dry-run"},
+		createPackRoot: true,
+		expectedStdout: []string{"Dry run: nothing to do"},
+	},
+	{
+		name:           "test dry run updating a pack not installed",
+		args:           []string{"update", "--dry-run", "DoesNotExist.Pack"},
+		createPackRoot: true,
+		expectedStdout: []string{"Planned actions:", "- skip DoesNotExist.Pack (not installed)"},
+	},
+	{
+		name:        "test dry run updating all packs default mode no pack root",
+		args:        []string{"update", "--dry-run"},
+		defaultMode: true,
+		expectedErr: errs.ErrPackRootDoesNotExist,
+		validationFunc: func(t *testing.T) {
+			assert.NoDirExists(t, "test_dry_run_updating_all_packs_default_mode_no_pack_root")
+		},
+	},
 	{
 		name:           "test updating pack missing file",
 		args:           []string{"update", "DoesNotExist.Pack"},
@@ -44,12 +44,21 @@ var (
 	ErrPdscFileTooDeepInPack   = errors.New("pdsc file is too deep in pack file")
 	ErrMultiplePdscFilesInPack = errors.New("multiple pdsc files found in pack file, cannot determine which one to use. Please remove the extra pdsc files")
 	ErrPdscWrongName           = errors.New("pdsc file has wrong name, it should be <PackID>.pdsc")
+	ErrPackHasDependents       = errors.New("pack is required by other installed packs, use \"--force\" to remove it anyway or \"--cascade\" to also remove the packs depending on it")
+	ErrDependencyConflict      = errors.New("conflicting requirements on pack")
+	ErrPackNotCached           = errors.New("pack file not found in .Download, add the pack again to cache it")
+	ErrInvalidPackIndex        = errors.New("index does not comply with PackIndex.xsd")
+	ErrPackOlderThanPublished  = errors.New("a newer version of the pack is published in the registry already")
 
 	// Errors related to network
-	ErrBadRequest            = errors.New("bad request")
-	ErrFailedDownloadingFile = errors.New("failed to download file")
-	ErrOffline               = errors.New("remote server is offline or cannot be reached")
-	ErrHTTPtimeout           = errors.New("HTTP get timed out")
+	ErrBadRequest             = errors.New("bad request")
+	ErrUnauthorized           = errors.New("the server requires credentials, or refused the ones given")
+	ErrInvalidCertificateFile = errors.New("invalid PEM certificate or key file")
+	ErrFailedDownloadingFile  = errors.New("failed to download file")
+	ErrOffline                = errors.New("remote server is offline or cannot be reached")
+	ErrHTTPtimeout            = errors.New("HTTP get timed out")
+	ErrNotModified            = errors.New("file not modified since last download")
+	ErrOfflineMode            = errors.New("file missing from the pack root, cannot be downloaded in offline mode")
 
 	// Errors related to file system
 	ErrFailedCreatingFile        = errors.New("failed to create a local file")
@@ -87,7 +96,13 @@ var (
 	ErrUnknownBehavior = errors.New("unknown behavior")
 
 	// Cmdline errors
-	ErrIncorrectCmdArgs = errors.New("incorrect setup of command line arguments")
+	ErrIncorrectCmdArgs    = errors.New("incorrect setup of command line arguments")
+	ErrUnknownOutputFormat = errors.New("unknown output format, use one of: json, yaml, table")
+	ErrUnknownGraphFormat  = errors.New("unknown graph format, use one of: dot, json")
+	ErrUnknownConfigKey    = errors.New("unknown configuration key, the command 'cpackget config list' shows all keys")
+	ErrInvalidConfigValue  = errors.New("invalid configuration value")
+	ErrMissingIndexURL     = errors.New("missing index url, pass it as argument or set \"index-url\" in the configuration")
+	ErrInvalidRegistry     = errors.New("invalid registry, it needs a unique name made of letters, digits, '-' and '_', and an url")
 
 	// Errors on installation structure
 	ErrCannotOverwritePublicIndex      = errors.New("cannot replace \"index.pidx\", use the flag \"-f/--force\" to force overwritting it")
@@ -97,6 +112,9 @@ var (
 	ErrPackVersionNotLatestReleasePdsc = errors.New("pack version is not the latest in the pdsc file")
 	ErrPackVersionNotAvailable         = errors.New("target pack version is not available")
 	ErrPackURLCannotBeFound            = errors.New("the pack is not found in the public index. The command 'cpackget list --public' shows all public packs")
+	ErrTransactionInProgress           = errors.New("a transaction is already in progress on the pack root")
+	ErrTransactionRollbackFailed       = errors.New("could not roll back the changes made to the pack root")
+	ErrPackRootLocked                  = errors.New("pack root is locked by another cpackget run")
 
 	// Hack to allow multiple error logs while still avoiding duplicating the last error log
 	ErrAlreadyLogged = errors.New("already logged")
@@ -0,0 +1,221 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+)
+
+// Supported formats of the requirements graph
+const (
+	GraphFormatDOT  = "dot"
+	GraphFormatJSON = "json"
+)
+
+// GraphFormats lists all accepted values of the "--graph" flag
+var GraphFormats = []string{GraphFormatDOT, GraphFormatJSON}
+
+// GraphNode is a pack of the requirements graph, either installed or missing.
+// Missing packs only have a version if it could be worked out from their PDSC file.
+type GraphNode struct {
+	ID      string `json:"id"`
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	State   string `json:"state"`
+}
+
+// GraphEdge is an entry of the <requirements><packages> section of a pack.
+// Its state tells whether the requirement is satisfied by an installed version,
+// unsatisfied by the installed ones, or missing altogether.
+type GraphEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Version string `json:"version"`
+	State   string `json:"state"`
+}
+
+// RequirementsGraph holds the packs and the requirements linking them
+type RequirementsGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// ListRequirementsGraph prints the requirements of all installed packs, and of the packs
+// they require transitively, as a graph in one of GraphFormats. Nodes and edges are sorted,
+// so that graphs of different pack roots can be compared.
+//
+// Parameters:
+//   - format: The graph format, "dot" or "json".
+//   - includeMissing: If true, the requirements of missing packs are walked as well,
+//     using the PDSC files available locally.
+//   - testing: If true, skips reading index files (used for testing).
+//
+// Returns:
+//   - error: An error if the format is unknown or installed packs cannot be listed.
+func ListRequirementsGraph(format string, includeMissing, testing bool) error {
+	format = strings.ToLower(format)
+	if !slices.Contains(GraphFormats, format) {
+		return fmt.Errorf("%q: %w", format, errs.ErrUnknownGraphFormat)
+	}
+
+	if !testing {
+		if err := ReadIndexFiles(); err != nil {
+			return err
+		}
+	}
+
+	graph, err := buildRequirementsGraph(includeMissing)
+	if err != nil {
+		return err
+	}
+	return WriteGraph(utils.GetOutputWriter(), format, graph)
+}
+
+// buildRequirementsGraph walks PdscXML.Dependencies() from every installed pack
+func buildRequirementsGraph(includeMissing bool) (*RequirementsGraph, error) {
+	packs, err := loadInstalledPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &RequirementsGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	nodes := map[string]bool{}
+	addNode := func(node GraphNode) {
+		if !nodes[node.ID] {
+			nodes[node.ID] = true
+			graph.Nodes = append(graph.Nodes, node)
+		}
+	}
+
+	// installedVersions maps the lowercase "vendor.name" keys to the installed versions, most recent first
+	installedVersions := map[string][]string{}
+	for _, pack := range packs {
+		key := resolverKey(pack.Vendor, pack.Name)
+		installedVersions[key] = sortVersions(append(installedVersions[key], pack.Version))
+		addNode(GraphNode{
+			ID:      pack.Vendor + "::" + pack.Name + "@" + pack.Version,
+			Vendor:  pack.Vendor,
+			Name:    pack.Name,
+			Version: pack.Version,
+			State:   PackStateInstalled,
+		})
+	}
+
+	type requirer struct {
+		id           string
+		dependencies [][]string
+	}
+	queue := []requirer{}
+	for _, pack := range packs {
+		if pack.Pdsc != nil {
+			queue = append(queue, requirer{id: pack.Vendor + "::" + pack.Name + "@" + pack.Version, dependencies: pack.Pdsc.Dependencies()})
+		}
+	}
+
+	expanded := map[string]bool{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, dependency := range current.dependencies {
+			vendor, name := dependency[1], dependency[0]
+			edge := GraphEdge{From: current.id, Version: dependency[2], State: PackStateMissing}
+
+			versions := installedVersions[resolverKey(vendor, name)]
+			if len(versions) > 0 {
+				edge.State = PackStateUnsatisfied
+			}
+			for _, version := range versions {
+				if requirementSatisfied(version, dependency) {
+					edge.State = PackStateSatisfied
+					edge.To = vendor + "::" + name + "@" + version
+					break
+				}
+			}
+
+			if edge.State != PackStateSatisfied {
+				node := GraphNode{ID: vendor + "::" + name, Vendor: vendor, Name: name, State: PackStateMissing}
+				var dependencies [][]string
+				if includeMissing {
+					missing, pdscXML := loadResolvedPack(vendor, name, false, false, 0)
+					for _, version := range missing.versions {
+						if requirementSatisfied(version, dependency) {
+							node.ID += "@" + version
+							node.Version = version
+							break
+						}
+					}
+					if pdscXML != nil {
+						dependencies = pdscXML.Dependencies()
+					}
+				}
+				edge.To = node.ID
+				addNode(node)
+				if includeMissing && !expanded[node.ID] {
+					expanded[node.ID] = true
+					queue = append(queue, requirer{id: node.ID, dependencies: dependencies})
+				}
+			}
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+
+	sort.SliceStable(graph.Nodes, func(i, j int) bool {
+		return strings.ToLower(graph.Nodes[i].ID) < strings.ToLower(graph.Nodes[j].ID)
+	})
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if !strings.EqualFold(a.From, b.From) {
+			return strings.ToLower(a.From) < strings.ToLower(b.From)
+		}
+		return strings.ToLower(a.To) < strings.ToLower(b.To)
+	})
+	return graph, nil
+}
+
+// WriteGraph encodes graph to w using one of GraphFormats
+func WriteGraph(w io.Writer, format string, graph *RequirementsGraph) error {
+	switch format {
+	case GraphFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(graph)
+	case GraphFormatDOT:
+		return writeGraphDOT(w, graph)
+	}
+	return nil
+}
+
+// writeGraphDOT prints graph in the Graphviz DOT language. Missing packs and
+// requirements that are not satisfied are drawn dashed and in red.
+func writeGraphDOT(w io.Writer, graph *RequirementsGraph) error {
+	lines := []string{"digraph requirements {", "  rankdir=LR;", "  node [shape=box];"}
+	for _, node := range graph.Nodes {
+		attributes := ""
+		if node.State != PackStateInstalled {
+			attributes = " [style=dashed, color=red]"
+		}
+		lines = append(lines, fmt.Sprintf("  %q%s;", node.ID, attributes))
+	}
+	for _, edge := range graph.Edges {
+		attributes := fmt.Sprintf("label=%q", utils.FormatRequiredVersion(edge.Version))
+		if edge.State != PackStateSatisfied {
+			attributes += ", style=dashed, color=red"
+		}
+		lines = append(lines, fmt.Sprintf("  %q -> %q [%s];", edge.From, edge.To, attributes))
+	}
+	lines = append(lines, "}")
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
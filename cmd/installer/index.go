@@ -0,0 +1,248 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	log "github.com/sirupsen/logrus"
+	"go.yaml.in/yaml/v3"
+)
+
+// IndexPackAttributes are the attributes of a pack in an index file which its PDSC file does not tell,
+// set by pack as Vendor::Pack in the file given to CreateIndex
+type IndexPackAttributes struct {
+	// Deprecated is the date, as YYYY-MM-DD, from which the pack is deprecated
+	Deprecated string `yaml:"deprecated,omitempty"`
+
+	// Replacement is the pack, as Vendor.Pack, replacing the deprecated one
+	Replacement string `yaml:"replacement,omitempty"`
+}
+
+// readIndexPackAttributes reads the attributes of the packs in attributesPath, by Vendor::Pack
+func readIndexPackAttributes(attributesPath string) (map[string]IndexPackAttributes, error) {
+	contents, err := os.ReadFile(attributesPath) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	attributes := map[string]IndexPackAttributes{}
+	if err := yaml.Unmarshal(contents, &attributes); err != nil {
+		return nil, fmt.Errorf("%q: %w", attributesPath, err)
+	}
+	for packID := range attributes {
+		if vendor, name, found := strings.Cut(packID, "::"); !found || vendor == "" || name == "" {
+			return nil, fmt.Errorf("%q in %q: %w", packID, attributesPath, errs.ErrBadPackName)
+		}
+	}
+	return attributes, nil
+}
+
+// findPdscFiles returns the PDSC files in source: the file itself, or the ones in the directory tree
+func findPdscFiles(source string) ([]string, error) {
+	info, err := os.Stat(source)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%q: %w", source, errs.ErrFileNotFound)
+	} else if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{source}, nil
+	}
+
+	pdscFiles := []string{}
+	err = filepath.WalkDir(source, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(filePath), utils.PdscExtension) {
+			pdscFiles = append(pdscFiles, filePath)
+		}
+		return nil
+	})
+	return pdscFiles, err
+}
+
+// readIndexPdscTag reads the PDSC file at pdscPath into the tag of its latest release. The
+// tag gets the URL of the PDSC file, or baseURL, where the file was found, if it has none.
+func readIndexPdscTag(pdscPath, baseURL string) (xml.PdscTag, error) {
+	pdscXML := xml.NewPdscXML(pdscPath)
+	if err := pdscXML.Read(); err != nil {
+		return xml.PdscTag{}, err
+	}
+	if pdscXML.URL == "" {
+		pdscXML.URL = baseURL
+	}
+	tag := pdscXML.Tag()
+	if tag.Vendor == "" || tag.Name == "" || tag.Version == "" {
+		return xml.PdscTag{}, fmt.Errorf("%q: missing vendor, name or release: %w", pdscPath, errs.ErrInvalidPackIndex)
+	}
+	if tag.URL == "" {
+		return xml.PdscTag{}, fmt.Errorf("%q: missing url: %w", pdscPath, errs.ErrInvalidPackIndex)
+	}
+	tag.URL = pdscXML.BaseURL()
+	if base := filepath.Base(pdscPath); !strings.EqualFold(base, tag.PdscFileName()) {
+		log.Warnf("%q should be named %q to be downloaded from the index", pdscPath, tag.PdscFileName())
+	}
+	return tag, nil
+}
+
+// CreateIndex writes an index file listing the latest release of the packs described
+// by the PDSC files found in sources.
+//
+// Parameters:
+//   - sources: Directories, searched recursively for PDSC files, PDSC files, or URLs of PDSC files.
+//   - indexPath: The index file to write.
+//   - vendor: The vendor of the index, the one of all the packs by default if they share it.
+//   - indexURL: The URL the index is published at, the one of all the packs by default if they share it.
+//   - attributesPath: If not empty, a YAML file setting the deprecated and replacement attributes
+//     of packs, see IndexPackAttributes.
+//   - insecureSkipVerify: If true, skips TLS certificate verification when downloading PDSC files.
+//   - timeout: The timeout in seconds of each download.
+//
+// Returns:
+//   - error: errs.ErrInvalidPackIndex if the index would not comply with PackIndex.xsd.
+func CreateIndex(sources []string, indexPath, vendor, indexURL, attributesPath string, insecureSkipVerify bool, timeout int) error {
+	attributes := map[string]IndexPackAttributes{}
+	if attributesPath != "" {
+		var err error
+		if attributes, err = readIndexPackAttributes(attributesPath); err != nil {
+			return err
+		}
+	}
+
+	pidxXML := xml.NewPidxXML(indexPath, false)
+	pidxXML.Clear()
+	addTag := func(tag xml.PdscTag, source string) {
+		if found := pidxXML.FindPdscNameTags(tag); len(found) > 0 {
+			if utils.SemverCompare(tag.Version, found[0].Version) <= 0 {
+				log.Warnf("Ignoring %q from %q, version %s is listed already", tag.VName(), source, found[0].Version)
+				return
+			}
+			log.Debugf("%q from %q replaces version %s", tag.Key(), source, found[0].Version)
+			_ = pidxXML.RemovePdsc(found[0])
+		}
+		_ = pidxXML.AddPdsc(tag)
+	}
+
+	for _, source := range sources {
+		if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+			if err := addRemotePdscTag(source, insecureSkipVerify, timeout, addTag); err != nil {
+				return err
+			}
+			continue
+		}
+
+		sourcePath, err := utils.FileURLToPath(source)
+		if err != nil {
+			return err
+		}
+		pdscFiles, err := findPdscFiles(sourcePath)
+		if err != nil {
+			return err
+		}
+		if len(pdscFiles) == 0 {
+			log.Warnf("No PDSC file found in %q", sourcePath)
+		}
+		for _, pdscFile := range pdscFiles {
+			tag, err := readIndexPdscTag(pdscFile, "")
+			if err != nil {
+				return err
+			}
+			addTag(tag, pdscFile)
+		}
+	}
+
+	tags := pidxXML.ListPdscTags()
+	if len(tags) == 0 {
+		return fmt.Errorf("%q: %w", strings.Join(sources, ", "), errs.ErrPdscFileNotFound)
+	}
+
+	// The index defaults to the vendor and url shared by all packs
+	if vendor == "" {
+		if !sharedBy(tags, func(tag xml.PdscTag) string { return tag.Vendor }, tags[0].Vendor) {
+			return fmt.Errorf("the packs have several vendors, pass the one of the index: %w", errs.ErrIncorrectCmdArgs)
+		}
+		vendor = tags[0].Vendor
+	}
+	if indexURL == "" {
+		if !sharedBy(tags, func(tag xml.PdscTag) string { return tag.URL }, tags[0].URL) {
+			return fmt.Errorf("the packs have several urls, pass the one of the index: %w", errs.ErrIncorrectCmdArgs)
+		}
+		indexURL = tags[0].URL
+	}
+
+	for packID, packAttributes := range attributes {
+		packVendor, packName, _ := strings.Cut(packID, "::")
+		found := pidxXML.FindPdscNameTags(xml.PdscTag{Vendor: packVendor, Name: packName})
+		if len(found) == 0 {
+			log.Warnf("Ignoring the attributes of %q, not in the index", packID)
+			continue
+		}
+		tag := found[0]
+		_ = pidxXML.RemovePdsc(tag)
+		tag.Deprecated = packAttributes.Deprecated
+		tag.Replacement = packAttributes.Replacement
+		_ = pidxXML.AddPdsc(tag)
+	}
+
+	pidxXML.SchemaVersion = "1.1.0"
+	pidxXML.Vendor = vendor
+	pidxXML.URL = indexURL
+	pidxXML.TimeStamp = time.Now().Format(time.RFC3339Nano)
+	if err := pidxXML.Validate(); err != nil {
+		return err
+	}
+	if err := pidxXML.Write(); err != nil {
+		return err
+	}
+	log.Infof("Wrote %q listing %d pack(s)", indexPath, len(pidxXML.ListPdscTags()))
+	return nil
+}
+
+// addRemotePdscTag downloads the PDSC file at pdscURL and adds the tag of its latest release
+func addRemotePdscTag(pdscURL string, insecureSkipVerify bool, timeout int, addTag func(xml.PdscTag, string)) error {
+	// Keep the downloads out of any pack root
+	downloadDir, err := os.MkdirTemp("", "cpackget-index-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(downloadDir)
+
+	pdscPath, err := utils.DownloadFileTo(pdscURL, downloadDir, false, false, insecureSkipVerify, timeout)
+	if err != nil {
+		return err
+	}
+	parsedURL, err := url.Parse(pdscURL)
+	if err != nil {
+		return err
+	}
+	parsedURL.Path = path.Dir(parsedURL.Path) + "/"
+	tag, err := readIndexPdscTag(pdscPath, parsedURL.String())
+	if err != nil {
+		return err
+	}
+	addTag(tag, pdscURL)
+	return nil
+}
+
+// sharedBy tells whether all tags have value as attribute, ignoring case
+func sharedBy(tags []xml.PdscTag, attribute func(xml.PdscTag) string, value string) bool {
+	for _, tag := range tags {
+		if !strings.EqualFold(attribute(tag), value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	"github.com/stretchr/testify/assert"
+)
+
+// indexPdsc returns the content of a PDSC file with the given releases, the latest first
+func indexPdsc(vendor, name, URL string, versions ...string) []byte {
+	releases := ""
+	for _, version := range versions {
+		releases += fmt.Sprintf("    <release version=%q date=\"2025-01-01\">Release %[1]s</release>\n", version)
+	}
+	return fmt.Appendf(nil, `<?xml version="1.0" encoding="UTF-8"?>
+<package schemaVersion="1.7.2">
+  <vendor>%s</vendor>
+  <name>%s</name>
+  <url>%s</url>
+  <releases>
+%s  </releases>
+</package>
+`, vendor, name, URL, releases)
+}
+
+// writeIndexPdsc writes a PDSC file in dir, named after its pack
+func writeIndexPdsc(t *testing.T, dir, vendor, name, URL string, versions ...string) {
+	assert.Nil(t, os.MkdirAll(dir, 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, vendor+"."+name+".pdsc"), indexPdsc(vendor, name, URL, versions...), 0600))
+}
+
+func TestCreateIndex(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test creating an index from a directory tree", func(t *testing.T) {
+		dir := t.TempDir()
+		writeIndexPdsc(t, filepath.Join(dir, "a"), "Acme", "PackA", "https://packs.acme.com/", "1.2.0", "1.1.0")
+		writeIndexPdsc(t, filepath.Join(dir, "b", "c"), "Acme", "PackB", "https://packs.acme.com", "2.0.0-rc1+build")
+		writeIndexPdsc(t, filepath.Join(dir, "old"), "Acme", "PackA", "https://packs.acme.com/", "1.0.0")
+		deprecations := filepath.Join(dir, "deprecations.yaml")
+		assert.Nil(os.WriteFile(deprecations, []byte("Acme::PackB:\n  deprecated: 2025-06-30\n  replacement: Acme.PackA\nAcme::Missing:\n  deprecated: 2025-06-30\n"), 0600))
+
+		indexPath := filepath.Join(dir, installer.PublicIndexName)
+		assert.Nil(installer.CreateIndex([]string{dir}, indexPath, "", "", deprecations, !InsecureSkipVerify, Timeout))
+
+		pidxXML := xml.NewPidxXML(indexPath, false)
+		assert.Nil(pidxXML.Read())
+		assert.Equal("Acme", pidxXML.Vendor)
+		assert.Equal("https://packs.acme.com/", pidxXML.URL)
+		assert.NotEmpty(pidxXML.TimeStamp)
+		assert.Nil(pidxXML.Validate())
+
+		tags := pidxXML.FindPdscNameTags(xml.PdscTag{Vendor: "Acme", Name: "PackA"})
+		assert.Len(tags, 1)
+		assert.Equal("1.2.0", tags[0].Version)
+		assert.Equal("https://packs.acme.com/", tags[0].URL)
+		assert.Empty(tags[0].Deprecated)
+		tags = pidxXML.FindPdscNameTags(xml.PdscTag{Vendor: "Acme", Name: "PackB"})
+		assert.Len(tags, 1)
+		assert.Equal("2.0.0-rc1", tags[0].Version)
+		assert.Equal("https://packs.acme.com/", tags[0].URL)
+		assert.Equal("2025-06-30", tags[0].Deprecated)
+		assert.Equal("Acme.PackA", tags[0].Replacement)
+
+		// The index complies with the schema
+		xmllint, err := exec.LookPath("xmllint")
+		if err != nil {
+			t.Log("xmllint not found, skipping the schema validation")
+			return
+		}
+		schema := filepath.Join("..", "..", "testdata", "PackIndex.xsd")
+		output, err := exec.Command(xmllint, "--noout", "--schema", schema, indexPath).CombinedOutput() // #nosec
+		assert.Nil(err, string(output))
+	})
+
+	t.Run("test creating an index from urls", func(t *testing.T) {
+		server := newConditionalServer()
+		defer server.server.Close()
+		server.set("packs/TheVendor.ThePack.pdsc", indexPdsc("TheVendor", "ThePack", "", "1.0.0"))
+		server.set("packs/TheVendor.OtherPack.pdsc", indexPdsc("TheVendor", "OtherPack", "https://mirror.the.vendor/", "3.0.0"))
+
+		indexPath := filepath.Join(t.TempDir(), installer.PublicIndexName)
+		sources := []string{server.URL() + "packs/TheVendor.ThePack.pdsc", server.URL() + "packs/TheVendor.OtherPack.pdsc"}
+
+		// The packs do not share their url
+		err := installer.CreateIndex(sources, indexPath, "", "", "", !InsecureSkipVerify, Timeout)
+		assert.True(errors.Is(err, errs.ErrIncorrectCmdArgs))
+		assert.NoFileExists(indexPath)
+
+		assert.Nil(installer.CreateIndex(sources, indexPath, "TheVendor", server.URL()+"packs/", "", !InsecureSkipVerify, Timeout))
+		pidxXML := xml.NewPidxXML(indexPath, false)
+		assert.Nil(pidxXML.Read())
+		tags := pidxXML.FindPdscNameTags(xml.PdscTag{Vendor: "TheVendor", Name: "ThePack"})
+		assert.Len(tags, 1)
+		assert.Equal(server.URL()+"packs/", tags[0].URL)
+		tags = pidxXML.FindPdscNameTags(xml.PdscTag{Vendor: "TheVendor", Name: "OtherPack"})
+		assert.Len(tags, 1)
+		assert.Equal("https://mirror.the.vendor/", tags[0].URL)
+	})
+
+	t.Run("test creating an invalid index", func(t *testing.T) {
+		dir := t.TempDir()
+		indexPath := filepath.Join(dir, installer.PublicIndexName)
+
+		// No PDSC files
+		err := installer.CreateIndex([]string{dir}, indexPath, "", "", "", !InsecureSkipVerify, Timeout)
+		assert.True(errors.Is(err, errs.ErrPdscFileNotFound))
+
+		// Not a semantic version
+		writeIndexPdsc(t, dir, "TheVendor", "ThePack", "https://the.vendor/", "1.0")
+		err = installer.CreateIndex([]string{dir}, indexPath, "", "", "", !InsecureSkipVerify, Timeout)
+		assert.True(errors.Is(err, errs.ErrInvalidPackIndex))
+		assert.True(strings.Contains(err.Error(), "1.0"))
+		assert.NoFileExists(indexPath)
+
+		// Not a pack ID in the deprecations
+		writeIndexPdsc(t, dir, "TheVendor", "ThePack", "https://the.vendor/", "1.0.0")
+		deprecations := filepath.Join(dir, "deprecations.yaml")
+		assert.Nil(os.WriteFile(deprecations, []byte("TheVendor.ThePack:\n  deprecated: 2025-06-30\n"), 0600))
+		err = installer.CreateIndex([]string{dir}, indexPath, "", "", deprecations, !InsecureSkipVerify, Timeout)
+		assert.True(errors.Is(err, errs.ErrBadPackName))
+	})
+}
@@ -0,0 +1,236 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+	"go.yaml.in/yaml/v3"
+)
+
+// LockFileName is the default name of the lock file written by "cpackget lock"
+const LockFileName = "cpackget.lock.yaml"
+
+// LockedPack pins an installed pack to its exact version, the URL its
+// pack file is downloaded from and the SHA-256 digest of that file
+type LockedPack struct {
+	Vendor     string `yaml:"vendor"`
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+	URL        string `yaml:"url"`
+	SHA256     string `yaml:"sha256"`
+	Dependency bool   `yaml:"dependency,omitempty"`
+}
+
+// LockFile lists the packs of a pack root, so that it can be reproduced elsewhere
+type LockFile struct {
+	Packs []LockedPack `yaml:"packs"`
+}
+
+// YamlPackID returns the locked pack in "Vendor::Name@Version" format
+func (l *LockedPack) YamlPackID() string {
+	return l.Vendor + "::" + l.Name + "@" + l.Version
+}
+
+// fileSHA256 returns the hex encoded SHA-256 digest of a file
+func fileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath) // #nosec
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadLockFile reads and parses a lock file written by LockPacks
+func ReadLockFile(lockFilePath string) (*LockFile, error) {
+	contents, err := os.ReadFile(lockFilePath) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	lockFile := &LockFile{}
+	if err := yaml.Unmarshal(contents, lockFile); err != nil {
+		return nil, fmt.Errorf("%q: %w", lockFilePath, err)
+	}
+	return lockFile, nil
+}
+
+// LockPacks writes a lock file listing every installed pack with its exact version,
+// the URL its pack file gets downloaded from, as resolved by FindPackURL, and the
+// SHA-256 digest of its pack file in ".Download/". Packs installed via PDSC files
+// have no pack file and are left out.
+//
+// Parameters:
+//   - lockFilePath: The path of the lock file to write.
+//   - insecureSkipVerify: A boolean indicating whether to skip TLS certificate verification.
+//   - testing: A boolean indicating whether the function is being run in a testing environment.
+//
+// Returns:
+//   - error: An error if any installed pack cannot be locked, or nil if successful.
+func LockPacks(lockFilePath string, insecureSkipVerify, testing bool) error {
+	if err := ReadIndexFiles(); err != nil {
+		return err
+	}
+	if err := Installation.readDependenciesPidx(); err != nil {
+		return err
+	}
+
+	installedPacks, err := findInstalledPacks(true, false)
+	if err != nil {
+		return err
+	}
+
+	lockFile := LockFile{Packs: []LockedPack{}}
+	var lastErr error
+	for _, installed := range installedPacks {
+		packID := installed.Vendor + "::" + installed.Name + "@" + installed.Version
+		if installed.isPdscInstalled {
+			log.Warnf("Not locking %s, it is installed via %s", packID, installed.pdscPath)
+			continue
+		}
+
+		locked, err := lockPack(installed, insecureSkipVerify, testing)
+		if err != nil {
+			log.Errorf("Cannot lock %s: %v", packID, err)
+			lastErr = errs.ErrAlreadyLogged
+			continue
+		}
+		lockFile.Packs = append(lockFile.Packs, *locked)
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+
+	sort.SliceStable(lockFile.Packs, func(i, j int) bool {
+		return strings.ToLower(lockFile.Packs[i].YamlPackID()) < strings.ToLower(lockFile.Packs[j].YamlPackID())
+	})
+
+	contents, err := yaml.Marshal(lockFile)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(lockFilePath, contents, 0600); err != nil {
+		return err
+	}
+	log.Infof("Locked %d pack(s) in %q", len(lockFile.Packs), lockFilePath)
+	return nil
+}
+
+// lockPack resolves the download URL of an installed pack and hashes its cached pack file
+func lockPack(installed installedPack, insecureSkipVerify, testing bool) (*LockedPack, error) {
+	pack, err := preparePack(installed.Vendor+"."+installed.Name+"."+installed.Version, false, false, false, true)
+	if err != nil {
+		return nil, err
+	}
+	packURL, err := FindPackURL(pack, insecureSkipVerify, testing)
+	if err != nil {
+		return nil, err
+	}
+
+	packFilePath := filepath.Join(Installation.DownloadDir, pack.PackFileName())
+	if !utils.FileExists(packFilePath) {
+		return nil, fmt.Errorf("%q: %w", packFilePath, errs.ErrPackNotCached)
+	}
+	digest, err := fileSHA256(packFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LockedPack{
+		Vendor:     installed.Vendor,
+		Name:       installed.Name,
+		Version:    installed.Version,
+		URL:        packURL,
+		SHA256:     digest,
+		Dependency: Installation.isDependency(installed.Vendor, installed.Name, installed.Version),
+	}, nil
+}
+
+// AddLockedPacks installs the exact pack versions listed in a lock file, downloading them
+// from the locked URLs. Every pack file is checked against its locked SHA-256 digest before
+// being installed, and the installation fails if any of them differs, rolling back the packs
+// installed so far. Requirements are not resolved, as the lock file already lists all the packs needed.
+//
+// Parameters:
+//   - lockFilePath: The path of the lock file written by LockPacks.
+//   - checkEula: A boolean indicating whether to ask the user to agree with the embedded licenses.
+//   - insecureSkipVerify: A boolean indicating whether to skip TLS certificate verification.
+//   - testing: A boolean indicating whether the function is being run in a testing environment.
+//   - timeout: The timeout in seconds for downloading pack files.
+//
+// Returns:
+//   - error: An error if a pack cannot be downloaded, verified or installed, or nil if successful.
+func AddLockedPacks(lockFilePath string, checkEula, insecureSkipVerify, testing bool, timeout int) error {
+	lockFile, err := ReadLockFile(lockFilePath)
+	if err != nil {
+		return err
+	}
+	if err := ReadIndexFiles(); err != nil {
+		return err
+	}
+
+	log.Infof("Adding %d locked pack(s) from %q", len(lockFile.Packs), lockFilePath)
+	return inTransaction("add --locked "+lockFilePath, func() error {
+		return addLockedPacks(lockFile, checkEula, insecureSkipVerify, testing, timeout)
+	})
+}
+
+// addLockedPacks downloads, verifies and installs the packs of lockFile, see AddLockedPacks
+func addLockedPacks(lockFile *LockFile, checkEula, insecureSkipVerify, testing bool, timeout int) error {
+	for _, locked := range lockFile.Packs {
+		if utils.DirExists(filepath.Join(Installation.PackRoot, locked.Vendor, locked.Name, locked.Version)) {
+			log.Infof("Pack %s is already installed", locked.YamlPackID())
+			continue
+		}
+
+		var err error
+		packFilePath := locked.URL
+		cached := true
+		if strings.HasPrefix(locked.URL, "http") {
+			parsedURL, _ := url.Parse(locked.URL)
+			cached = utils.FileExists(filepath.Join(utils.CacheDir, path.Base(parsedURL.Path)))
+			if packFilePath, err = utils.DownloadFile(locked.URL, true, true, true, insecureSkipVerify, timeout); err != nil {
+				return err
+			}
+		}
+
+		digest, err := fileSHA256(packFilePath)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(digest, locked.SHA256) {
+			log.Errorf("SHA-256 of %q is %s, but %s is locked to %s", packFilePath, digest, locked.YamlPackID(), locked.SHA256)
+			if !cached {
+				utils.UnsetReadOnly(packFilePath)
+				os.Remove(packFilePath)
+			}
+			return fmt.Errorf("%s: %w", locked.YamlPackID(), errs.ErrIntegrityCheckFailed)
+		}
+
+		if locked.Dependency {
+			packFilePath = "$" + packFilePath
+		}
+		if err := AddPack(packFilePath, checkEula, false, false, true, insecureSkipVerify, testing, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
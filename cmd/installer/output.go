@@ -0,0 +1,182 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+	"go.yaml.in/yaml/v3"
+)
+
+// Pack states reported in structured output
+const (
+	PackStateInstalled = "installed"
+	PackStateCached    = "cached"
+	PackStateAvailable = "available"
+	PackStateMissing   = "missing"
+	PackStateOutdated  = "outdated"
+
+	// States of a requirement as reported by ListDependents
+	PackStateSatisfied   = "satisfied"
+	PackStateUnsatisfied = "unsatisfied"
+)
+
+// DependencyRecord is the structured representation of one entry of
+// a pack's <requirements><packages> section
+type DependencyRecord struct {
+	Vendor  string `json:"vendor" yaml:"vendor"`
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version" yaml:"version"`
+	State   string `json:"state" yaml:"state"`
+}
+
+// PackRecord is the structured representation of a listed pack, built
+// from the same information used to print the regular log lines
+type PackRecord struct {
+	Vendor        string             `json:"vendor" yaml:"vendor"`
+	Name          string             `json:"name" yaml:"name"`
+	Version       string             `json:"version" yaml:"version"`
+	State         string             `json:"state" yaml:"state"`
+	LatestVersion string             `json:"latestVersion,omitempty" yaml:"latestVersion,omitempty"`
+	PdscPath      string             `json:"pdscPath,omitempty" yaml:"pdscPath,omitempty"`
+	Deprecated    string             `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	Replacement   string             `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	Dependencies  []DependencyRecord `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	Via           []string           `json:"via,omitempty" yaml:"via,omitempty"`
+	Registry      string             `json:"registry,omitempty" yaml:"registry,omitempty"`
+	Errors        []string           `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// YamlPackID returns the record's pack ID in "Vendor::Name@Version" format
+func (r *PackRecord) YamlPackID() string {
+	return r.Vendor + "::" + r.Name + "@" + r.Version
+}
+
+// dependencyRecords converts the requirements loaded via loadDependencies
+// into structured records
+func (p *PackType) dependencyRecords() []DependencyRecord {
+	records := []DependencyRecord{}
+	for _, req := range p.Requirements.packages {
+		state := PackStateMissing
+		if req.installed {
+			state = PackStateInstalled
+		}
+		records = append(records, DependencyRecord{
+			Vendor:  req.info[1],
+			Name:    req.info[0],
+			Version: req.info[2],
+			State:   state,
+		})
+	}
+	return records
+}
+
+// listOutput collects pack records during listing. When no structured output
+// format is selected, messages are logged right away, as it has always been done.
+type listOutput struct {
+	format  string
+	records []PackRecord
+}
+
+func newListOutput() *listOutput {
+	return &listOutput{
+		format:  utils.GetOutputFormat(),
+		records: []PackRecord{},
+	}
+}
+
+// structured tells whether log lines should be held back in favor of records
+func (o *listOutput) structured() bool {
+	return o.format != ""
+}
+
+// infof logs informational headers, which are omitted from structured output
+func (o *listOutput) infof(format string, args ...any) {
+	if !o.structured() {
+		log.Infof(format, args...)
+	}
+}
+
+// add registers a listed pack, logMessage is printed when in log mode
+func (o *listOutput) add(record PackRecord, logMessage string) {
+	if o.structured() {
+		o.records = append(o.records, record)
+		return
+	}
+	log.Info(logMessage)
+}
+
+// addError works like add, but logs logMessage as an error
+func (o *listOutput) addError(record PackRecord, logMessage string) {
+	if o.structured() {
+		o.records = append(o.records, record)
+		return
+	}
+	log.Error(logMessage)
+}
+
+// flush writes all collected records in the selected format
+func (o *listOutput) flush() error {
+	if !o.structured() {
+		return nil
+	}
+	return WriteRecords(utils.GetOutputWriter(), o.format, o.records)
+}
+
+// WriteRecords encodes records to w using one of utils.OutputFormats
+func WriteRecords(w io.Writer, format string, records []PackRecord) error {
+	switch format {
+	case utils.OutputFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case utils.OutputFormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(records)
+	case utils.OutputFormatTable:
+		return writeRecordsTable(w, records)
+	}
+	return nil
+}
+
+// writeRecordsTable prints records as aligned columns
+func writeRecordsTable(w io.Writer, records []PackRecord) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACK\tSTATE\tDETAILS")
+	for _, record := range records {
+		details := []string{}
+		if record.LatestVersion != "" {
+			details = append(details, "latest "+record.LatestVersion)
+		}
+		if record.Deprecated != "" {
+			deprecated := "deprecated " + record.Deprecated
+			if record.Replacement != "" {
+				deprecated += ", replaced by " + record.Replacement
+			}
+			details = append(details, deprecated)
+		}
+		for _, dep := range record.Dependencies {
+			details = append(details, utils.FormatPackVersion([]string{dep.Name, dep.Vendor, dep.Version})+" ("+dep.State+")")
+		}
+		if len(record.Via) > 0 {
+			details = append(details, "via "+strings.Join(record.Via, " -> "))
+		}
+		if record.PdscPath != "" {
+			details = append(details, record.PdscPath)
+		}
+		if len(record.Errors) > 0 {
+			details = append(details, "error: "+strings.Join(record.Errors, ", "))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", record.YamlPackID(), record.State, strings.Join(details, "; "))
+	}
+	return tw.Flush()
+}
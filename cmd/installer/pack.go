@@ -32,6 +32,9 @@ type PackType struct {
 	// IsPublic tells whether the pack exists in the public index or not
 	IsPublic bool
 
+	// registry is the registry a public pack resolved from
+	registry *Registry
+
 	// isDownloaded tells whether the file needed to be downloaded from a server
 	isDownloaded bool
 
@@ -110,15 +113,15 @@ func preparePack(packPath string, toBeRemoved, forceLatest, noLocal, nometa bool
 	}
 
 	// Clean out any possible query or user auth in the URL
-	// to help finding the correct path info
+	// to help finding the correct path info. The user auth is
+	// kept as the credentials of the host.
 	if strings.HasPrefix(packPath, "http") {
-		url, err := url.Parse(packPath)
+		url, err := url.Parse(utils.StripCredentials(packPath))
 		if err != nil {
 			log.Error(err)
 			return pack, errs.ErrBadPackURL
 		}
 
-		url.User = nil
 		url.Fragment = ""
 		url.RawQuery = ""
 
@@ -192,7 +195,7 @@ func (p *PackType) fetch(insecureSkipVerify bool, timeout int) error {
 	if strings.HasPrefix(p.path, "http") {
 		p.path, err = utils.DownloadFile(p.path, true, true, true, insecureSkipVerify, timeout)
 		if err == errs.ErrTerminatedByUser {
-			log.Infof("Aborting pack download. Removing %q", p.path)
+			log.Infof("Aborting pack download of %q", p.path)
 		}
 
 		p.isDownloaded = true
@@ -209,6 +212,38 @@ func (p *PackType) fetch(insecureSkipVerify bool, timeout int) error {
 
 // validate ensures the pack is legit and it has all minimal requirements to be installed.
 func (p *PackType) validate() error {
+	tmpPdscFileName := filepath.Join(os.TempDir(), utils.RandStringBytes(10))
+	defer os.RemoveAll(tmpPdscFileName)
+
+	pdscFilePath, err := p.checkContents(tmpPdscFileName)
+	if err != nil {
+		return err
+	}
+
+	pdscFileName := p.PdscFileName() // destination file name in .Download directory
+	newPdscFileName := p.PdscFileNameWithVersion()
+
+	if !p.IsPublic {
+		localPdscFilePath := filepath.Join(Installation.LocalDir, pdscFileName)
+		if err := Installation.stash(localPdscFilePath); err != nil {
+			return err
+		}
+		_ = utils.CopyFile(pdscFilePath, localPdscFilePath)
+	}
+
+	versionedPdscFilePath := filepath.Join(Installation.DownloadDir, newPdscFileName)
+	if err := Installation.stash(versionedPdscFilePath); err != nil {
+		return err
+	}
+	_ = utils.CopyFile(pdscFilePath, versionedPdscFilePath)
+
+	return nil
+}
+
+// checkContents makes sure the files of the pack are safe to extract, and that it has a single
+// PDSC file, named after the pack, which latest release is the version of the pack. The PDSC
+// file gets extracted in tmpPdscDir, and its path returned.
+func (p *PackType) checkContents(tmpPdscDir string) (string, error) {
 	log.Debug("Validating pack")
 	var err error
 	myPdscFileName := p.PdscFileName()
@@ -222,9 +257,9 @@ func (p *PackType) validate() error {
 		if strings.Contains(file.Name, "..") {
 			if ext == utils.PdscExtension {
 				log.Errorf("File %q invalid file path", file.Name)
-				return errs.ErrInvalidFilePath
+				return "", errs.ErrInvalidFilePath
 			} else {
-				return errs.ErrInsecureZipFileName
+				return "", errs.ErrInsecureZipFileName
 			}
 		}
 
@@ -239,7 +274,7 @@ func (p *PackType) validate() error {
 					if err != nil {
 						log.Warnf("Pack %q contains an additional %s file in a deeper subfolder, this may cause issues", p.path, utils.PdscExtension)
 					}
-					return fmt.Errorf("%q: %w", file.Name, errs.ErrPdscWrongName)
+					return "", fmt.Errorf("%q: %w", file.Name, errs.ErrPdscWrongName)
 				}
 				// Collect valid PDSC files for processing
 				validPdscFiles = append(validPdscFiles, file)
@@ -252,36 +287,34 @@ func (p *PackType) validate() error {
 	}
 
 	if len(validPdscFiles) > 1 {
-		return errs.ErrMultiplePdscFilesInPack
+		return "", errs.ErrMultiplePdscFilesInPack
 	}
 
 	if err != nil {
 		if len(validPdscFiles) > 0 {
 			log.Warnf("Pack %q contains an additional %s file in a deeper subfolder, this may cause issues", p.path, utils.PdscExtension)
 		} else {
-			return err
+			return "", err
 		}
 	}
 
 	if len(validPdscFiles) == 0 {
 		log.Errorf("%q not found in %q", myPdscFileName, p.path)
-		return errs.ErrPdscFileNotFound
+		return "", errs.ErrPdscFileNotFound
 	}
 
 	// Process the first valid PDSC file found
 	file := validPdscFiles[0]
 
 	// Read pack's pdsc
-	tmpPdscFileName := filepath.Join(os.TempDir(), utils.RandStringBytes(10))
-	defer os.RemoveAll(tmpPdscFileName)
-
-	if err := utils.SecureInflateFile(file, tmpPdscFileName, ""); err != nil {
-		return err
+	if err := utils.SecureInflateFile(file, tmpPdscDir, ""); err != nil {
+		return "", err
 	}
 
-	p.Pdsc = xml.NewPdscXML(filepath.Join(tmpPdscFileName, file.Name)) // #nosec
+	pdscFilePath := filepath.Join(tmpPdscDir, file.Name) // #nosec
+	p.Pdsc = xml.NewPdscXML(pdscFilePath)
 	if err := p.Pdsc.Read(); err != nil {
-		return err
+		return "", err
 	}
 
 	// Sanity check: make sure the version being installed actually exists in the PDSC file
@@ -294,26 +327,15 @@ func (p *PackType) validate() error {
 		releaseTag := p.Pdsc.FindReleaseTagByVersion(version)
 		if releaseTag == nil {
 			log.Errorf("The pack's pdsc (%s) has no release tag matching version %q", myPdscFileName, version)
-			return errs.ErrPackVersionNotFoundInPdsc
+			return "", errs.ErrPackVersionNotFoundInPdsc
 		}
 
 		log.Errorf("The latest release (%s) in pack's pdsc (%s) does not match pack version %q", latestVersion, myPdscFileName, version)
-		return errs.ErrPackVersionNotLatestReleasePdsc
+		return "", errs.ErrPackVersionNotLatestReleasePdsc
 	}
 
 	p.Pdsc.FileName = file.Name
-
-	pdscFileName := p.PdscFileName()                          // destination file name in .Download directory
-	pdscFilePath := filepath.Join(tmpPdscFileName, file.Name) // #nosec
-	newPdscFileName := p.PdscFileNameWithVersion()
-
-	if !p.IsPublic {
-		_ = utils.CopyFile(pdscFilePath, filepath.Join(Installation.LocalDir, pdscFileName))
-	}
-
-	_ = utils.CopyFile(pdscFilePath, filepath.Join(Installation.DownloadDir, newPdscFileName))
-
-	return nil
+	return pdscFilePath, nil
 }
 
 // purge removes all cached files matching the pattern derived from the PackType's
@@ -331,24 +353,13 @@ func (p *PackType) validate() error {
 func (p *PackType) purge() (bool, error) {
 	log.Debugf("Purging \"%v\"", p.path)
 
-	fileNamePattern := p.Vendor + "\\." + p.Name
-	if len(p.Version) > 0 {
-		fileNamePattern += "\\." + p.GetVersionNoMeta() + ".*"
-	} else {
-		fileNamePattern += "\\..*?"
-	}
-	fileNamePattern += "\\.(?:pack|zip|pdsc)"
-
-	files, err := utils.ListDir(Installation.DownloadDir, fileNamePattern)
+	files, err := p.purgedFiles()
 	if err != nil {
 		return false, err
 	}
 
 	cTag := xml.PdscTag{Vendor: p.Vendor, Name: p.Name, Version: p.Version}
-	if err = Installation.PublicCacheIndexXML.RemovePdsc(cTag); err == nil {
-		webFile := Installation.WebDir + "/" + p.VName() + utils.PdscExtension
-		files = append(files, webFile) // also add pdsc file in .Web
-	}
+	_ = Installation.PublicCacheIndexXML.RemovePdsc(cTag)
 	_ = Installation.PublicCacheIndexXML.Write()
 
 	log.Debugf("Files to be purged \"%v\"", files)
@@ -358,7 +369,7 @@ func (p *PackType) purge() (bool, error) {
 	}
 
 	for _, file := range files {
-		if err := os.Remove(file); err != nil {
+		if err := Installation.stash(file); err != nil {
 			return false, err
 		}
 	}
@@ -366,6 +377,35 @@ func (p *PackType) purge() (bool, error) {
 	return false, nil
 }
 
+// cachedFiles lists the files of the pack cached under ".Download/": pack files and
+// versioned PDSC files, of the pack's version or of all versions if none is set
+func (p *PackType) cachedFiles() ([]string, error) {
+	fileNamePattern := p.Vendor + "\\." + p.Name
+	if len(p.Version) > 0 {
+		fileNamePattern += "\\." + p.GetVersionNoMeta() + ".*"
+	} else {
+		fileNamePattern += "\\..*?"
+	}
+	fileNamePattern += "\\.(?:pack|zip|pdsc)"
+
+	return utils.ListDir(Installation.DownloadDir, fileNamePattern)
+}
+
+// purgedFiles lists the files purge removes: the cached files of the pack and,
+// if the pack is listed in the cache index, its PDSC file in ".Web/"
+func (p *PackType) purgedFiles() ([]string, error) {
+	files, err := p.cachedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	cTag := xml.PdscTag{Vendor: p.Vendor, Name: p.Name, Version: p.Version}
+	if len(Installation.PublicCacheIndexXML.FindPdscTags(cTag)) > 0 {
+		files = append(files, filepath.Join(p.pdscRegistry().webDir, p.PdscFileName()))
+	}
+	return files, nil
+}
+
 // install installs pack files to installation's directories
 // It:
 //   - Extracts all files to "CMSIS_PACK_ROOT/p.Vendor/p.Name/p.Version/"
@@ -426,8 +466,43 @@ func (p *PackType) install(installation *PacksInstallationType, checkEula bool)
 		return errs.ErrLicenseNotFound
 	}
 
-	// Inflate all files
-	err = utils.EnsureDir(packHomeDir)
+	// Inflate all files, into a clean directory when reinstalling
+	if err = installation.stash(packHomeDir); err != nil {
+		return err
+	}
+	if stagedDir := installation.takeStagedPack(p); stagedDir != "" {
+		err = p.moveStaged(stagedDir, packHomeDir)
+	} else {
+		err = p.extract(installation, packHomeDir)
+	}
+
+	// Close zip file so Windows can't complain if we rename it
+	p.zipReader.Close()
+	if err != nil {
+		return err
+	}
+
+	if !p.isDownloaded {
+		if err := installation.stash(packBackupPath); err != nil {
+			return err
+		}
+		return utils.CopyFile(p.path, packBackupPath)
+	}
+
+	if filepath.Base(p.path) != filepath.Base(packBackupPath) {
+		err := utils.MoveFile(p.path, packBackupPath)
+		if err != nil {
+			return err
+		}
+		p.path = packBackupPath
+	}
+
+	return nil
+}
+
+// extract inflates all files of the pack to packHomeDir
+func (p *PackType) extract(installation *PacksInstallationType, packHomeDir string) error {
+	err := utils.EnsureDir(packHomeDir)
 	if err != nil {
 		log.Errorf("Can't access pack directory %q: %s", packHomeDir, err)
 		return err
@@ -458,8 +533,6 @@ func (p *PackType) install(installation *PacksInstallationType, checkEula bool)
 		}
 		err = utils.SecureInflateFile(file, packHomeDir, p.Subfolder)
 		if err != nil {
-			defer p.zipReader.Close()
-
 			if err == errs.ErrTerminatedByUser {
 				log.Infof("Aborting pack extraction. Removing %q", packHomeDir)
 				if newErr := p.uninstall(installation); newErr != nil {
@@ -470,22 +543,21 @@ func (p *PackType) install(installation *PacksInstallationType, checkEula bool)
 		}
 	}
 
-	// Close zip file so Windows can't complain if we rename it
-	p.zipReader.Close()
+	return nil
+}
 
-	if !p.isDownloaded {
-		return utils.CopyFile(p.path, packBackupPath)
+// moveStaged moves the files of the pack extracted beforehand by PrefetchPacks to packHomeDir
+func (p *PackType) moveStaged(stagedDir, packHomeDir string) error {
+	log.Infof("Moving files extracted beforehand to %s...", packHomeDir)
+	if utils.GetEncodedProgress() {
+		encodedProgress := utils.NewEncodedProgress(int64(len(p.zipReader.File)), 0, p.path)
+		_ = encodedProgress.Add(len(p.zipReader.File))
 	}
-
-	if filepath.Base(p.path) != filepath.Base(packBackupPath) {
-		err := utils.MoveFile(p.path, packBackupPath)
-		if err != nil {
-			return err
-		}
-		p.path = packBackupPath
+	if err := utils.EnsureDir(filepath.Dir(packHomeDir)); err != nil {
+		log.Errorf("Can't access pack directory %q: %s", packHomeDir, err)
+		return err
 	}
-
-	return nil
+	return utils.MoveFile(stagedDir, packHomeDir)
 }
 
 // uninstall removes the pack from the installation directory.
@@ -500,7 +572,7 @@ func (p *PackType) uninstall(installation *PacksInstallationType) error {
 
 	// Remove Vendor/Pack/x.y.z
 	packPath := filepath.Join(installation.PackRoot, p.Vendor, p.Name, p.GetVersionNoMeta())
-	if err := os.RemoveAll(packPath); err != nil {
+	if err := installation.stash(packPath); err != nil {
 		return err
 	}
 
@@ -515,7 +587,7 @@ func (p *PackType) uninstall(installation *PacksInstallationType) error {
 		if !p.IsPublic {
 			localPdscFileName := p.PdscFileName()
 			filePath := filepath.Join(installation.LocalDir, localPdscFileName)
-			if err := os.Remove(filePath); err != nil {
+			if err := installation.stash(filePath); err != nil {
 				return err
 			}
 		}
@@ -807,7 +879,7 @@ func (p *PackType) toggleReadOnly(setReadOnly bool) {
 	packVersionedPdscPath := filepath.Join(Installation.DownloadDir, p.PdscFileNameWithVersion())
 
 	// .Web/Vendor.Pack.pdsc or .Local/Vendor.Pack.pdsc
-	packPdscPath := filepath.Join(Installation.WebDir, p.PdscFileName())
+	packPdscPath := filepath.Join(p.pdscRegistry().webDir, p.PdscFileName())
 	if !p.IsPublic {
 		packPdscPath = filepath.Join(Installation.LocalDir, p.PdscFileName())
 	}
@@ -834,3 +906,11 @@ func (p *PackType) Lock() {
 func (p *PackType) Unlock() {
 	p.toggleReadOnly(false)
 }
+
+// pdscRegistry returns the registry holding the PDSC file of a public pack
+func (p *PackType) pdscRegistry() *Registry {
+	if p.registry != nil {
+		return p.registry
+	}
+	return Installation.publicRegistry()
+}
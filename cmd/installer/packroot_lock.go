@@ -0,0 +1,169 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// PackRootLockFileName is the advisory lock file, under ".Local/", held by the
+// cpackget run working on the pack root
+const PackRootLockFileName = "cpackget.lock"
+
+// packRootLockOwner is written to the lock file by the cpackget run holding it
+type packRootLockOwner struct {
+	PID      int    `json:"pid"`
+	Hostname string `json:"hostname"`
+	Command  string `json:"command"`
+	Acquired string `json:"acquired"`
+}
+
+// heldPackRootLock is the path of the lock file held by this process, empty if none
+var heldPackRootLock string
+
+// packRootLockPollInterval is how often a locked pack root gets checked again
+const packRootLockPollInterval = 200 * time.Millisecond
+
+// unreadableLockAge is the age after which a lock file that cannot be read is deemed stale,
+// its owner having had plenty of time to write it
+const unreadableLockAge = time.Minute
+
+// String describes the owner for messages about the lock
+func (o *packRootLockOwner) String() string {
+	return fmt.Sprintf("pid %d on %q (%s), since %s", o.PID, o.Hostname, o.Command, o.Acquired)
+}
+
+// isStale tells whether the owner is a process of this machine that is not running anymore.
+// The liveness of processes of other machines sharing the pack root cannot be checked.
+func (o *packRootLockOwner) isStale() bool {
+	hostname, _ := os.Hostname()
+	return o.Hostname == hostname && !utils.ProcessExists(o.PID)
+}
+
+// readPackRootLock reads the owner of a lock file, or nil if it cannot be read
+func readPackRootLock(lockPath string) *packRootLockOwner {
+	contents, err := os.ReadFile(lockPath) // #nosec
+	if err != nil {
+		return nil
+	}
+	owner := &packRootLockOwner{}
+	if err := json.Unmarshal(contents, owner); err != nil {
+		return nil
+	}
+	return owner
+}
+
+// holdsPackRootLock tells whether this process holds the lock of the current pack root
+func holdsPackRootLock() bool {
+	return heldPackRootLock != "" && heldPackRootLock == filepath.Join(Installation.LocalDir, PackRootLockFileName)
+}
+
+// AcquirePackRootLock makes sure only one cpackget run at a time works on the pack root,
+// even across processes, by creating the lock file ".Local/cpackget.lock". If another run
+// holds it, it waits until it gets released. A lock left by a process of this machine that is
+// not running anymore gets removed. Acquiring the lock again from the same process does nothing.
+//
+// Parameters:
+//   - timeout: The maximum number of seconds to wait for the lock, 0 to wait indefinitely.
+//
+// Returns:
+//   - error: ErrPackRootLocked if the lock could not be acquired in time, or nil if successful.
+func AcquirePackRootLock(timeout int) error {
+	lockPath := filepath.Join(Installation.LocalDir, PackRootLockFileName)
+	if heldPackRootLock == lockPath {
+		return nil
+	}
+	if heldPackRootLock != "" {
+		ReleasePackRootLock()
+	}
+
+	hostname, _ := os.Hostname()
+	owner := packRootLockOwner{
+		PID:      os.Getpid(),
+		Hostname: hostname,
+		Command:  strings.Join(append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...), " "),
+	}
+
+	start := time.Now()
+	waiting := false
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644) // #nosec
+		if err == nil {
+			owner.Acquired = time.Now().Format(time.RFC3339)
+			contents, _ := json.Marshal(owner)
+			_, err = file.Write(contents)
+			file.Close()
+			if err != nil {
+				os.Remove(lockPath)
+				return err
+			}
+			log.Debugf("Acquired %q", lockPath)
+			heldPackRootLock = lockPath
+			return nil
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+
+		// The owner might still be writing the lock file, in which case it cannot be read yet
+		current := readPackRootLock(lockPath)
+		if current != nil && current.isStale() {
+			log.Warnf("Removing the stale lock of %s", current)
+			// Make sure the lock did not change hands in the meantime
+			if latest := readPackRootLock(lockPath); latest != nil && *latest == *current {
+				os.Remove(lockPath)
+			}
+			continue
+		}
+		if info, err := os.Stat(lockPath); current == nil && err == nil && time.Since(info.ModTime()) > unreadableLockAge {
+			log.Warnf("Removing the unreadable lock %q", lockPath)
+			os.Remove(lockPath)
+			continue
+		}
+
+		if timeout > 0 && time.Since(start) >= time.Duration(timeout)*time.Second {
+			if current == nil {
+				return fmt.Errorf("%w: %q", errs.ErrPackRootLocked, lockPath)
+			}
+			return fmt.Errorf("%w: %s", errs.ErrPackRootLocked, current)
+		}
+		if !waiting {
+			waiting = true
+			if current != nil {
+				log.Infof("Waiting for the pack root, locked by %s", current)
+			} else {
+				log.Infof("Waiting for the pack root, locked by %q", lockPath)
+			}
+		}
+		time.Sleep(packRootLockPollInterval)
+	}
+}
+
+// ReleasePackRootLock removes the lock file acquired by AcquirePackRootLock, if any
+func ReleasePackRootLock() {
+	if heldPackRootLock == "" {
+		return
+	}
+	lockPath := heldPackRootLock
+	heldPackRootLock = ""
+
+	// Leave alone a lock that got taken over, e.g. after being deemed stale
+	if owner := readPackRootLock(lockPath); owner != nil && owner.PID != os.Getpid() {
+		return
+	}
+	if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not remove %q: %v", lockPath, err)
+		return
+	}
+	log.Debugf("Released %q", lockPath)
+}
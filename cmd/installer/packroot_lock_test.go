@@ -0,0 +1,87 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePackRootLock writes the lock file of the pack root as if held by the process pid of hostname
+func writePackRootLock(pid int, hostname string) string {
+	lockPath := filepath.Join(installer.Installation.LocalDir, installer.PackRootLockFileName)
+	contents, _ := json.Marshal(map[string]any{"pid": pid, "hostname": hostname, "command": "cpackget add", "acquired": "2024-01-01T00:00:00Z"})
+	_ = os.WriteFile(lockPath, contents, 0600)
+	return lockPath
+}
+
+func TestAcquirePackRootLock(t *testing.T) {
+
+	assert := assert.New(t)
+	hostname, _ := os.Hostname()
+
+	t.Run("test acquiring and releasing the pack root lock", func(t *testing.T) {
+		localTestingDir := "test-acquire-pack-root-lock"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		lockPath := filepath.Join(installer.Installation.LocalDir, installer.PackRootLockFileName)
+		assert.Nil(installer.AcquirePackRootLock(1))
+		assert.FileExists(lockPath)
+
+		// Acquiring it again from the same process does nothing
+		assert.Nil(installer.AcquirePackRootLock(1))
+
+		installer.ReleasePackRootLock()
+		assert.NoFileExists(lockPath)
+	})
+
+	t.Run("test acquiring the pack root lock left by a dead process", func(t *testing.T) {
+		localTestingDir := "test-acquire-pack-root-lock-stale"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		assert.Nil(cmd.Run())
+		lockPath := writePackRootLock(cmd.ProcessState.Pid(), hostname)
+
+		assert.Nil(installer.AcquirePackRootLock(1))
+		installer.ReleasePackRootLock()
+		assert.NoFileExists(lockPath)
+	})
+
+	t.Run("test timing out on the pack root lock of a running process", func(t *testing.T) {
+		localTestingDir := "test-acquire-pack-root-lock-timeout"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		lockPath := writePackRootLock(os.Getppid(), hostname)
+		assert.ErrorIs(installer.AcquirePackRootLock(1), errs.ErrPackRootLocked)
+
+		// The lock of another process is left alone
+		installer.ReleasePackRootLock()
+		assert.FileExists(lockPath)
+	})
+
+	t.Run("test timing out on the pack root lock of another machine", func(t *testing.T) {
+		localTestingDir := "test-acquire-pack-root-lock-other-host"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		// Its pid cannot be checked from here, so the lock is not stale
+		writePackRootLock(999999999, "another-"+hostname)
+		assert.ErrorIs(installer.AcquirePackRootLock(1), errs.ErrPackRootLocked)
+	})
+}
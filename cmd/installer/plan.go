@@ -0,0 +1,468 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"archive/zip"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	log "github.com/sirupsen/logrus"
+)
+
+// Actions a dry run plan is made of
+const (
+	PlanAdd      = "add"
+	PlanSkip     = "skip"
+	PlanDownload = "download"
+	PlanExtract  = "extract"
+	PlanLicense  = "license"
+	PlanRequire  = "require"
+	PlanRemove   = "remove"
+	PlanPurge    = "purge"
+	PlanNote     = "note"
+)
+
+// PlanStep is an action a command would take, followed by the actions it leads to,
+// e.g. downloading and extracting a pack being added, or adding its requirements.
+type PlanStep struct {
+	Action  string
+	Target  string
+	Details string
+	Steps   []*PlanStep
+}
+
+// Plan collects the actions that "add", "update" and "rm" would take in the pack root,
+// so that they can be reviewed before actually running the commands.
+type Plan struct {
+	Steps []*PlanStep
+
+	// planned tracks the packs added to the plan, so that shared requirements show up once
+	planned map[string]bool
+}
+
+// NewPlan returns an empty plan
+func NewPlan() *Plan {
+	return &Plan{planned: map[string]bool{}}
+}
+
+// add appends a step to parent, or to the top level of the plan if parent is nil
+func (p *Plan) add(parent *PlanStep, action, target, details string) *PlanStep {
+	step := &PlanStep{Action: action, Target: target, Details: details}
+	if parent == nil {
+		p.Steps = append(p.Steps, step)
+	} else {
+		parent.Steps = append(parent.Steps, step)
+	}
+	return step
+}
+
+// Log prints the plan as a tree, each step indented under the one leading to it
+func (p *Plan) Log() {
+	if len(p.Steps) == 0 {
+		log.Info("Dry run: nothing to do")
+		return
+	}
+
+	log.Infof("Dry run: nothing was changed in %q. Planned actions:", Installation.PackRoot)
+	var logSteps func(steps []*PlanStep, indent string)
+	logSteps = func(steps []*PlanStep, indent string) {
+		for _, step := range steps {
+			details := ""
+			if step.Details != "" {
+				details = " (" + step.Details + ")"
+			}
+			log.Infof("%s- %s %s%s", indent, step.Action, step.Target, details)
+			logSteps(step.Steps, indent+"  ")
+		}
+	}
+	logSteps(p.Steps, "  ")
+}
+
+// startDryRun redirects the downloads needed for planning, like PDSC files of public packs,
+// to a scratch directory. The returned function restores the installation and removes it.
+func startDryRun() (func(), error) {
+	dryRunDir, err := os.MkdirTemp("", "cpackget-dry-run-")
+	if err != nil {
+		return nil, err
+	}
+
+	cacheDir := utils.CacheDir
+	Installation.dryRunDir = dryRunDir
+	utils.CacheDir = dryRunDir
+	return func() {
+		Installation.dryRunDir = ""
+		utils.CacheDir = cacheDir
+		os.RemoveAll(dryRunDir)
+	}, nil
+}
+
+// planPackID formats a pack as Vendor::Name@x.y.z, using the version that would be installed
+func planPackID(pack *PackType) string {
+	if version := pack.GetVersionNoMeta(); version != "" {
+		return pack.Vendor + "::" + pack.Name + "@" + version
+	}
+	return pack.Vendor + "::" + pack.Name
+}
+
+// readPackFilePdsc reads the PDSC file embedded in a pack file without installing it
+func readPackFilePdsc(packFilePath, pdscFileName string) (*xml.PdscXML, error) {
+	zipReader, err := zip.OpenReader(packFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w: %s", packFilePath, errs.ErrFailedDecompressingFile, err)
+	}
+	defer zipReader.Close()
+
+	for _, file := range zipReader.File {
+		if !strings.EqualFold(filepath.Base(file.Name), pdscFileName) {
+			continue
+		}
+
+		tmpPdscDir, err := os.MkdirTemp("", "cpackget-pdsc-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmpPdscDir)
+
+		if err := utils.SecureInflateFile(file, tmpPdscDir, ""); err != nil {
+			return nil, err
+		}
+		pdscXML := xml.NewPdscXML(filepath.Join(tmpPdscDir, file.Name)) // #nosec
+		return pdscXML, pdscXML.Read()
+	}
+
+	return nil, fmt.Errorf("%q in %q: %w", pdscFileName, packFilePath, errs.ErrPdscFileNotFound)
+}
+
+// PlanAddPack works out what AddPack would do, without changing the pack root.
+// It resolves the pack version and URL, and expands its requirements into the plan.
+//
+// Parameters:
+//   - plan: The plan to add the actions to.
+//   - packPath: The path to the pack to be added.
+//   - checkEula: A boolean indicating whether the user would be asked to agree with the embedded license.
+//   - extractEula: A boolean indicating whether only the embedded license would be extracted.
+//   - forceReinstall: A boolean indicating whether an installed pack would be reinstalled.
+//   - noRequirements: A boolean indicating whether the requirements of the pack would be skipped.
+//   - insecureSkipVerify: A boolean indicating whether to skip TLS certificate verification.
+//   - testing: A boolean indicating whether the function is being run in a testing environment.
+//
+// Returns:
+//   - error: An error if the pack cannot be resolved, or nil if successful.
+func PlanAddPack(plan *Plan, packPath string, checkEula, extractEula, forceReinstall, noRequirements, insecureSkipVerify, testing bool) error {
+	stopDryRun, err := startDryRun()
+	if err != nil {
+		return err
+	}
+	defer stopDryRun()
+
+	if err := ReadIndexFiles(); err != nil {
+		return err
+	}
+
+	log.Debugf("Planning to add pack %q", packPath)
+	return plan.addPack(nil, packPath, false, checkEula, extractEula, forceReinstall, noRequirements, insecureSkipVerify, testing)
+}
+
+// PlanUpdatePack works out what UpdatePack would do, without changing the pack root.
+// An empty packPath plans the update of all installed packs.
+//
+// Parameters:
+//   - plan: The plan to add the actions to.
+//   - packPath: Path or identifier of the pack to update. Empty string updates all packs.
+//   - checkEula: A boolean indicating whether the user would be asked to agree with the embedded license.
+//   - noRequirements: A boolean indicating whether the requirements of the pack would be skipped.
+//   - insecureSkipVerify: A boolean indicating whether to skip TLS certificate verification.
+//   - testing: A boolean indicating whether the function is being run in a testing environment.
+//
+// Returns:
+//   - error: An error if the pack, or one of the installed packs, cannot be resolved, or nil if successful.
+func PlanUpdatePack(plan *Plan, packPath string, checkEula, noRequirements, insecureSkipVerify, testing bool) error {
+	stopDryRun, err := startDryRun()
+	if err != nil {
+		return err
+	}
+	defer stopDryRun()
+
+	if err := ReadIndexFiles(); err != nil {
+		return err
+	}
+
+	if packPath != "" {
+		return plan.updatePack(packPath, checkEula, noRequirements, insecureSkipVerify, testing)
+	}
+
+	installedPacks, err := findInstalledPacks(false, true)
+	if err != nil {
+		return err
+	}
+	// Like UpdatePack, the first failure stops the update of all packs
+	for _, installedPack := range installedPacks {
+		if err := plan.updatePack(installedPack.VName(), checkEula, noRequirements, insecureSkipVerify, testing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlanRemovePack works out what RemovePack would do, without changing the pack root.
+// Like RemovePack, it fails if the removal would leave installed packs with unsatisfied
+// requirements, unless force or cascade is set.
+//
+// Parameters:
+//   - plan: The plan to add the actions to.
+//   - packPath: The path to the pack to be removed.
+//   - purge: A boolean indicating whether the cached pack files would be removed.
+//   - force: A boolean indicating whether to remove the pack even if other installed packs require it.
+//   - cascade: A boolean indicating whether to also remove the installed packs requiring it.
+//   - withDeps: A boolean indicating whether to also remove its orphaned dependency packs.
+//
+// Returns:
+//   - error: An error if the pack cannot be removed, or nil if successful.
+func PlanRemovePack(plan *Plan, packPath string, purge, force, cascade, withDeps bool) error {
+	stopDryRun, err := startDryRun()
+	if err != nil {
+		return err
+	}
+	defer stopDryRun()
+
+	if err := ReadIndexFiles(); err != nil {
+		return err
+	}
+
+	log.Debugf("Planning to remove pack %q", packPath)
+	removal, err := resolveRemoval(packPath, purge, force, cascade, withDeps)
+	if err != nil {
+		return err
+	}
+	pack := removal.pack
+
+	packs, err := loadInstalledPacks()
+	if err != nil {
+		return err
+	}
+	for _, key := range removal.dependents {
+		for _, installed := range packs {
+			if strings.EqualFold(installed.Key(), key) {
+				plan.add(nil, PlanRemove, installed.YamlPackID(), "depends on "+pack.PackID())
+			}
+		}
+	}
+
+	for _, version := range removal.versions {
+		if utils.DirExists(filepath.Join(Installation.PackRoot, pack.Vendor, pack.Name, version)) {
+			plan.add(nil, PlanRemove, pack.Vendor+"::"+pack.Name+"@"+version, filepath.Join(Installation.PackRoot, pack.Vendor, pack.Name, version))
+		} else {
+			plan.add(nil, PlanRemove, pack.Vendor+"::"+pack.Name+"@"+version, "reference in local_repository.pidx")
+		}
+	}
+
+	if purge {
+		files, err := pack.purgedFiles()
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			plan.add(nil, PlanPurge, file, "")
+		}
+	}
+
+	if withDeps {
+		// Orphans are worked out like removeOrphanedDependencies does once the packs are gone
+		removedKeys := removal.removedKeys()
+		remaining := []*PackType{}
+		for _, installed := range packs {
+			if !removedKeys[strings.ToLower(installed.Key())] {
+				remaining = append(remaining, installed)
+			}
+		}
+		if err := Installation.readDependenciesPidx(); err != nil {
+			return err
+		}
+		for _, orphan := range orphanedDependencies(remaining) {
+			if removal.dependencies[strings.ToLower(orphan.Key())] {
+				plan.add(nil, PlanRemove, orphan.YamlPackID(), "no longer required by any explicitly installed pack")
+			}
+		}
+	}
+
+	return nil
+}
+
+// PlanAddPdsc works out what AddPdsc would do, without changing the pack root
+func PlanAddPdsc(plan *Plan, pdscPath string) error {
+	if !utils.FileExists(pdscPath) {
+		log.Errorf("File %q doesn't exist", pdscPath)
+		return errs.ErrFileNotFound
+	}
+	absPath, err := filepath.Abs(pdscPath)
+	if err != nil {
+		return err
+	}
+	plan.add(nil, PlanAdd, absPath, "reference in local_repository.pidx")
+	return nil
+}
+
+// PlanRemovePdsc works out what RemovePdsc would do, without changing the pack root
+func PlanRemovePdsc(plan *Plan, pdscPath string) error {
+	if err := readExistingIndexFiles(); err != nil {
+		return err
+	}
+
+	info, err := utils.ExtractPackInfo(pdscPath)
+	if err != nil {
+		return err
+	}
+	if len(Installation.LocalPidx.FindPdscTags(xml.PdscTag{Vendor: info.Vendor, Name: info.Pack})) == 0 {
+		return errs.ErrPdscEntryNotFound
+	}
+	plan.add(nil, PlanRemove, pdscPath, "reference in local_repository.pidx")
+	return nil
+}
+
+// addPack resolves the pack like AddPack does and plans its installation
+func (p *Plan) addPack(parent *PlanStep, packPath string, isDep, checkEula, extractEula, forceReinstall, noRequirements, insecureSkipVerify, testing bool) error {
+	pack, err := preparePack(packPath, false, false, false, true)
+	if err != nil {
+		return err
+	}
+	if pack.isPackID {
+		if pack.path, err = FindPackURL(pack, insecureSkipVerify, testing); err != nil {
+			return err
+		}
+	}
+
+	if !extractEula && pack.isInstalled && !forceReinstall {
+		p.add(parent, PlanSkip, planPackID(pack), "already installed")
+		return nil
+	}
+
+	details := ""
+	if isDep {
+		details = "dependency"
+	} else if pack.isInstalled {
+		details = "reinstall"
+	}
+	return p.installPack(parent, pack, details, checkEula, extractEula, noRequirements, insecureSkipVerify, testing)
+}
+
+// updatePack resolves the pack like UpdatePack does and plans the installation of its latest version
+func (p *Plan) updatePack(packPath string, checkEula, noRequirements, insecureSkipVerify, testing bool) error {
+	pack, err := preparePack(packPath, false, true, true, true)
+	if err != nil {
+		return err
+	}
+
+	if pack.isInstalled {
+		p.add(nil, PlanSkip, packPath, fmt.Sprintf("latest version %s already installed", pack.targetVersion))
+		return nil
+	}
+	if !pack.IsPublic {
+		p.add(nil, PlanSkip, packPath, "not installed")
+		return nil
+	}
+
+	if pack.isPackID {
+		if pack.path, err = FindPackURL(pack, insecureSkipVerify, testing); err != nil {
+			return err
+		}
+	}
+	return p.installPack(nil, pack, "update", checkEula, false, noRequirements, insecureSkipVerify, testing)
+}
+
+// installPack plans fetching and extracting a resolved pack, the license prompt and its requirements
+func (p *Plan) installPack(parent *PlanStep, pack *PackType, details string, checkEula, extractEula, noRequirements, insecureSkipVerify, testing bool) error {
+	key := strings.ToLower(pack.PackIDWithVersion())
+	if p.planned[key] {
+		p.add(parent, PlanSkip, planPackID(pack), "already planned")
+		return nil
+	}
+	p.planned[key] = true
+
+	step := p.add(parent, PlanAdd, planPackID(pack), details)
+
+	// Pack files get downloaded to ".Download/" unless already cached there
+	packFilePath := pack.path
+	if strings.HasPrefix(pack.path, "http") {
+		packFilePath = ""
+		if packURL, err := url.Parse(pack.path); err == nil {
+			if cachedPath := filepath.Join(Installation.DownloadDir, path.Base(packURL.Path)); utils.FileExists(cachedPath) {
+				packFilePath = cachedPath
+			}
+		}
+		if packFilePath == "" {
+			p.add(step, PlanDownload, pack.path, "")
+		}
+	} else if !utils.FileExists(pack.path) {
+		log.Errorf("File %q doesn't exist", pack.path)
+		return errs.ErrFileNotFound
+	}
+
+	// The license and requirements are read from the pack file if at hand, otherwise from its PDSC file
+	var pdscXML *xml.PdscXML
+	if packFilePath != "" {
+		var err error
+		if pdscXML, err = readPackFilePdsc(packFilePath, pack.PdscFileName()); err != nil {
+			return err
+		}
+	} else if pdscFilePath := findPackPdsc(pack); pdscFilePath != "" {
+		pdscXML = xml.NewPdscXML(pdscFilePath)
+		if err := pdscXML.Read(); err != nil {
+			pdscXML = nil
+		}
+	}
+
+	packHomeDir := filepath.Join(Installation.PackRoot, pack.Vendor, pack.Name, pack.GetVersionNoMeta())
+	if pdscXML == nil {
+		p.add(step, PlanNote, "license and requirements", "unknown until the pack is downloaded")
+	} else if pdscXML.License != "" {
+		switch {
+		case extractEula:
+			p.add(step, PlanLicense, pdscXML.License, "extract to "+Installation.DownloadDir)
+		case checkEula:
+			p.add(step, PlanLicense, pdscXML.License, "prompt for agreement")
+		default:
+			p.add(step, PlanLicense, pdscXML.License, "agreed")
+		}
+	}
+	if extractEula {
+		return nil
+	}
+
+	source := pack.path
+	if packFilePath != "" {
+		source = packFilePath
+	}
+	p.add(step, PlanExtract, packHomeDir, "from "+source)
+
+	if noRequirements || pdscXML == nil {
+		return nil
+	}
+	requirements, err := resolveRequirements(pack, pdscXML, insecureSkipVerify, 0)
+	if err != nil {
+		return err
+	}
+	for _, dependency := range pdscXML.Dependencies() {
+		requireStep := p.add(step, PlanRequire, utils.FormatPackVersion(dependency), "")
+		if node := requirements.packs[resolverKey(dependency[1], dependency[0])]; node.installed {
+			requireStep.Details = "installed: " + node.Version
+		} else if node.Version != "" {
+			requireStep.Details = "resolved: " + node.Version
+		}
+	}
+	// Missing packs are added in the order they would be installed, requirements first
+	for _, node := range requirements.order {
+		if err := p.addPack(step, node.path(), true, checkEula, false, false, true, insecureSkipVerify, testing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
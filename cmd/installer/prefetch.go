@@ -0,0 +1,241 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"archive/zip"
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/semaphore"
+)
+
+// StagingDirName is the directory, in the transaction directory, the packs
+// fetched by PrefetchPacks get extracted to
+const StagingDirName = "staging"
+
+// resolvedPackURL is what FindPackURL found for a pack
+type resolvedPackURL struct {
+	url           string
+	targetVersion string
+}
+
+// stagedPack is a pack extracted ahead of its installation
+type stagedPack struct {
+	// dir holds the extracted files
+	dir string
+
+	// subfolder is the folder stripped from the files of the pack, see PackType.Subfolder
+	subfolder string
+}
+
+// PrefetchPacks downloads and extracts the packs about to be added or updated, several at a time,
+// so that a batch of packs does not wait for each download in turn. The packs are staged in the
+// transaction in progress, then picked up by AddPack or UpdatePack, which still install them,
+// check their licenses, install their requirements and write the index files one pack at a time.
+//
+// Pack IDs get resolved to URLs beforehand, one after the other. Packs that do not need to be
+// installed are left out. A pack failing to be fetched is left out as well: it gets fetched
+// again when installed, which reports the failure.
+//
+// Parameters:
+//   - packPaths: The packs about to be added or updated, as passed to AddPack or UpdatePack.
+//   - update: If true, the packs are about to be updated to their latest version.
+//   - forceReinstall: If true, packs already installed are about to be reinstalled.
+//   - insecureSkipVerify: If true, skips TLS certificate verification for HTTPS downloads.
+//   - testing: If true, skips updating the public index.
+//   - concurrency: The number of packs to fetch at a time. If 0, nothing gets prefetched.
+//   - timeout: The timeout for each download operation.
+//
+// Returns:
+//   - error: An error if the index files cannot be read, or nil if successful.
+func PrefetchPacks(packPaths []string, update, forceReinstall, insecureSkipVerify, testing bool, concurrency, timeout int) error {
+	if Installation.transaction == nil || CheckConcurrency(concurrency) == 0 {
+		return nil
+	}
+
+	if err := ReadIndexFiles(); err != nil {
+		return err
+	}
+	if !testing {
+		for _, packPath := range packPaths {
+			if global, err := isGlobal(packPath); err == nil && global {
+				if err := UpdatePublicIndexIfOnline(); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	prefetchPacks(packPaths, update, forceReinstall, insecureSkipVerify, testing, concurrency, timeout)
+	return nil
+}
+
+// prefetchPacks fetches the packs, see PrefetchPacks
+func prefetchPacks(packPaths []string, update, forceReinstall, insecureSkipVerify, testing bool, concurrency, timeout int) {
+	t := Installation.transaction
+	concurrency = CheckConcurrency(concurrency)
+	if t == nil || concurrency == 0 {
+		return
+	}
+
+	var packs []*PackType
+	queued := map[string]bool{}
+	for _, packPath := range packPaths {
+		if filepath.Ext(packPath) == utils.PdscExtension {
+			continue
+		}
+		pack, err := preparePack(packPath, false, update, update, true)
+		if err != nil {
+			continue
+		}
+		if pack.isInstalled && (update || !forceReinstall) {
+			continue
+		}
+		if update && !pack.IsPublic {
+			continue
+		}
+		if pack.isPackID {
+			if pack.path, err = findPackURL(pack, insecureSkipVerify, testing); err != nil {
+				continue
+			}
+		}
+		if queued[pack.PackIDWithVersion()] {
+			continue
+		}
+		queued[pack.PackIDWithVersion()] = true
+		packs = append(packs, pack)
+	}
+
+	// A single pack is better fetched when installed
+	if len(packs) < 2 {
+		return
+	}
+
+	log.Infof("Fetching %d packs, up to %d at a time", len(packs), concurrency)
+
+	ctx := context.TODO()
+	sem := semaphore.NewWeighted(int64(concurrency))
+
+	for _, pack := range packs {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			log.Errorf("Failed to acquire semaphore: %v", err)
+			break
+		}
+
+		go func(pack *PackType) {
+			defer sem.Release(1)
+			t.prefetch(pack, insecureSkipVerify, timeout)
+		}(pack)
+	}
+	if err := sem.Acquire(ctx, int64(concurrency)); err != nil {
+		log.Errorf("Failed to acquire semaphore: %v", err)
+	}
+}
+
+// prefetch downloads the pack file, if it is not a local one, and extracts it to the staging directory
+func (t *transaction) prefetch(pack *PackType, insecureSkipVerify bool, timeout int) {
+	packFilePath := pack.path
+	if strings.HasPrefix(packFilePath, "http") {
+		var err error
+		if packFilePath, err = utils.DownloadFile(packFilePath, true, true, false, insecureSkipVerify, timeout); err != nil {
+			log.Debugf("Could not prefetch %q: %v", pack.path, err)
+			return
+		}
+	}
+
+	zipReader, err := zip.OpenReader(packFilePath)
+	if err != nil {
+		log.Debugf("Could not prefetch %q: %v", pack.path, err)
+		return
+	}
+	defer zipReader.Close()
+
+	staged := stagedPack{
+		dir:       filepath.Join(t.dir, StagingDirName, pack.PackIDWithVersion()),
+		subfolder: pdscSubfolder(zipReader, pack.PdscFileName()),
+	}
+	log.Debugf("Extracting files from %q to %q", packFilePath, staged.dir)
+	for _, file := range zipReader.File {
+		if err := utils.SecureInflateFile(file, staged.dir, staged.subfolder); err != nil {
+			log.Debugf("Could not prefetch %q: %v", pack.path, err)
+			_ = removePath(staged.dir)
+			return
+		}
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.staged[pack.PackIDWithVersion()] = staged
+}
+
+// pdscSubfolder returns the folder the pdsc file of the pack was compressed in,
+// the same way validate does, or an empty string if there is none
+func pdscSubfolder(zipReader *zip.ReadCloser, pdscFileName string) string {
+	for _, file := range zipReader.File {
+		if strings.ToLower(filepath.Ext(file.Name)) != utils.PdscExtension {
+			continue
+		}
+		subfoldersCount := strings.Count(file.Name, "/") + strings.Count(file.Name, "\\")
+		if subfoldersCount == 1 && strings.EqualFold(filepath.Base(file.Name), pdscFileName) {
+			return filepath.Dir(file.Name)
+		}
+	}
+	return ""
+}
+
+// takeStagedPack returns the directory the pack got extracted to by PrefetchPacks, handing
+// it over to the caller, or an empty string if the pack was not extracted the way it is
+// about to be installed
+func (p *PacksInstallationType) takeStagedPack(pack *PackType) string {
+	t := p.transaction
+	if t == nil {
+		return ""
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	staged, found := t.staged[pack.PackIDWithVersion()]
+	if !found {
+		return ""
+	}
+	delete(t.staged, pack.PackIDWithVersion())
+	if staged.subfolder != pack.Subfolder || !utils.DirExists(staged.dir) {
+		return ""
+	}
+	return staged.dir
+}
+
+// findPackURL calls FindPackURL, reusing what it found for the same pack earlier in
+// the transaction, when prefetching it, so that each pack gets resolved only once
+func findPackURL(pack *PackType, insecureSkipVerify, testing bool) (string, error) {
+	t := Installation.transaction
+	if t == nil {
+		return FindPackURL(pack, insecureSkipVerify, testing)
+	}
+
+	key := pack.path + "@" + pack.Version
+	t.lock.Lock()
+	resolved, found := t.packURLs[key]
+	t.lock.Unlock()
+	if found {
+		pack.targetVersion = resolved.targetVersion
+		return resolved.url, nil
+	}
+
+	url, err := FindPackURL(pack, insecureSkipVerify, testing)
+	if err != nil {
+		return "", err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.packURLs[key] = resolvedPackURL{url: url, targetVersion: pack.targetVersion}
+	return url, nil
+}
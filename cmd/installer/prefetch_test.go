@@ -0,0 +1,150 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefetchPacks(t *testing.T) {
+
+	assert := assert.New(t)
+
+	stagingDir := func(packID string) string {
+		return filepath.Join(installer.Installation.LocalDir, installer.TransactionDirName, installer.StagingDirName, packID)
+	}
+
+	t.Run("test prefetching packs from the local file system", func(t *testing.T) {
+		localTestingDir := "test-prefetch-packs-local"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		packsDir := t.TempDir()
+		packPaths := []string{
+			createFakePackFile(packsDir, "TheVendor", "First", "1.0.0"),
+			createFakePackFile(packsDir, "TheVendor", "Second", "1.0.0"),
+			createFakePackFile(packsDir, "TheVendor", "Third", "1.0.0"),
+		}
+
+		assert.Nil(installer.BeginTransaction("add"))
+		assert.Nil(installer.PrefetchPacks(packPaths, false, !ForceReinstall, !InsecureSkipVerify, true, 2, Timeout))
+		assert.FileExists(filepath.Join(stagingDir("TheVendor.First.1.0.0"), "TheVendor.First.pdsc"))
+		assert.FileExists(filepath.Join(stagingDir("TheVendor.Second.1.0.0"), "TheVendor.Second.pdsc"))
+		assert.FileExists(filepath.Join(stagingDir("TheVendor.Third.1.0.0"), "TheVendor.Third.pdsc"))
+
+		// Installing the packs picks up their extracted files
+		for _, packPath := range packPaths {
+			assert.Nil(installer.AddPack(packPath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		}
+		assert.NoDirExists(stagingDir("TheVendor.First.1.0.0"))
+		assert.Nil(installer.EndTransaction(nil))
+
+		for _, name := range []string{"First", "Second", "Third"} {
+			assert.FileExists(filepath.Join(localTestingDir, "TheVendor", name, "1.0.0", "TheVendor."+name+".pdsc"))
+			assert.FileExists(filepath.Join(installer.Installation.DownloadDir, "TheVendor."+name+".1.0.0.pack"))
+		}
+		assert.NoDirExists(filepath.Join(installer.Installation.LocalDir, installer.TransactionDirName))
+	})
+
+	t.Run("test prefetching packs from a server", func(t *testing.T) {
+		localTestingDir := "test-prefetch-packs-server"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		packServer := NewServer()
+		packsDir := t.TempDir()
+		var packPaths []string
+		for _, name := range []string{"First", "Second"} {
+			packFilePath := createFakePackFile(packsDir, "TheVendor", name, "1.0.0")
+			content, err := os.ReadFile(packFilePath)
+			assert.Nil(err)
+			packServer.AddRoute(filepath.Base(packFilePath), content)
+			packPaths = append(packPaths, packServer.URL()+filepath.Base(packFilePath))
+		}
+
+		assert.Nil(installer.BeginTransaction("add"))
+		assert.Nil(installer.PrefetchPacks(packPaths, false, !ForceReinstall, !InsecureSkipVerify, true, 2, Timeout))
+		assert.FileExists(filepath.Join(installer.Installation.DownloadDir, "TheVendor.First.1.0.0.pack"))
+		assert.FileExists(filepath.Join(installer.Installation.DownloadDir, "TheVendor.Second.1.0.0.pack"))
+		assert.DirExists(stagingDir("TheVendor.First.1.0.0"))
+		assert.DirExists(stagingDir("TheVendor.Second.1.0.0"))
+
+		for _, packPath := range packPaths {
+			assert.Nil(installer.AddPack(packPath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		}
+		assert.Nil(installer.EndTransaction(nil))
+
+		assert.DirExists(filepath.Join(localTestingDir, "TheVendor", "First", "1.0.0"))
+		assert.DirExists(filepath.Join(localTestingDir, "TheVendor", "Second", "1.0.0"))
+	})
+
+	t.Run("test prefetching nothing without concurrency", func(t *testing.T) {
+		localTestingDir := "test-prefetch-packs-no-concurrency"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		packsDir := t.TempDir()
+		packPaths := []string{
+			createFakePackFile(packsDir, "TheVendor", "First", "1.0.0"),
+			createFakePackFile(packsDir, "TheVendor", "Second", "1.0.0"),
+		}
+
+		// Outside transactions either
+		assert.Nil(installer.PrefetchPacks(packPaths, false, !ForceReinstall, !InsecureSkipVerify, true, 2, Timeout))
+		assert.NoDirExists(filepath.Join(installer.Installation.LocalDir, installer.TransactionDirName))
+
+		assert.Nil(installer.BeginTransaction("add"))
+		assert.Nil(installer.PrefetchPacks(packPaths, false, !ForceReinstall, !InsecureSkipVerify, true, Concurrency, Timeout))
+		assert.NoDirExists(filepath.Join(installer.Installation.LocalDir, installer.TransactionDirName, installer.StagingDirName))
+		assert.Nil(installer.EndTransaction(nil))
+	})
+
+	t.Run("test prefetching leaves installed and missing packs out", func(t *testing.T) {
+		localTestingDir := "test-prefetch-packs-skipped"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		packsDir := t.TempDir()
+		installedPackPath := createFakePackFile(packsDir, "TheVendor", "Installed", "1.0.0")
+		assert.Nil(installer.AddPack(installedPackPath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		packPaths := []string{
+			installedPackPath,
+			createFakePackFile(packsDir, "TheVendor", "First", "1.0.0"),
+			createFakePackFile(packsDir, "TheVendor", "Second", "1.0.0"),
+			filepath.Join(packsDir, "TheVendor.Missing.1.0.0.pack"),
+		}
+
+		assert.Nil(installer.BeginTransaction("add"))
+		assert.Nil(installer.PrefetchPacks(packPaths, false, !ForceReinstall, !InsecureSkipVerify, true, 2, Timeout))
+		assert.NoDirExists(stagingDir("TheVendor.Installed.1.0.0"))
+		assert.NoDirExists(stagingDir("TheVendor.Missing.1.0.0"))
+		assert.DirExists(stagingDir("TheVendor.First.1.0.0"))
+
+		// The missing pack gets reported when installed, rolling back the batch along with the staged packs
+		var err error
+		for _, packPath := range packPaths {
+			if err = installer.AddPack(packPath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout); err != nil {
+				break
+			}
+		}
+		assert.ErrorIs(installer.EndTransaction(err), errs.ErrFileNotFound)
+		assert.NoDirExists(filepath.Join(localTestingDir, "TheVendor", "First"))
+		assert.NoDirExists(filepath.Join(installer.Installation.LocalDir, installer.TransactionDirName))
+		assert.DirExists(filepath.Join(localTestingDir, "TheVendor", "Installed", "1.0.0"))
+	})
+}
@@ -0,0 +1,171 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"archive/zip"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	log "github.com/sirupsen/logrus"
+)
+
+// pdscURLPattern matches the <url> of the package, the first one of a PDSC file
+var pdscURLPattern = regexp.MustCompile(`(?s)<url>.*?</url>`)
+
+// setPdscURL returns the contents of a PDSC file with the <url> of its package set to URL,
+// leaving the rest of the file untouched, or false if the file has no <url>
+func setPdscURL(contents []byte, URL string) ([]byte, bool) {
+	loc := pdscURLPattern.FindIndex(contents)
+	if loc == nil {
+		return contents, false
+	}
+	newContents := append([]byte{}, contents[:loc[0]]...)
+	newContents = append(newContents, "<url>"+html.EscapeString(URL)+"</url>"...)
+	return append(newContents, contents[loc[1]:]...), true
+}
+
+// PublishPack copies the pack file at packPath, and its unversioned PDSC file, into the
+// registry directory registryDir, then updates or inserts the entry of the pack in the
+// index file of the registry. The registry can then be served by any static web server,
+// or used as a file:// index.
+//
+// The pack gets checked like the packs being installed. The <url> of the copied PDSC file
+// is set to the one of the registry, for the pack to be downloaded from it.
+//
+// Parameters:
+//   - packPath: The pack file to publish.
+//   - registryDir: The registry directory, created if missing.
+//   - registryURL: The URL the registry is served at. If empty, the url of its index file,
+//     or the file:// URL of registryDir for a new registry.
+//
+// Returns:
+//   - string: The path of the published pack file.
+//   - error: errs.ErrPackOlderThanPublished if the registry has a newer version of the pack.
+func PublishPack(packPath, registryDir, registryURL string) (string, error) {
+	packPath, err := utils.FileURLToPath(packPath)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(filepath.Ext(packPath), utils.PackExtension) {
+		return "", fmt.Errorf("%q: %w", packPath, errs.ErrBadPackName)
+	}
+	if !utils.FileExists(packPath) {
+		return "", fmt.Errorf("%q: %w", packPath, errs.ErrFileNotFound)
+	}
+	info, err := utils.ExtractPackInfo(packPath)
+	if err != nil {
+		return "", err
+	}
+
+	pack := &PackType{path: packPath}
+	pack.Vendor = info.Vendor
+	pack.Name = info.Pack
+	pack.Version = info.Version
+	pack.versionModifier = info.VersionModifier
+
+	pack.zipReader, err = zip.OpenReader(packPath)
+	if err != nil {
+		log.Errorf("Can't decompress %q: %s", packPath, err)
+		return "", errs.ErrFailedDecompressingFile
+	}
+	defer pack.zipReader.Close()
+
+	tmpPdscDir, err := os.MkdirTemp("", "cpackget-publish-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpPdscDir)
+
+	pdscFilePath, err := pack.checkContents(tmpPdscDir)
+	if err != nil {
+		return "", err
+	}
+
+	indexPath := filepath.Join(registryDir, PublicIndexName)
+	pidxXML := xml.NewPidxXML(indexPath, false)
+	if utils.FileExists(indexPath) {
+		if err := pidxXML.Read(); err != nil {
+			return "", err
+		}
+		if registryURL == "" {
+			registryURL = pidxXML.URL
+		}
+	} else {
+		pidxXML.Clear()
+		pidxXML.SchemaVersion = "1.1.0"
+		pidxXML.Vendor = pack.Vendor
+	}
+	if registryURL == "" {
+		if registryURL, err = utils.PathToFileURL(registryDir); err != nil {
+			return "", err
+		}
+	}
+	if !strings.HasSuffix(registryURL, "/") {
+		registryURL += "/"
+	}
+
+	// The registry must not go back to an older version of the pack
+	found := pidxXML.FindPdscNameTags(pack.PdscTag)
+	if len(found) > 0 && utils.SemverCompare(found[0].Version, pack.GetVersionNoMeta()) > 0 {
+		return "", fmt.Errorf("%s, published %s: %w", pack.PackIDWithVersion(), found[0].Version, errs.ErrPackOlderThanPublished)
+	}
+
+	pdscContents, err := os.ReadFile(pdscFilePath) // #nosec
+	if err != nil {
+		return "", err
+	}
+	pdscContents, hasURL := setPdscURL(pdscContents, registryURL)
+	if !hasURL {
+		return "", fmt.Errorf("%q: missing url: %w", pack.PdscFileName(), errs.ErrInvalidPackIndex)
+	}
+	if err := os.WriteFile(pdscFilePath, pdscContents, 0600); err != nil {
+		return "", err
+	}
+	if releaseTag := pack.Pdsc.FindReleaseTagByVersion(pack.Version); releaseTag != nil && releaseTag.URL != "" {
+		log.Warnf("%s gets downloaded from %q, the url of its release, not from the registry", pack.PackIDWithVersion(), releaseTag.URL)
+	}
+
+	tag, err := readIndexPdscTag(pdscFilePath, "")
+	if err != nil {
+		return "", err
+	}
+	for _, foundTag := range found {
+		tag.Deprecated = foundTag.Deprecated
+		tag.Replacement = foundTag.Replacement
+		_ = pidxXML.RemovePdsc(foundTag)
+	}
+	_ = pidxXML.AddPdsc(tag)
+
+	pidxXML.URL = registryURL
+	pidxXML.TimeStamp = time.Now().Format(time.RFC3339Nano)
+	if err := pidxXML.Validate(); err != nil {
+		return "", err
+	}
+
+	// The index gets written last, for it to only list packs whose files are in place
+	if err := utils.EnsureDir(registryDir); err != nil {
+		return "", err
+	}
+	publishedPackPath := filepath.Join(registryDir, pack.PackFileName())
+	if err := utils.CopyFile(packPath, publishedPackPath); err != nil {
+		return "", err
+	}
+	if err := utils.CopyFile(pdscFilePath, filepath.Join(registryDir, pack.PdscFileName())); err != nil {
+		return "", err
+	}
+	if err := pidxXML.Write(); err != nil {
+		return "", err
+	}
+	log.Infof("Published %s to %q", pack.PackIDWithVersion(), registryDir)
+	return publishedPackPath, nil
+}
@@ -0,0 +1,124 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishPack(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test publishing packs to a new registry", func(t *testing.T) {
+		registryDir := filepath.Join(t.TempDir(), "registry")
+		registryURL, err := utils.PathToFileURL(registryDir)
+		assert.Nil(err)
+
+		packPath, err := installer.PublishPack(publicLocalPack123, registryDir, "")
+		assert.Nil(err)
+		assert.Equal(filepath.Join(registryDir, "TheVendor.PublicLocalPack.1.2.3.pack"), packPath)
+		assert.FileExists(packPath)
+
+		pdscXML := xml.NewPdscXML(filepath.Join(registryDir, "TheVendor.PublicLocalPack.pdsc"))
+		assert.Nil(pdscXML.Read())
+		assert.Equal(registryURL+"/", pdscXML.URL)
+
+		pidxXML := xml.NewPidxXML(filepath.Join(registryDir, installer.PublicIndexName), false)
+		assert.Nil(pidxXML.Read())
+		assert.Equal("TheVendor", pidxXML.Vendor)
+		assert.Equal(registryURL+"/", pidxXML.URL)
+		assert.Nil(pidxXML.Validate())
+		tags := pidxXML.FindPdscNameTags(xml.PdscTag{Vendor: "TheVendor", Name: "PublicLocalPack"})
+		assert.Len(tags, 1)
+		assert.Equal("1.2.3", tags[0].Version)
+		assert.Equal(registryURL+"/", tags[0].URL)
+
+		// A newer version replaces the entry of the pack, keeping its attributes
+		_ = pidxXML.RemovePdsc(tags[0])
+		tags[0].Deprecated = "2025-06-30"
+		assert.Nil(pidxXML.AddPdsc(tags[0]))
+		assert.Nil(pidxXML.Write())
+
+		_, err = installer.PublishPack(publicLocalPack124, registryDir, "")
+		assert.Nil(err)
+		assert.Nil(pidxXML.Read())
+		tags = pidxXML.FindPdscNameTags(xml.PdscTag{Vendor: "TheVendor", Name: "PublicLocalPack"})
+		assert.Len(tags, 1)
+		assert.Equal("1.2.4", tags[0].Version)
+		assert.Equal("2025-06-30", tags[0].Deprecated)
+
+		// The registry does not go back to an older version
+		_, err = installer.PublishPack(publicLocalPack123, registryDir, "")
+		assert.True(errors.Is(err, errs.ErrPackOlderThanPublished))
+		pdscXML = xml.NewPdscXML(filepath.Join(registryDir, "TheVendor.PublicLocalPack.pdsc"))
+		assert.Nil(pdscXML.Read())
+		assert.Equal("1.2.4", pdscXML.LatestVersion())
+	})
+
+	t.Run("test publishing with the url of the registry", func(t *testing.T) {
+		registryDir := t.TempDir()
+
+		_, err := installer.PublishPack(publicLocalPack123, registryDir, "https://packs.the.vendor/registry")
+		assert.Nil(err)
+		_, err = installer.PublishPack(nonPublicLocalPack123, registryDir, "")
+		assert.Nil(err)
+
+		pidxXML := xml.NewPidxXML(filepath.Join(registryDir, installer.PublicIndexName), false)
+		assert.Nil(pidxXML.Read())
+		assert.Equal("https://packs.the.vendor/registry/", pidxXML.URL)
+		for _, tag := range pidxXML.ListPdscTags() {
+			assert.Equal("https://packs.the.vendor/registry/", tag.URL)
+		}
+		assert.Len(pidxXML.ListPdscTags(), 2)
+	})
+
+	t.Run("test publishing invalid packs", func(t *testing.T) {
+		registryDir := filepath.Join(t.TempDir(), "registry")
+
+		_, err := installer.PublishPack(packWithoutPdscFileInside, registryDir, "")
+		assert.Equal(errs.ErrPdscFileNotFound, err)
+		_, err = installer.PublishPack(pack123VersionNotLatest, registryDir, "")
+		assert.Equal(errs.ErrPackVersionNotLatestReleasePdsc, err)
+		_, err = installer.PublishPack(packWithCorruptZip, registryDir, "")
+		assert.Equal(errs.ErrFailedDecompressingFile, err)
+		_, err = installer.PublishPack(packThatDoesNotExist, registryDir, "")
+		assert.True(errors.Is(err, errs.ErrFileNotFound))
+		_, err = installer.PublishPack(pdscPack123, registryDir, "")
+		assert.True(errors.Is(err, errs.ErrBadPackName))
+		assert.NoDirExists(registryDir)
+	})
+
+	t.Run("test adding packs from a file url registry", func(t *testing.T) {
+		registryDir := t.TempDir()
+		_, err := installer.PublishPack(publicLocalPack124, registryDir, "")
+		assert.Nil(err)
+		registryURL, err := utils.PathToFileURL(registryDir)
+		assert.Nil(err)
+
+		localTestingDir := "test-add-from-file-url-registry"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		assert.Nil(installer.UpdatePublicIndex(registryURL, true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		assert.Nil(installer.AddPack(publicLocalPackLegacyPackID+"@1.2.4", !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		assert.DirExists(filepath.Join(localTestingDir, "TheVendor", "PublicLocalPack", "1.2.4"))
+		assert.FileExists(filepath.Join(localTestingDir, ".Download", "TheVendor.PublicLocalPack.1.2.4.pack"))
+
+		// The registry is left as it was
+		entries, err := os.ReadDir(registryDir)
+		assert.Nil(err)
+		assert.Len(entries, 3)
+	})
+}
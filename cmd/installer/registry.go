@@ -0,0 +1,276 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	log "github.com/sirupsen/logrus"
+)
+
+// PublicRegistryName is the name of the registry of the public index, in .Web
+const PublicRegistryName = "public"
+
+// RegistriesDirName is the folder, in .Web, holding a folder for each registry other than
+// the public one, with its own index.pidx and PDSC files
+const RegistriesDirName = "registries"
+
+// Registry is an index of packs. Packs are resolved from the first registry, in priority order,
+// listing them and allowed to provide their vendor.
+type Registry struct {
+	// Name identifies the registry, PublicRegistryName for the public index
+	Name string
+
+	// URL of the index file of the registry. The one of the public index is given to init instead.
+	URL string
+
+	// Allow lists the patterns, see path.Match, of the vendors the registry provides
+	// packs of, ignoring case. All vendors are allowed if empty.
+	Allow []string
+
+	// Deny lists the patterns of the vendors the registry never provides packs of
+	Deny []string
+
+	// webDir holds the index file and the PDSC files of the registry
+	webDir string
+
+	// index is the path of the index file of the registry, read into indexXML
+	index    string
+	indexXML *xml.PidxXML
+}
+
+// IsPublic tells whether r is the registry of the public index
+func (r *Registry) IsPublic() bool {
+	return r.Name == PublicRegistryName
+}
+
+// allows tells whether r may provide packs of vendor
+func (r *Registry) allows(vendor string) bool {
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(vendor)); matched {
+				return true
+			}
+		}
+		return false
+	}
+	if matches(r.Deny) {
+		return false
+	}
+	return len(r.Allow) == 0 || matches(r.Allow)
+}
+
+// gRegistries are the registries configured, in priority order, see SetRegistries
+var gRegistries []Registry
+
+// SetRegistries sets the registries consulted, in priority order, by the next pack roots set.
+// The public index comes last, unless listed as PublicRegistryName to set its priority and
+// the vendors it provides packs of. The credentials in the URLs are kept for their hosts,
+// out of the URLs written down to the pack root.
+func SetRegistries(registries []Registry) error {
+	names := map[string]bool{}
+	for i, registry := range registries {
+		if !utils.IsPackNameValid(registry.Name) || names[strings.ToLower(registry.Name)] {
+			return fmt.Errorf("%q: %w", registry.Name, errs.ErrInvalidRegistry)
+		}
+		names[strings.ToLower(registry.Name)] = true
+		if registry.IsPublic() != (registry.URL == "") {
+			return fmt.Errorf("%q: %w: only the public index is without url", registry.Name, errs.ErrInvalidRegistry)
+		}
+		for _, pattern := range append(registry.Allow, registry.Deny...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%q: %w: %v", registry.Name, errs.ErrInvalidRegistry, err)
+			}
+		}
+		registries[i].URL = utils.StripCredentials(registry.URL)
+	}
+	gRegistries = registries
+	return nil
+}
+
+// setRegistries sets up the registries configured in the pack root
+func (p *PacksInstallationType) setRegistries() {
+	p.Registries = nil
+	hasPublic := false
+	for _, registry := range gRegistries {
+		if registry.IsPublic() {
+			hasPublic = true
+			p.Registries = append(p.Registries, p.newPublicRegistry(registry.Allow, registry.Deny))
+			continue
+		}
+		registry.webDir = filepath.Join(p.WebDir, RegistriesDirName, registry.Name)
+		registry.index = filepath.Join(registry.webDir, PublicIndexName)
+		registry.indexXML = xml.NewPidxXML(registry.index, false)
+		p.Registries = append(p.Registries, &registry)
+	}
+	if !hasPublic {
+		p.Registries = append(p.Registries, p.newPublicRegistry(nil, nil))
+	}
+}
+
+// newPublicRegistry returns the registry of the public index
+func (p *PacksInstallationType) newPublicRegistry(allow, deny []string) *Registry {
+	return &Registry{
+		Name:     PublicRegistryName,
+		Allow:    allow,
+		Deny:     deny,
+		webDir:   p.WebDir,
+		index:    p.PublicIndex,
+		indexXML: p.PublicIndexXML,
+	}
+}
+
+// publicRegistry returns the registry of the public index
+func (p *PacksInstallationType) publicRegistry() *Registry {
+	for _, registry := range p.Registries {
+		if registry.IsPublic() {
+			return registry
+		}
+	}
+	return p.newPublicRegistry(nil, nil)
+}
+
+// findRegistry returns the registry Vendor.Name resolves from, along with its tags in the index of
+// the registry. The registries are consulted in priority order, skipping those not allowed to
+// provide packs of vendor. Nothing is returned if no registry lists the pack.
+func (p *PacksInstallationType) findRegistry(vendor, name string) (*Registry, []xml.PdscTag) {
+	registries := p.Registries
+	if len(registries) == 0 {
+		registries = []*Registry{p.publicRegistry()}
+	}
+	for _, registry := range registries {
+		if !registry.allows(vendor) {
+			log.Debugf("Registry %q does not provide packs of %q", registry.Name, vendor)
+			continue
+		}
+		if tags := registry.indexXML.FindPdscTags(xml.PdscTag{Vendor: vendor, Name: name}); len(tags) > 0 {
+			return registry, tags
+		}
+	}
+	return nil, nil
+}
+
+// readRegistries reads the index files of the registries other than the public one,
+// leaving empty the ones not downloaded yet. The public registry follows the public index.
+func (p *PacksInstallationType) readRegistries() error {
+	for _, registry := range p.Registries {
+		if registry.IsPublic() {
+			registry.webDir = p.WebDir
+			registry.index = p.PublicIndex
+			registry.indexXML = p.PublicIndexXML
+			continue
+		}
+		if !utils.FileExists(registry.index) {
+			registry.indexXML.Clear()
+			continue
+		}
+		if err := registry.indexXML.Read(); err != nil {
+			return fmt.Errorf("%q: %w", registry.index, err)
+		}
+	}
+	return nil
+}
+
+// UpdateRegistries updates the index files of the registries other than the public one, in
+// priority order, along with the PDSC files already downloaded from them unless sparse is set.
+// A registry failing to update does not prevent the others from being updated.
+//
+// Parameters:
+//   - sparse: If true, only the index files get updated.
+//   - insecureSkipVerify: If true, skips TLS certificate verification for HTTPS downloads.
+//   - timeout: The timeout in seconds of each download.
+//
+// Returns:
+//   - error: errs.ErrAlreadyLogged if any registry could not be updated, otherwise nil.
+func UpdateRegistries(sparse, insecureSkipVerify bool, timeout int) error {
+	failed := false
+	for _, registry := range Installation.Registries {
+		if registry.IsPublic() {
+			continue
+		}
+		if err := Installation.updateRegistry(registry, sparse, insecureSkipVerify, timeout); err != nil {
+			log.Errorf("Cannot update registry %q: %v", registry.Name, err)
+			failed = true
+		}
+	}
+	if err := Installation.writeValidators(); err != nil {
+		return err
+	}
+	if failed {
+		return errs.ErrAlreadyLogged
+	}
+	return nil
+}
+
+// updateRegistry downloads the index file of registry, and the PDSC files already downloaded from it
+func (p *PacksInstallationType) updateRegistry(registry *Registry, sparse, insecureSkipVerify bool, timeout int) error {
+	log.Infof("Updating registry %q", registry.Name)
+	if err := utils.EnsureDir(registry.webDir); err != nil {
+		return err
+	}
+
+	var indexPath string
+	var validators utils.Validators
+	if strings.HasPrefix(registry.URL, "http://") || strings.HasPrefix(registry.URL, "https://") {
+		var err error
+		validators = p.validatorsOf(registry.index, registry.URL)
+		indexPath, err = utils.DownloadFileIfModified(registry.URL, false, &validators, false, false, insecureSkipVerify, timeout)
+		if errors.Is(err, errs.ErrNotModified) {
+			log.Debugf("Index of registry %q did not change", registry.Name)
+			indexPath = registry.index
+		} else if err != nil {
+			return err
+		} else {
+			defer os.Remove(indexPath)
+		}
+	} else {
+		var err error
+		if indexPath, err = utils.FileURLToPath(registry.URL); err != nil {
+			return err
+		}
+		if !utils.FileExists(indexPath) {
+			return fmt.Errorf("%q: %w", indexPath, errs.ErrFileNotFound)
+		}
+	}
+
+	if indexPath != registry.index {
+		utils.UnsetReadOnly(registry.index)
+		if err := utils.CopyFile(indexPath, registry.index); err != nil {
+			return err
+		}
+		utils.SetReadOnly(registry.index)
+		p.setValidators(registry.index, registry.URL, validators)
+	}
+	if err := registry.indexXML.Read(); err != nil {
+		return err
+	}
+	if sparse {
+		return nil
+	}
+
+	// Refresh the PDSC files downloaded so far
+	matches, err := filepath.Glob(filepath.Join(registry.webDir, "*"+utils.PdscExtension))
+	if err != nil {
+		return err
+	}
+	for _, pdscFilePath := range matches {
+		vendor, name, _ := strings.Cut(strings.TrimSuffix(filepath.Base(pdscFilePath), utils.PdscExtension), ".")
+		tags := registry.indexXML.FindPdscTags(xml.PdscTag{Vendor: vendor, Name: name})
+		if len(tags) == 0 {
+			continue
+		}
+		if err := p.downloadPdscFile(registry, tags[0], false, false, false, insecureSkipVerify, timeout); err != nil {
+			log.Error(err)
+		}
+	}
+	return nil
+}
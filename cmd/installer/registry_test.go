@@ -0,0 +1,147 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistries(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test invalid registries", func(t *testing.T) {
+		defer func() { _ = installer.SetRegistries(nil) }()
+
+		err := installer.SetRegistries([]installer.Registry{{Name: "internal", URL: "https://packs.acme.com/index.pidx"}, {Name: "Internal", URL: "file:///packs/index.pidx"}})
+		assert.True(errors.Is(err, errs.ErrInvalidRegistry))
+		err = installer.SetRegistries([]installer.Registry{{Name: "internal"}})
+		assert.True(errors.Is(err, errs.ErrInvalidRegistry))
+		err = installer.SetRegistries([]installer.Registry{{Name: installer.PublicRegistryName, URL: "https://packs.acme.com/index.pidx"}})
+		assert.True(errors.Is(err, errs.ErrInvalidRegistry))
+		err = installer.SetRegistries([]installer.Registry{{Name: "not a name", URL: "https://packs.acme.com/index.pidx"}})
+		assert.True(errors.Is(err, errs.ErrInvalidRegistry))
+		err = installer.SetRegistries([]installer.Registry{{Name: "internal", URL: "https://packs.acme.com/index.pidx", Allow: []string{"Acme["}}})
+		assert.True(errors.Is(err, errs.ErrInvalidRegistry))
+	})
+
+	t.Run("test packs resolve from the first registry allowed to provide them", func(t *testing.T) {
+		server := newConditionalServer()
+		defer server.server.Close()
+		server.set("acme/index.pidx", vendorPidx("Acme", "ThePack", "1.0.0", server.URL()+"acme/"))
+
+		assert.Nil(installer.SetRegistries([]installer.Registry{
+			{Name: "acme", URL: server.URL() + "acme/index.pidx", Allow: []string{"acme*"}},
+			{Name: installer.PublicRegistryName, Deny: []string{"Acme"}},
+		}))
+		defer func() { _ = installer.SetRegistries(nil) }()
+
+		localTestingDir := "test-registries"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		// The public index lists a pack of Acme too, which must not be used
+		assert.Nil(installer.Installation.PublicIndexXML.AddPdsc(xml.PdscTag{Vendor: "Acme", Name: "ThePack", Version: "9.9.9", URL: "https://www.keil.com/pack/"}))
+		assert.Nil(installer.Installation.PublicIndexXML.AddPdsc(xml.PdscTag{Vendor: "TheVendor", Name: "PublicPack", Version: "1.2.3", URL: "https://www.keil.com/pack/"}))
+		assert.Nil(installer.Installation.PublicIndexXML.Write())
+
+		assert.Nil(installer.UpdateRegistries(true, !InsecureSkipVerify, Timeout))
+		assert.FileExists(filepath.Join(installer.Installation.WebDir, installer.RegistriesDirName, "acme", installer.PublicIndexName))
+
+		assert.Nil(utils.SetOutputFormat(utils.OutputFormatJSON))
+		defer func() { _ = utils.SetOutputFormat("") }()
+		var buf bytes.Buffer
+		utils.SetOutputWriter(&buf)
+		defer utils.SetOutputWriter(os.Stdout)
+		assert.Nil(installer.ListInstalledPacks(ListCached, ListPublic, !ListUpdates, !ListDeprecated, !ListRequirements, true, ListFilter))
+
+		records := []installer.PackRecord{}
+		assert.Nil(json.Unmarshal(buf.Bytes(), &records))
+		assert.Len(records, 2)
+		registryOf := map[string]string{}
+		for _, record := range records {
+			registryOf[record.Vendor+"::"+record.Name+"@"+record.Version] = record.Registry
+		}
+		assert.Equal(map[string]string{"Acme::ThePack@1.0.0": "acme", "TheVendor::PublicPack@1.2.3": ""}, registryOf)
+
+		// Registries that cannot be reached are reported
+		assert.Nil(installer.SetRegistries([]installer.Registry{{Name: "missing", URL: server.URL() + "missing/index.pidx"}}))
+		assert.Nil(installer.SetPackRoot(localTestingDir, !CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Equal(errs.ErrAlreadyLogged, installer.UpdateRegistries(true, !InsecureSkipVerify, Timeout))
+	})
+
+	t.Run("test registry packs are cached and purged from the folder of their registry", func(t *testing.T) {
+		var files map[string][]byte
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if username, password, _ := r.BasicAuth(); username != "user" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			content, found := files[strings.TrimPrefix(r.URL.Path, "/")]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("ETag", fmt.Sprintf(`"%x"`, len(content)))
+			_, _ = w.Write(content)
+		}))
+		defer server.Close()
+		defer utils.ResetCredentials()
+		files = map[string][]byte{
+			"acme/index.pidx":        vendorPidx("Acme", "ThePack", "1.0.0", server.URL+"/acme/"),
+			"acme/Acme.ThePack.pdsc": indexPdsc("Acme", "ThePack", server.URL+"/acme/", "1.0.0"),
+		}
+
+		registryURL := strings.Replace(server.URL, "https://", "https://user:secret@", 1) + "/acme/index.pidx"
+		assert.Nil(installer.SetRegistries([]installer.Registry{{Name: "acme", URL: registryURL}}))
+		defer func() { _ = installer.SetRegistries(nil) }()
+
+		localTestingDir := "test-registries-cache"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		// The PDSC file downloaded from the registry gets refreshed, from the same host
+		registryDir := filepath.Join(installer.Installation.WebDir, installer.RegistriesDirName, "acme")
+		assert.Nil(os.MkdirAll(registryDir, 0700))
+		pdscFilePath := filepath.Join(registryDir, "Acme.ThePack.pdsc")
+		assert.Nil(os.WriteFile(pdscFilePath, indexPdsc("Acme", "ThePack", server.URL+"/acme/", "0.9.0"), 0600))
+		assert.Nil(installer.UpdateRegistries(false, !InsecureSkipVerify, Timeout))
+		content, err := os.ReadFile(pdscFilePath)
+		assert.Nil(err)
+		assert.Equal(files["acme/Acme.ThePack.pdsc"], content)
+
+		// The credentials of the registry are not written down
+		validators, err := os.ReadFile(filepath.Join(registryDir, installer.ValidatorsFileName))
+		assert.Nil(err)
+		assert.NotContains(string(validators), "secret")
+
+		assert.Nil(installer.InitializeCache())
+		assert.Len(installer.Installation.PublicCacheIndexXML.FindPdscTags(xml.PdscTag{Vendor: "Acme", Name: "ThePack"}), 1)
+
+		_, err = installer.RemovePack("Acme.ThePack", true, !Force, !Cascade, !WithDeps, true)
+		assert.Nil(err)
+		assert.NoFileExists(pdscFilePath)
+		assert.Empty(installer.Installation.PublicCacheIndexXML.FindPdscTags(xml.PdscTag{Vendor: "Acme", Name: "ThePack"}))
+	})
+}
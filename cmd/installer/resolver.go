@@ -0,0 +1,401 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	log "github.com/sirupsen/logrus"
+)
+
+// packConstraint is a requirement put on a pack somewhere in the requirements graph
+type packConstraint struct {
+	// requirement is in [Name, Vendor, Version] format, as returned by xml.PdscXML.Dependencies()
+	requirement []string
+
+	// chain holds the keys of the packs leading from the pack being installed to the requirer
+	chain []string
+}
+
+// resolvedPack is a pack of the requirements graph, with the single version
+// picked to satisfy all the constraints put on it
+type resolvedPack struct {
+	Vendor string
+	Name   string
+
+	// Version is the one picked for the pack, empty if no version of it is known
+	Version string
+
+	// installed tells whether Version is already installed
+	installed bool
+
+	// constraints lists the requirements on this pack across the whole graph
+	constraints []packConstraint
+
+	// requires holds the keys of the packs listed in its <requirements>
+	requires []string
+
+	// chain holds the keys of the packs leading from the pack being installed to this one
+	chain []string
+
+	// versions lists the known releases of the pack, most recent first
+	versions []string
+
+	// installedVersions lists the versions of the pack in the pack root, most recent first
+	installedVersions []string
+}
+
+// resolution is the outcome of resolving the requirements of a pack being installed
+type resolution struct {
+	root *resolvedPack
+
+	// packs maps the lowercase "vendor.name" keys to the packs of the graph, root included
+	packs map[string]*resolvedPack
+
+	// order lists the packs that need installing, each one after the packs it requires
+	order []*resolvedPack
+
+	// pdscFiles maps the keys of the packs to the PDSC file their requirements were read from, nil if unknown
+	pdscFiles map[string]*xml.PdscXML
+}
+
+// resolverKey returns the key identifying a pack in the requirements graph
+func resolverKey(vendor, name string) string {
+	return strings.ToLower(vendor + "." + name)
+}
+
+// ID formats the pack as Vendor::Name@x.y.z, or Vendor::Name if no version got picked
+func (r *resolvedPack) ID() string {
+	if r.Version == "" {
+		return r.Vendor + "::" + r.Name
+	}
+	return r.Vendor + "::" + r.Name + "@" + r.Version
+}
+
+// path returns the pack path to install the picked version with
+func (r *resolvedPack) path() string {
+	if r.Version == "" {
+		// Let the installation report the pack as not found
+		return r.Vendor + "." + r.Name + "." + r.constraints[0].requirement[2]
+	}
+	return r.Vendor + "." + r.Name + "." + r.Version
+}
+
+// satisfies tells whether version fulfills all the constraints on the pack
+func (r *resolvedPack) satisfies(version string) bool {
+	for _, constraint := range r.constraints {
+		if !requirementSatisfied(version, constraint.requirement) {
+			return false
+		}
+	}
+	return true
+}
+
+// pickVersion selects the version to use for the pack. An installed version satisfying
+// all constraints wins over newer releases, so that nothing is installed needlessly.
+// It returns false if versions of the pack are known but none satisfies all constraints.
+func (r *resolvedPack) pickVersion() bool {
+	for _, version := range r.installedVersions {
+		if r.satisfies(version) {
+			r.Version = version
+			r.installed = true
+			return true
+		}
+	}
+	for _, version := range r.versions {
+		if r.satisfies(version) {
+			r.Version = version
+			return true
+		}
+	}
+	return len(r.versions) == 0 && len(r.installedVersions) == 0
+}
+
+// sortVersions strips the metadata of versions, drops the duplicates and sorts them, most recent first
+func sortVersions(versions []string) []string {
+	seen := map[string]bool{}
+	sorted := []string{}
+	for _, version := range versions {
+		version = utils.SemverStripMeta(version)
+		if version == "" || seen[version] {
+			continue
+		}
+		seen[version] = true
+		sorted = append(sorted, version)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return utils.SemverCompare(sorted[i], sorted[j]) > 0
+	})
+	return sorted
+}
+
+// loadResolvedPack gathers the known and installed versions of Vendor.Name,
+// along with its PDSC file. Public PDSC files missing from ".Web/" get downloaded if download is set.
+func loadResolvedPack(vendor, name string, download, insecureSkipVerify bool, timeout int) (*resolvedPack, *xml.PdscXML) {
+	node := &resolvedPack{Vendor: vendor, Name: name}
+
+	pack := &PackType{}
+	pack.Vendor = vendor
+	pack.Name = name
+	pack.versionModifier = utils.AnyVersion
+
+	if registry, tags := Installation.findRegistry(vendor, name); len(tags) > 0 {
+		pack.IsPublic = true
+		pack.registry = registry
+		if !download {
+			log.Debugf("Not downloading the pdsc file of %s", pack.PackID())
+		} else if err := Installation.downloadPdscFile(registry, tags[0], true, false, false, insecureSkipVerify, timeout); err != nil {
+			log.Debugf("Could not retrieve the pdsc file of %s: %v", pack.PackID(), err)
+		}
+	}
+
+	_, installedVersions := Installation.PackIsInstalled(pack, false)
+	node.installedVersions = sortVersions(installedVersions)
+
+	pdscFilePath := findPackPdsc(pack)
+	if pdscFilePath == "" && len(node.installedVersions) > 0 {
+		pdscFilePath = filepath.Join(Installation.PackRoot, vendor, name, node.installedVersions[0], pack.PdscFileName())
+	}
+	if pdscFilePath == "" || !utils.FileExists(pdscFilePath) {
+		log.Debugf("No pdsc file found for %s, its requirements are unknown", pack.PackID())
+		return node, nil
+	}
+
+	pdscXML := xml.NewPdscXML(pdscFilePath)
+	if err := pdscXML.Read(); err != nil {
+		log.Debugf("Ignoring the requirements of %q: %v", pdscFilePath, err)
+		return node, nil
+	}
+	node.versions = sortVersions(pdscXML.AllReleases())
+	return node, pdscXML
+}
+
+// maxResolutionPasses bounds how many times resolveRequirements walks the graph again
+// with the requirements of the versions it picked
+const maxResolutionPasses = 8
+
+// versionPdsc reads the PDSC file of one version of Vendor.Name, either installed
+// or cached in ".Download/", or returns nil if it is not available locally
+func versionPdsc(vendor, name, version string) *xml.PdscXML {
+	for _, candidate := range []string{
+		filepath.Join(Installation.PackRoot, vendor, name, version, vendor+"."+name+utils.PdscExtension),
+		filepath.Join(Installation.DownloadDir, vendor+"."+name+"."+version+utils.PdscExtension),
+	} {
+		if !utils.FileExists(candidate) {
+			continue
+		}
+		pdscXML := xml.NewPdscXML(candidate)
+		if err := pdscXML.Read(); err != nil {
+			log.Debugf("Ignoring %q: %v", candidate, err)
+			continue
+		}
+		return pdscXML
+	}
+	return nil
+}
+
+// resolveRequirements walks the <requirements><packages> of the pack being installed,
+// whose PDSC file is rootPdsc, and of every pack they lead to. It then picks for each
+// required pack one version satisfying all the ranges put on it across the whole graph.
+//
+// The requirements of a required pack are first read from its latest PDSC file. When an
+// older version gets picked, e.g. to satisfy a range, the graph is walked again with the
+// requirements of that version, read from its installed or cached PDSC file. If neither
+// is available locally, the requirements of the latest release are used.
+//
+// If no version of a pack satisfies all of them, the conflicting requirements are logged
+// along with the chain of requirers leading to each one, and ErrDependencyConflict is returned.
+//
+// Parameters:
+//   - root: The pack being installed.
+//   - rootPdsc: The PDSC file of the pack being installed.
+//   - insecureSkipVerify: A boolean indicating whether to skip TLS certificate verification.
+//   - timeout: The timeout in seconds for downloading PDSC files.
+//
+// Returns:
+//   - *resolution: The resolved graph, with the packs to install in topological order.
+//   - error: An error if the requirements conflict, or nil if successful.
+func resolveRequirements(root *PackType, rootPdsc *xml.PdscXML, insecureSkipVerify bool, timeout int) (*resolution, error) {
+	loaded := map[string]*loadedPack{}
+	picked := map[string]*xml.PdscXML{}
+	for pass := 1; ; pass++ {
+		res, err := walkRequirements(root, rootPdsc, loaded, picked, insecureSkipVerify, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		changed := false
+		for key, node := range res.packs {
+			pdscXML := res.pdscFiles[key]
+			if node == res.root || node.Version == "" || pdscXML == nil || utils.SemverCompare(pdscXML.LatestVersion(), node.Version) == 0 {
+				continue
+			}
+			pickedPdsc := versionPdsc(node.Vendor, node.Name, node.Version)
+			if pickedPdsc != nil && pickedPdsc.FileName != pdscXML.FileName {
+				log.Debugf("Reading the requirements of %s from %q", node.ID(), pickedPdsc.FileName)
+				picked[key] = pickedPdsc
+				changed = true
+			} else if pickedPdsc == nil && picked[key] != nil {
+				// Another version got picked since, back to the latest requirements
+				delete(picked, key)
+				changed = true
+			}
+		}
+		if !changed {
+			return res, nil
+		}
+		if pass == maxResolutionPasses {
+			log.Debugf("Requirements still changing after %d passes, keeping the last resolution", pass)
+			return res, nil
+		}
+	}
+}
+
+// loadedPack is what loadResolvedPack found out about a pack, kept across resolution passes
+type loadedPack struct {
+	node *resolvedPack
+	pdsc *xml.PdscXML
+}
+
+// walkRequirements resolves the requirements graph once, see resolveRequirements. The packs
+// loaded get added to loaded, and the PDSC files in picked replace the latest ones.
+func walkRequirements(root *PackType, rootPdsc *xml.PdscXML, loaded map[string]*loadedPack, picked map[string]*xml.PdscXML, insecureSkipVerify bool, timeout int) (*resolution, error) {
+	rootKey := resolverKey(root.Vendor, root.Name)
+	res := &resolution{
+		root: &resolvedPack{
+			Vendor:            root.Vendor,
+			Name:              root.Name,
+			Version:           root.GetVersionNoMeta(),
+			installed:         true,
+			versions:          []string{root.GetVersionNoMeta()},
+			installedVersions: []string{root.GetVersionNoMeta()},
+		},
+		packs:     map[string]*resolvedPack{},
+		pdscFiles: map[string]*xml.PdscXML{rootKey: rootPdsc},
+	}
+	res.packs[rootKey] = res.root
+
+	// Breadth first, so that each pack is reached through its shortest chain of requirers
+	discovered := []string{}
+	queue := []string{rootKey}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		requirer := res.packs[key]
+		chain := append(append([]string{}, requirer.chain...), key)
+
+		if res.pdscFiles[key] == nil {
+			continue
+		}
+		for _, dependency := range res.pdscFiles[key].Dependencies() {
+			depKey := resolverKey(dependency[1], dependency[0])
+			node, found := res.packs[depKey]
+			if !found {
+				if loaded[depKey] == nil {
+					loadedNode, loadedPdsc := loadResolvedPack(dependency[1], dependency[0], true, insecureSkipVerify, timeout)
+					loaded[depKey] = &loadedPack{node: loadedNode, pdsc: loadedPdsc}
+				}
+				node = &resolvedPack{
+					Vendor:            loaded[depKey].node.Vendor,
+					Name:              loaded[depKey].node.Name,
+					versions:          loaded[depKey].node.versions,
+					installedVersions: loaded[depKey].node.installedVersions,
+					chain:             chain,
+				}
+				res.packs[depKey] = node
+				res.pdscFiles[depKey] = loaded[depKey].pdsc
+				if pickedPdsc, found := picked[depKey]; found {
+					res.pdscFiles[depKey] = pickedPdsc
+				}
+				discovered = append(discovered, depKey)
+				queue = append(queue, depKey)
+			}
+			node.constraints = append(node.constraints, packConstraint{requirement: dependency, chain: chain})
+			requirer.requires = append(requirer.requires, depKey)
+		}
+	}
+
+	conflicts := []string{}
+	for _, key := range append([]string{rootKey}, discovered...) {
+		node := res.packs[key]
+		if key == rootKey {
+			// The pack being installed is required back by one of its requirements
+			if node.satisfies(node.Version) {
+				continue
+			}
+		} else if node.pickVersion() {
+			log.Debugf("Resolved %s to %s", node.Vendor+"::"+node.Name, node.Version)
+			continue
+		}
+		conflicts = append(conflicts, node.Vendor+"::"+node.Name)
+		log.Errorf("No version of %s satisfies all of its requirements:", node.Vendor+"::"+node.Name)
+		for _, constraint := range node.constraints {
+			log.Errorf("  %s required by %s", utils.FormatPackVersion(constraint.requirement), res.formatChain(constraint.chain))
+		}
+		if key != rootKey {
+			log.Errorf("  available versions: %s", utils.VersionList(sortVersions(append(node.installedVersions, node.versions...))))
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%w: %s", errs.ErrDependencyConflict, strings.Join(conflicts, ", "))
+	}
+
+	// Depth first, so that every pack comes after the packs it requires
+	visited := map[string]bool{}
+	var visit func(key string)
+	visit = func(key string) {
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		node := res.packs[key]
+		for _, depKey := range node.requires {
+			visit(depKey)
+		}
+		if key != rootKey && !node.installed {
+			res.order = append(res.order, node)
+		}
+	}
+	visit(rootKey)
+
+	return res, nil
+}
+
+// formatChain formats a chain of requirers as "Vendor::A@1.0.0 -> Vendor::B@2.0.0"
+func (r *resolution) formatChain(chain []string) string {
+	ids := []string{}
+	for _, key := range chain {
+		ids = append(ids, r.packs[key].ID())
+	}
+	return strings.Join(ids, " -> ")
+}
+
+// installRequirements installs the packs the resolution found missing, in topological order.
+// They are installed as dependencies, without resolving their own requirements again.
+func (r *resolution) installRequirements(checkEula, extractEula, insecureSkipVerify bool, timeout int) error {
+	if len(r.order) == 0 {
+		log.Debugf("pack has all required dependencies installed (%d packs)", len(r.packs)-1)
+		return nil
+	}
+
+	ids := []string{}
+	for _, node := range r.order {
+		ids = append(ids, node.ID())
+	}
+	log.Infof("Package requirements not satisfied - installing %s", strings.Join(ids, " "))
+
+	for _, node := range r.order {
+		log.Debugf("installing %s, required by %s", node.ID(), r.formatChain(node.chain))
+		if err := AddPack("$"+node.path(), checkEula, extractEula, false, true, insecureSkipVerify, false, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
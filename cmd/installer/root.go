@@ -13,6 +13,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -32,6 +33,7 @@ import (
 
 const PublicIndexName = "index.pidx"
 const PublicCacheIndex = "cache.pidx"
+const DependenciesIndexName = "dependencies.pidx"
 const KeilDefaultPackRoot = "https://www.keil.com/pack/"
 const ConnectionTryURL = "https://www.keil.com/pack/keil.vidx"
 
@@ -103,11 +105,19 @@ func GetDefaultCmsisPackRoot() string {
 //
 // Behavior:
 //   - Handles global pack updates by checking and updating the public index if necessary.
-//   - Supports reinstallation by temporarily backing up and restoring existing installations.
+//   - Supports reinstallation by backing up the existing installation until the transaction ends.
 //   - Fetches the pack from its source and installs it, ensuring dependencies are satisfied unless skipped.
 //   - Provides detailed logging for each step of the installation process.
-//   - Ensures proper cleanup and rollback in case of errors during installation.
+//   - Runs in the transaction in progress, or in one of its own, so that a failure
+//     rolls back the pack, its requirements and the index files.
 func AddPack(packPath string, checkEula, extractEula, forceReinstall, noRequirements, insecureSkipVerify, testing bool, timeout int) error {
+	return inTransaction("add "+strings.TrimPrefix(packPath, "$"), func() error {
+		return addPack(packPath, checkEula, extractEula, forceReinstall, noRequirements, insecureSkipVerify, testing, timeout)
+	})
+}
+
+// addPack installs a pack, see AddPack
+func addPack(packPath string, checkEula, extractEula, forceReinstall, noRequirements, insecureSkipVerify, testing bool, timeout int) error {
 
 	isDep := false
 	// tag dependency packs with $ for correct logging output
@@ -139,29 +149,15 @@ func AddPack(packPath string, checkEula, extractEula, forceReinstall, noRequirem
 		return err
 	}
 	if pack.isPackID {
-		if pack.path, err = FindPackURL(pack, insecureSkipVerify, testing); err != nil {
+		if pack.path, err = findPackURL(pack, insecureSkipVerify, testing); err != nil {
 			return err
 		}
 	}
 
-	dropPreInstalled := false
-	fullPackPath := ""
-	backupPackPath := ""
 	if !extractEula && pack.isInstalled {
 		if forceReinstall {
-
-			log.Debugf("Making temporary backup of pack %q", packPath)
-
-			// Get target pack's full path and move it to a temporary "_tmp" directory
-			fullPackPath = filepath.Join(Installation.PackRoot, pack.Vendor, pack.Name, pack.GetVersionNoMeta())
-			backupPackPath = fullPackPath + "_tmp"
-
-			if err := utils.MoveFile(fullPackPath, backupPackPath); err != nil {
-				return err
-			}
-
-			log.Debugf("Moved pack to temporary path %q", backupPackPath)
-			dropPreInstalled = true
+			// The installed pack gets backed up by the transaction when reinstalling it
+			log.Debugf("Reinstalling pack %q", packPath)
 		} else {
 			switch pack.versionModifier {
 			case utils.AnyVersion:
@@ -184,6 +180,20 @@ func AddPack(packPath string, checkEula, extractEula, forceReinstall, noRequirem
 				log.Errorf("Pack %q is already installed here: %q, use the --force-reinstall (-F) flag to force installation",
 					packPath, filepath.Join(Installation.PackRoot, pack.Vendor, pack.Name, pack.GetVersionNoMeta()))
 			}
+			if !isDep {
+				// Explicitly adding a pack pulled in as a dependency keeps it from being autoremoved
+				version := pack.GetVersionNoMeta()
+				if pack.versionModifier == utils.AnyVersion {
+					version = ""
+				}
+				promoted, err := Installation.removeDependencyTag(pack.Vendor, pack.Name, version)
+				if err != nil {
+					return err
+				}
+				if promoted {
+					log.Infof("Pack %q is now marked as explicitly installed", packPath)
+				}
+			}
 			return nil
 		}
 	}
@@ -192,10 +202,24 @@ func AddPack(packPath string, checkEula, extractEula, forceReinstall, noRequirem
 		return err
 	}
 
+	// Resolve the requirements before installing anything, so that conflicts between them
+	// leave the pack root untouched. Packs whose PDSC file cannot be read up front
+	// get their requirements resolved once installed.
+	var requirements *resolution
+	if !noRequirements {
+		if pdscXML, err := readPackFilePdsc(pack.path, pack.PdscFileName()); err == nil {
+			if requirements, err = resolveRequirements(pack, pdscXML, insecureSkipVerify, timeout); err != nil {
+				return err
+			}
+		} else {
+			log.Debugf("Could not read the pdsc file of %q before installing it: %v", pack.path, err)
+		}
+	}
+
 	// Since we only get the target version here, can only
 	// print the message now for dependencies
 	if isDep {
-		log.Infof("Adding pack %s", pack.VName()+"."+pack.targetVersion)
+		log.Infof("Adding pack %s", pack.VName()+"."+pack.GetVersionNoMeta())
 	}
 	// Tells the UI to return right away with the [E]xtract option selected
 	ui.Extract = extractEula
@@ -209,65 +233,27 @@ func AddPack(packPath string, checkEula, extractEula, forceReinstall, noRequirem
 		if err == errs.ErrEula {
 			return nil
 		}
-		if dropPreInstalled {
-			log.Error("Error installing pack, reverting temporary pack to original state")
-			// Make sure the original directory doesn't exist to avoid moving errors
-			if err := os.RemoveAll(fullPackPath); err != nil {
-				return err
-			}
-			if err := utils.MoveFile(backupPackPath, fullPackPath); err != nil {
-				return err
-			}
-		}
 		return err
 	}
 
-	// Remove the original "temporary" pack
-	// Manual removal via os.RemoveAll as "_tmp" is an invalid packPath for RemovePack
-	if dropPreInstalled {
-		utils.UnsetReadOnlyR(backupPackPath)
-		if err := os.RemoveAll(backupPackPath); err != nil {
-			return err
-		}
-		log.Debugf("Successfully deleted temporary pack %q", backupPackPath)
+	if isDep {
+		err = Installation.addDependencyTag(pack.Vendor, pack.Name, pack.GetVersionNoMeta())
+	} else {
+		_, err = Installation.removeDependencyTag(pack.Vendor, pack.Name, pack.GetVersionNoMeta())
+	}
+	if err != nil {
+		return err
 	}
 
 	if !noRequirements {
 		log.Debug("installing package requirements")
-		err := pack.loadDependencies(true)
-		if err != nil {
-			return err
-		}
-		if !pack.RequirementsSatisfied() {
-			// Print all dependencies info on one message
-			msg := ""
-			for _, p := range pack.Requirements.packages {
-				if !p.installed {
-					msg += utils.FormatPackVersion(p.info) + " "
-				}
-			}
-			if msg != "" {
-				log.Infof("Package requirements not satisfied - installing %s", msg)
-			}
-			for _, req := range pack.Requirements.packages {
-				// Recursively install dependencies
-				path := req.info[1] + "." + req.info[0] + "." + req.info[2]
-				pack, err := preparePack(path, false, false, false, true)
-				if err != nil {
-					return err
-				}
-				if !pack.isInstalled {
-					log.Debug("pack has dependencies, installing")
-					err := AddPack("$"+path, checkEula, extractEula, forceReinstall, false, insecureSkipVerify, false, timeout)
-					if err != nil {
-						return err
-					}
-				} else {
-					log.Debugf("required pack %s already installed - skipping", path)
-				}
+		if requirements == nil {
+			if requirements, err = resolveRequirements(pack, pack.Pdsc, insecureSkipVerify, timeout); err != nil {
+				return err
 			}
-		} else {
-			log.Debugf("pack has all required dependencies installed (%d packs)", len(pack.Requirements.packages))
+		}
+		if err := requirements.installRequirements(checkEula, extractEula, insecureSkipVerify, timeout); err != nil {
+			return err
 		}
 	} else {
 		log.Debug("skipping requirements checking and installation")
@@ -280,14 +266,39 @@ func AddPack(packPath string, checkEula, extractEula, forceReinstall, noRequirem
 // If the pack is installed, it will be uninstalled. If the purge option is enabled,
 // the pack will be completely removed from the system.
 //
+// Before removing anything, the requirements of all installed packs are checked.
+// If the removal would leave any of them unsatisfied, the pack is not removed,
+// unless force is set, or cascade is set, in which case those dependents
+// (and the packs depending on them) are removed first.
+//
+// If withDeps is set, the packs that got installed as dependencies of the removed pack
+// are also removed, as long as no explicitly installed pack still requires them.
+//
 // Parameters:
 //   - packPath: The path to the pack to be removed.
 //   - purge: A boolean indicating whether to completely remove the pack.
-//   - timeout: An integer specifying the timeout duration for the operation.
+//   - force: A boolean indicating whether to remove the pack even if other installed packs require it.
+//   - cascade: A boolean indicating whether to also remove the installed packs requiring it.
+//   - withDeps: A boolean indicating whether to also remove its orphaned dependency packs.
+//   - testing: A boolean indicating whether the function is being run in a testing environment.
 //
 // Returns:
 //   - error: An error if the removal process fails, or nil if successful.
-func RemovePack(packPath string, purge, testing bool) (bool, error) {
+//
+// The removal runs in the transaction in progress, or in one of its own, so that a failure
+// restores the packs removed so far.
+func RemovePack(packPath string, purge, force, cascade, withDeps, testing bool) (bool, error) {
+	var ok bool
+	err := inTransaction("rm "+packPath, func() error {
+		var err error
+		ok, err = removePack(packPath, purge, force, cascade, withDeps, testing)
+		return err
+	})
+	return ok, err
+}
+
+// removePack removes a pack, see RemovePack
+func removePack(packPath string, purge, force, cascade, withDeps, testing bool) (bool, error) {
 	log.Debugf("Removing pack \"%v\"", packPath)
 
 	if err := ReadIndexFiles(); err != nil {
@@ -297,11 +308,29 @@ func RemovePack(packPath string, purge, testing bool) (bool, error) {
 	// TODO: by default, remove latest version first
 	// if no version is given
 
-	pack, err := preparePack(packPath, true, false, false, true)
+	removal, err := resolveRemoval(packPath, purge, force, cascade, withDeps)
 	if err != nil {
 		return false, err
 	}
 
+	for _, packID := range removal.dependents {
+		log.Infof("Removing %s, which depends on %s", packID, removal.pack.PackID())
+		if _, err := RemovePack(packID, purge, true, false, false, testing); err != nil {
+			return false, err
+		}
+	}
+
+	ok, err := uninstallPack(removal.pack, packPath, purge)
+	if err != nil || !withDeps {
+		return ok, err
+	}
+	return ok, removeOrphanedDependencies(removal.dependencies, purge, testing)
+}
+
+// uninstallPack removes the installed versions of a pack, or its reference in
+// local_repository.pidx, and purges its cached files if requested
+func uninstallPack(pack *PackType, packPath string, purge bool) (bool, error) {
+	var err error
 	removeInstalled := false
 
 	if pack.isInstalled {
@@ -311,6 +340,9 @@ func RemovePack(packPath string, purge, testing bool) (bool, error) {
 		if err = pack.uninstall(Installation); err != nil {
 			return false, err
 		}
+		if _, err = Installation.removeDependencyTag(pack.Vendor, pack.Name, pack.GetVersionNoMeta()); err != nil {
+			return false, err
+		}
 
 		if purge {
 			ok := false
@@ -342,15 +374,121 @@ func RemovePack(packPath string, purge, testing bool) (bool, error) {
 
 	if purge && !removeInstalled {
 		pack.Unlock()
-		ok, err := pack.purge()
-		return ok, err
+		return pack.purge()
+	}
+	return false, nil
+}
+
+// dependentsTree returns the keys ("Vendor.Name.x.y.z") of the dependents of pack and of the
+// packs depending on them, deepest dependents first. Each pack shows up once, even with cycles.
+func dependentsTree(requirers []*PackType, pack *PackType, dependents []dependentPack) []string {
+	visited := map[string]bool{strings.ToLower(pack.PackIDWithVersion()): true}
+	tree := []string{}
+	var collect func(dependents []dependentPack)
+	collect = func(dependents []dependentPack) {
+		for _, dependent := range dependents {
+			key := strings.ToLower(dependent.Key())
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			collect(brokenDependents(requirers, dependent.Vendor, dependent.Name, []string{dependent.Version}))
+			tree = append(tree, dependent.Key())
+		}
+	}
+	collect(dependents)
+	return tree
+}
+
+// versionsToRemove returns the installed versions of the pack that RemovePack uninstalls
+func (p *PackType) versionsToRemove() []string {
+	if p.isInstalled && p.GetVersionNoMeta() != "" {
+		return []string{p.GetVersionNoMeta()}
+	}
+	return p.installedVersions
+}
+
+// packRemoval is what removing a pack takes, worked out by resolveRemoval
+// for both RemovePack and PlanRemovePack
+type packRemoval struct {
+	// pack is the pack being removed
+	pack *PackType
+
+	// versions are the installed versions of the pack being uninstalled
+	versions []string
+
+	// dependents are the keys ("Vendor.Name.x.y.z") of the installed packs requiring
+	// the pack, removed beforehand with cascade, deepest dependents first
+	dependents []string
+
+	// dependencies are the lowercase keys ("vendor.name.x.y.z") of the installed packs that
+	// the removed versions require, directly or transitively. Only worked out with withDeps.
+	dependencies map[string]bool
+}
+
+// resolveRemoval works out what removing the pack at packPath takes. Unless force is set,
+// removing the pack must not break any installed pack: without cascade, it fails listing
+// the dependents that would be left with unsatisfied requirements, with cascade, they get
+// removed as well.
+func resolveRemoval(packPath string, purge, force, cascade, withDeps bool) (*packRemoval, error) {
+	pack, err := preparePack(packPath, true, false, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	removal := &packRemoval{pack: pack, versions: pack.versionsToRemove()}
+	if len(removal.versions) == 0 {
+		if !purge {
+			log.Errorf("Pack \"%v\" is not installed", packPath)
+			return nil, errs.ErrPackNotInstalled
+		}
+		return removal, nil
+	}
+
+	if !force {
+		requirers, err := loadRequirers()
+		if err != nil {
+			return nil, err
+		}
+		dependents := brokenDependents(requirers, pack.Vendor, pack.Name, removal.versions)
+		if len(dependents) > 0 && !cascade {
+			log.Errorf("Cannot remove %s, it is required by:", pack.PackID())
+			for _, dependent := range dependents {
+				log.Errorf("  %s (requires %s)", dependent.YamlPackID(), utils.FormatPackVersion(dependent.requirement))
+			}
+			return nil, errs.ErrPackHasDependents
+		}
+		removal.dependents = dependentsTree(requirers, pack, dependents)
+	}
+
+	if withDeps {
+		// Collected before the pack is gone, for the ones left orphaned to be removed afterwards
+		packs, err := loadInstalledPacks()
+		if err != nil {
+			return nil, err
+		}
+		roots := []*PackType{}
+		for _, installed := range packs {
+			if strings.EqualFold(installed.Vendor, pack.Vendor) && strings.EqualFold(installed.Name, pack.Name) &&
+				slices.Contains(removal.versions, installed.Version) {
+				roots = append(roots, installed)
+			}
+		}
+		removal.dependencies = requiredPacks(packs, roots)
 	}
+	return removal, nil
+}
 
-	if !removeInstalled {
-		log.Errorf("Pack \"%v\" is not installed", packPath)
-		return false, errs.ErrPackNotInstalled
+// removedKeys returns the lowercase keys ("vendor.name.x.y.z") of the packs the removal uninstalls
+func (r *packRemoval) removedKeys() map[string]bool {
+	removed := map[string]bool{}
+	for _, version := range r.versions {
+		removed[strings.ToLower(r.pack.PackID()+"."+version)] = true
 	}
-	return false, err
+	for _, key := range r.dependents {
+		removed[strings.ToLower(key)] = true
+	}
+	return removed
 }
 
 // AddPdsc adds a PDSC (Pack Description) file to the installation.
@@ -364,6 +502,13 @@ func RemovePack(packPath string, purge, testing bool) (bool, error) {
 // Returns:
 //   - error: An error if any step fails, otherwise nil.
 func AddPdsc(pdscPath string) error {
+	return inTransaction("add "+pdscPath, func() error {
+		return addPdsc(pdscPath)
+	})
+}
+
+// addPdsc adds a PDSC file, see AddPdsc
+func addPdsc(pdscPath string) error {
 	log.Infof("Adding pdsc \"%v\"", pdscPath)
 
 	if err := ReadIndexFiles(); err != nil {
@@ -406,6 +551,13 @@ func AddPdsc(pdscPath string) error {
 //  5. Writes the updated local Pidx (Pack Index) to disk.
 //  6. Touches the Pack Index to update its timestamp.
 func RemovePdsc(pdscPath string) error {
+	return inTransaction("rm "+pdscPath, func() error {
+		return removePdsc(pdscPath)
+	})
+}
+
+// removePdsc removes a PDSC file, see RemovePdsc
+func removePdsc(pdscPath string) error {
 	log.Debugf("Removing pdsc \"%v\"", pdscPath)
 
 	pdsc, err := preparePdsc(pdscPath)
@@ -444,7 +596,7 @@ func RemovePdsc(pdscPath string) error {
 //
 //	massDownloadPdscFiles(pdscTag, true, 30)
 func massDownloadPdscFiles(pdscTag xml.PdscTag, skipInstalledPdscFiles, showInfo, insecureSkipVerify bool, timeout int, errTags *lockedSlice) {
-	if err := Installation.downloadPdscFile(pdscTag, skipInstalledPdscFiles, showInfo, false, insecureSkipVerify, timeout); err != nil {
+	if err := Installation.downloadPdscFile(Installation.publicRegistry(), pdscTag, skipInstalledPdscFiles, showInfo, false, insecureSkipVerify, timeout); err != nil {
 		errTags.lock.Lock()
 		errTags.slice = append(errTags.slice, pdscTag)
 		errTags.lock.Unlock()
@@ -462,11 +614,22 @@ func massDownloadPdscFiles(pdscTag xml.PdscTag, skipInstalledPdscFiles, showInfo
 //   - subCall: Indicates if this is a recursive call (used internally).
 //   - insecureSkipVerify: If true, skips TLS certificate verification during fetch.
 //   - testing: If true, skips public index updates (used for testing).
+//   - concurrency: Number of packs fetched at a time when updating all packs, see PrefetchPacks.
 //   - timeout: HTTP timeout in seconds for pack download operations.
 //
 // Returns:
 //   - error: An error if the update process fails, otherwise nil.
-func UpdatePack(packPath string, checkEula, noRequirements, subCall, insecureSkipVerify, testing bool, timeout int) error {
+//
+// The update runs in the transaction in progress, or in one of its own. When updating all
+// packs, the first failure stops the update and rolls back the packs updated so far.
+func UpdatePack(packPath string, checkEula, noRequirements, subCall, insecureSkipVerify, testing bool, concurrency, timeout int) error {
+	return inTransaction("update "+packPath, func() error {
+		return updatePack(packPath, checkEula, noRequirements, subCall, insecureSkipVerify, testing, concurrency, timeout)
+	})
+}
+
+// updatePack updates a pack, or all installed packs, see UpdatePack
+func updatePack(packPath string, checkEula, noRequirements, subCall, insecureSkipVerify, testing bool, concurrency, timeout int) error {
 
 	if !subCall {
 		if packPath == "" {
@@ -492,10 +655,15 @@ func UpdatePack(packPath string, checkEula, noRequirements, subCall, insecureSki
 		if err != nil {
 			return err
 		}
+		packIDs := make([]string, 0, len(installedPacks))
 		for _, installedPack := range installedPacks {
-			err = UpdatePack(installedPack.VName(), checkEula, noRequirements, true, insecureSkipVerify, testing, timeout)
+			packIDs = append(packIDs, installedPack.VName())
+		}
+		prefetchPacks(packIDs, true, false, insecureSkipVerify, testing, concurrency, timeout)
+		for _, packID := range packIDs {
+			err = updatePack(packID, checkEula, noRequirements, true, insecureSkipVerify, testing, concurrency, timeout)
 			if err != nil {
-				log.Error(err)
+				return err
 			}
 		}
 		return nil
@@ -518,7 +686,7 @@ func UpdatePack(packPath string, checkEula, noRequirements, subCall, insecureSki
 	log.Infof("Updating pack %q", packPath)
 
 	if pack.isPackID {
-		if pack.path, err = FindPackURL(pack, insecureSkipVerify, testing); err != nil {
+		if pack.path, err = findPackURL(pack, insecureSkipVerify, testing); err != nil {
 			return err
 		}
 	}
@@ -527,6 +695,24 @@ func UpdatePack(packPath string, checkEula, noRequirements, subCall, insecureSki
 		return err
 	}
 
+	// Resolve the requirements before installing anything, see AddPack
+	var requirements *resolution
+	if !noRequirements {
+		if pdscXML, err := readPackFilePdsc(pack.path, pack.PdscFileName()); err == nil {
+			if requirements, err = resolveRequirements(pack, pdscXML, insecureSkipVerify, timeout); err != nil {
+				return err
+			}
+		} else {
+			log.Debugf("Could not read the pdsc file of %q before installing it: %v", pack.path, err)
+		}
+	}
+
+	// The new version of a pack pulled in as a dependency is a dependency as well
+	isDep, err := Installation.installedAsDependency(pack.Vendor, pack.Name)
+	if err != nil {
+		return err
+	}
+
 	// Unlock the pack (to enable reinstalling) and lock it afterwards
 	pack.Unlock()
 	defer pack.Lock()
@@ -539,42 +725,21 @@ func UpdatePack(packPath string, checkEula, noRequirements, subCall, insecureSki
 		return err
 	}
 
-	if !noRequirements {
-		log.Debug("installing package requirements")
-		err := pack.loadDependencies(true)
-		if err != nil {
+	if isDep {
+		if err := Installation.addDependencyTag(pack.Vendor, pack.Name, pack.GetVersionNoMeta()); err != nil {
 			return err
 		}
-		if !pack.RequirementsSatisfied() {
-			// Print all dependencies info on one message
-			msg := ""
-			for _, p := range pack.Requirements.packages {
-				if !p.installed {
-					msg += utils.FormatPackVersion(p.info) + " "
-				}
-			}
-			if msg != "" {
-				log.Infof("Package requirements not satisfied - installing %s", msg)
-			}
-			for _, req := range pack.Requirements.packages {
-				// Recursively install dependencies
-				path := req.info[1] + "." + req.info[0] + "." + req.info[2]
-				pack, err := preparePack(path, false, false, false, true)
-				if err != nil {
-					return err
-				}
-				if !pack.isInstalled {
-					log.Debug("pack has dependencies, installing")
-					err := AddPack("$"+path, checkEula, false, false, false, insecureSkipVerify, false, timeout)
-					if err != nil {
-						return err
-					}
-				} else {
-					log.Debugf("required pack %s already installed - skipping", path)
-				}
+	}
+
+	if !noRequirements {
+		log.Debug("installing package requirements")
+		if requirements == nil {
+			if requirements, err = resolveRequirements(pack, pack.Pdsc, insecureSkipVerify, timeout); err != nil {
+				return err
 			}
-		} else {
-			log.Debugf("pack has all required dependencies installed (%d packs)", len(pack.Requirements.packages))
+		}
+		if err := requirements.installRequirements(checkEula, false, insecureSkipVerify, timeout); err != nil {
+			return err
 		}
 	} else {
 		log.Debug("skipping requirements checking and installation")
@@ -584,10 +749,23 @@ func UpdatePack(packPath string, checkEula, noRequirements, subCall, insecureSki
 }
 
 func InitializeCache() error {
-	pattern := filepath.Join(Installation.WebDir, "*"+utils.PdscExtension)
-	matches, err := filepath.Glob(pattern)
-	if err != nil {
-		return err
+	registries := Installation.Registries
+	if len(registries) == 0 {
+		registries = []*Registry{Installation.publicRegistry()}
+	}
+
+	// The registries other than the public one keep their PDSC files in folders of their own. Going
+	// from the last registry in priority order lets the one a pack resolves from have the last word.
+	var matches []string
+	for i := len(registries) - 1; i >= 0; i-- {
+		registryMatches, err := filepath.Glob(filepath.Join(registries[i].webDir, "*"+utils.PdscExtension))
+		if err != nil {
+			return err
+		}
+		sort.Slice(registryMatches, func(i, j int) bool {
+			return strings.ToLower(registryMatches[i]) < strings.ToLower(registryMatches[j])
+		})
+		matches = append(matches, registryMatches...)
 	}
 
 	Installation.PublicCacheIndexXML.Clear()
@@ -597,9 +775,6 @@ func InitializeCache() error {
 		return nil
 	}
 
-	sort.Slice(matches, func(i, j int) bool {
-		return strings.ToLower(matches[i]) < strings.ToLower(matches[j])
-	})
 	for _, pdscFilePath := range matches {
 		packInfo, err := utils.ExtractPackInfo(strings.ReplaceAll(pdscFilePath, utils.PdscExtension, ""))
 		if err != nil {
@@ -628,6 +803,7 @@ func InitializeCache() error {
 				cacheTag.URL = releaseTag.URL[:i+1]
 			}
 		}
+		cacheTag.URL = utils.RemoveCredentials(cacheTag.URL)
 		_ = Installation.PublicCacheIndexXML.AddReplacePdsc(cacheTag)
 	}
 	return nil
@@ -936,17 +1112,32 @@ func UpdateInstalledPDSCFiles(pidxXML, cidxXML *xml.PidxXML, updatePrivatePdsc,
 // If the public index file exists, it first checks for an active internet connection and the
 // timestamp of the file. If the system is online and the file is outdated, it downloads the
 // latest version of the public index. If the system is offline, it skips the update process.
+// The index gets refreshed from where it came from, e.g. a .vidx file or a mirror, and the
+// connection is only checked against keil.com for the indexes served from there.
 //
 // If the public index file does not exist, it downloads the public index without performing
 // any checks and creates an update configuration file.
 //
 // Returns an error if any step in the update process fails.
 func UpdatePublicIndexIfOnline() error {
+	if Installation.dryRunDir != "" {
+		log.Debug("Dry run: Skipping public index update")
+		return nil
+	}
+	if utils.GetOffline() {
+		log.Debug("Offline mode: Skipping public index update")
+		return nil
+	}
+
 	// If public index already exists then first check if online, then its timestamp
 	// if we are online and it is too old then download a current version
 
+	indexURL := refreshedIndexURL()
 	if utils.FileExists(Installation.PublicIndex) {
-		err := utils.CheckConnection(ConnectionTryURL, 0)
+		var err error
+		if strings.HasPrefix(indexURL, KeilDefaultPackRoot) {
+			err = utils.CheckConnection(ConnectionTryURL, 0)
+		}
 		if err != nil && errors.Unwrap(err) != errs.ErrOffline {
 			log.Warnf("Cannot check for public index update: %v", err)
 		}
@@ -955,7 +1146,7 @@ func UpdatePublicIndexIfOnline() error {
 			err = Installation.checkUpdateCfg(&updateConf, true)
 			if err != nil {
 				UnlockPackRoot()
-				err1 := UpdatePublicIndex(ActualPublicIndex, false, false, false, true, false, false, false, 0, 0)
+				err1 := UpdatePublicIndex(indexURL, false, false, false, true, false, false, false, 0, 0)
 				if err1 != nil {
 					log.Warnf("Cannot update public index: %v", err1)
 					return nil
@@ -969,7 +1160,7 @@ func UpdatePublicIndexIfOnline() error {
 	// if public index does not or not yet exist then download without check
 	if !utils.FileExists(Installation.PublicIndex) {
 		UnlockPackRoot()
-		err1 := UpdatePublicIndex(ActualPublicIndex, false, false, false, true, false, false, false, 0, 0)
+		err1 := UpdatePublicIndex(indexURL, false, false, false, true, false, false, false, 0, 0)
 		if err1 != nil {
 			log.Warnf("Cannot update public index: %v", err1)
 			return nil
@@ -981,6 +1172,21 @@ func UpdatePublicIndexIfOnline() error {
 	return nil
 }
 
+// refreshedIndexURL returns the URL the public index was last updated from, or the default
+// public index URL for a pack root without one
+func refreshedIndexURL() string {
+	if !utils.FileExists(Installation.PublicIndex) {
+		return ActualPublicIndex
+	}
+	if Installation.PublicIndexXML.URL == "" {
+		_ = Installation.PublicIndexXML.Read()
+	}
+	if Installation.PublicIndexXML.URL == "" && readIndexSources() == nil {
+		return ActualPublicIndex
+	}
+	return publicIndexURL()
+}
+
 // UpdatePublicIndex updates the public index file from a given path or URL.
 //
 // Parameters:
@@ -998,16 +1204,28 @@ func UpdatePublicIndexIfOnline() error {
 //
 // Returns:
 //   - error: An error if the update fails, otherwise nil.
-func UpdatePublicIndex(indexPath string, sparse, downloadPdsc, downloadRemainingPdscFiles, skipDeprecatedPdscFiles, updatePrivatePdsc, showInfo, insecureSkipVerify bool, concurrency int, timeout int) error {
+func UpdatePublicIndex(indexPath string, sparse, downloadPdsc, downloadRemainingPdscFiles, skipDeprecatedPdscFiles, updatePrivatePdsc, showInfo, insecureSkipVerify bool, concurrency int, timeout int) (err error) {
+	indexPath = utils.StripCredentials(indexPath)
+
 	// For backwards compatibility, allow indexPath to be a file, but ideally it should be empty
 	if indexPath == "" {
-		indexPath = strings.TrimSuffix(Installation.PublicIndexXML.URL, "/") + "/" + PublicIndexName
+		indexPath = publicIndexURL()
+	}
+	vidxURL := ""
+	if isVidx(indexPath) {
+		vidxURL = indexPath
 	}
 
-	var err error
+	// Only refresh the index downloaded before if it changed since
+	var indexURL string
+	var indexValidators utils.Validators
 
 	if strings.HasPrefix(indexPath, "http://") || strings.HasPrefix(indexPath, "https://") {
-		if !strings.HasPrefix(indexPath, "https://127.0.0.1") {
+		if utils.GetOffline() {
+			return fmt.Errorf("%q: %w", indexPath, errs.ErrOfflineMode)
+		}
+		// Other servers, e.g. mirrors or "cpackget serve", may be reachable when keil.com is not
+		if strings.HasPrefix(indexPath, KeilDefaultPackRoot) {
 			err = utils.CheckConnection(ConnectionTryURL, 0)
 			if err != nil && errors.Unwrap(err) == errs.ErrOffline {
 				return err
@@ -1026,13 +1244,32 @@ func UpdatePublicIndex(indexPath string, sparse, downloadPdsc, downloadRemaining
 			log.Warnf("Non-HTTPS url: %q", indexPath)
 		}
 
-		indexPath, err = utils.DownloadFile(indexPath, false, true, true, insecureSkipVerify, timeout)
-		if err != nil {
+		indexURL = indexPath
+		if vidxURL != "" {
+			// The vendor indexes it lists may have changed even if it did not
+			indexPath, err = utils.DownloadFile(indexURL, false, true, true, insecureSkipVerify, timeout)
+		} else {
+			indexValidators = Installation.validatorsOf(Installation.PublicIndex, indexURL)
+			indexPath, err = utils.DownloadFileIfModified(indexURL, false, &indexValidators, true, true, insecureSkipVerify, timeout)
+		}
+		if errors.Is(err, errs.ErrNotModified) {
+			log.Infof("Public index did not change")
+			indexPath = Installation.PublicIndex
+		} else if err != nil {
 			return err
+		} else {
+			defer os.Remove(indexPath)
 		}
-		defer os.Remove(indexPath)
 	} else {
 		if indexPath != "" {
+			// A file:// URL may point at a registry directory, e.g. one written by "publish"
+			isFileURL := strings.HasPrefix(indexPath, "file://")
+			if indexPath, err = utils.FileURLToPath(indexPath); err != nil {
+				return err
+			}
+			if isFileURL && utils.DirExists(indexPath) {
+				indexPath = filepath.Join(indexPath, PublicIndexName)
+			}
 			if !utils.FileExists(indexPath) && !utils.DirExists(indexPath) {
 				return errs.ErrFileNotFoundUseInit
 			}
@@ -1046,17 +1283,44 @@ func UpdatePublicIndex(indexPath string, sparse, downloadPdsc, downloadRemaining
 		}
 	}
 
+	var sources *indexSources
+	if vidxURL != "" {
+		if !strings.HasPrefix(vidxURL, "http://") && !strings.HasPrefix(vidxURL, "https://") {
+			if absPath, err := filepath.Abs(vidxURL); err == nil {
+				vidxURL = absPath
+			}
+		}
+		indexPath, sources, err = mergeVendorIndex(indexPath, vidxURL, insecureSkipVerify, concurrency, timeout)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(indexPath)
+	}
+
 	savedIndexPath := Installation.PublicIndexXML.GetFileName()
 	Installation.PublicIndexXML.SetFileName(indexPath) // The downloaded index.pidx
 	if err := Installation.PublicIndexXML.Read(); err != nil {
 		return err
 	}
 
-	utils.UnsetReadOnly(Installation.PublicIndex)
-	if err := utils.CopyFile(indexPath, Installation.PublicIndex); err != nil {
+	if indexPath != Installation.PublicIndex {
+		utils.UnsetReadOnly(Installation.PublicIndex)
+		if err := utils.CopyFile(indexPath, Installation.PublicIndex); err != nil {
+			return err
+		}
+		utils.SetReadOnly(Installation.PublicIndex)
+	}
+	if indexURL != "" {
+		Installation.setValidators(Installation.PublicIndex, indexURL, indexValidators)
+	}
+	if err := writeIndexSources(sources); err != nil {
 		return err
 	}
-	utils.SetReadOnly(Installation.PublicIndex)
+	defer func() {
+		if validatorsErr := Installation.writeValidators(); err == nil {
+			err = validatorsErr
+		}
+	}()
 
 	if downloadPdsc {
 		err = DownloadPDSCFiles(false, skipDeprecatedPdscFiles, insecureSkipVerify, concurrency, timeout)
@@ -1140,7 +1404,7 @@ func findInstalledPacks(addLocalPacks, removeDuplicates bool) ([]installedPack,
 
 	if addLocalPacks {
 		// Add packs listed in .Local/local_repository.pidx to the list
-		if err := Installation.LocalPidx.Read(); err != nil {
+		if err := Installation.readLocalPidx(); err != nil {
 			log.Error(err)
 		} else {
 			installedPdscs := Installation.LocalPidx.ListPdscTags()
@@ -1211,6 +1475,9 @@ func findInstalledPacks(addLocalPacks, removeDuplicates bool) ([]installedPack,
 //   - testing: If true, skips reading index files (used for testing).
 //   - listFilter: A string to filter the packs by.
 //
+// If a structured output format is selected via utils.SetOutputFormat, the listed
+// packs are printed as PackRecord entries instead of log lines.
+//
 // Returns:
 //   - error: An error if any occurs during the listing process.
 func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, listRequirements, testing bool, listFilter string) error {
@@ -1221,18 +1488,31 @@ func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, lis
 			return err
 		}
 	}
+
+	output := newListOutput()
+
 	if listPublic || listDeprecated {
 		if listFilter != "" {
-			log.Infof("Listing packs from the public index, filtering by %q", listFilter)
+			output.infof("Listing packs from the public index, filtering by %q", listFilter)
 		} else {
-			log.Infof("Listing packs from the public index")
+			output.infof("Listing packs from the public index")
 		}
 
-		pdscTags := Installation.PublicIndexXML.ListPdscTags()
+		// Packs of each registry, unless they resolve from another one
+		pdscTags := []xml.PdscTag{}
+		registryOf := map[string]*Registry{}
+		for _, registry := range Installation.Registries {
+			for _, pdscTag := range registry.indexXML.ListPdscTags() {
+				if found, _ := Installation.findRegistry(pdscTag.Vendor, pdscTag.Name); found == registry {
+					pdscTags = append(pdscTags, pdscTag)
+					registryOf[pdscTag.Key()] = registry
+				}
+			}
+		}
 
 		if len(pdscTags) == 0 {
-			log.Info("(no packs in public index)")
-			return nil
+			output.infof("(no packs in public index)")
+			return output.flush()
 		}
 
 		sort.Slice(pdscTags, func(i, j int) bool {
@@ -1248,7 +1528,19 @@ func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, lis
 				(listPublic && !listDeprecated && isDeprecated) {
 				continue
 			}
+			record := PackRecord{
+				Vendor:      pdscTag.Vendor,
+				Name:        pdscTag.Name,
+				Version:     pdscTag.Version,
+				State:       PackStateAvailable,
+				Deprecated:  pdscTag.Deprecated,
+				Replacement: pdscTag.Replacement,
+			}
 			logMessage := pdscTag.YamlPackID()
+			if registry := registryOf[pdscTag.Key()]; registry != nil && !registry.IsPublic() {
+				logMessage += fmt.Sprintf(" (registry %s)", registry.Name)
+				record.Registry = registry.Name
+			}
 			if isDeprecated {
 				logMessage += " (deprecated)"
 			}
@@ -1256,20 +1548,22 @@ func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, lis
 
 			if ok, _ := Installation.PackIsInstalled(&PackType{PdscTag: pdscTag}, false); ok {
 				logMessage += " (installed)"
+				record.State = PackStateInstalled
 			} else if utils.FileExists(packFilePath) {
 				logMessage += " (cached)"
+				record.State = PackStateCached
 			}
 
 			// To avoid showing empty log lines ("I: ")
 			if listFilter == "" || utils.FilterPackID(logMessage, listFilter) != "" {
-				log.Info(logMessage)
+				output.add(record, logMessage)
 			}
 		}
 	} else if listCached {
 		if listFilter != "" {
-			log.Infof("Listing cached packs, filtering by %q", listFilter)
+			output.infof("Listing cached packs, filtering by %q", listFilter)
 		} else {
-			log.Infof("Listing cached packs")
+			output.infof("Listing cached packs")
 		}
 		pattern := filepath.Join(Installation.DownloadDir, "*"+utils.PackExtension)
 		matches, err := filepath.Glob(pattern)
@@ -1278,8 +1572,8 @@ func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, lis
 		}
 
 		if len(matches) == 0 {
-			log.Info("(no packs cached)")
-			return nil
+			output.infof("(no packs cached)")
+			return output.flush()
 		}
 
 		sort.Slice(matches, func(i, j int) bool {
@@ -1298,31 +1592,38 @@ func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, lis
 				Name:    packInfo.Pack,
 				Version: packInfo.Version,
 			}
+			record := PackRecord{
+				Vendor:  pdscTag.Vendor,
+				Name:    pdscTag.Name,
+				Version: pdscTag.Version,
+				State:   PackStateCached,
+			}
 
 			logMessage := pdscTag.YamlPackID()
 			if ok, _ := Installation.PackIsInstalled(&PackType{PdscTag: pdscTag}, false); ok {
 				logMessage += " (installed)"
+				record.State = PackStateInstalled
 			}
 
 			if listFilter == "" || utils.FilterPackID(logMessage, listFilter) != "" {
-				log.Info(logMessage)
+				output.add(record, logMessage)
 			}
 		}
 	} else {
 		if listUpdates {
 			if listFilter != "" {
-				log.Infof("Listing installed packs with available update, filtering by %q", listFilter)
+				output.infof("Listing installed packs with available update, filtering by %q", listFilter)
 			} else {
-				log.Infof("Listing installed packs with available update")
+				output.infof("Listing installed packs with available update")
 			}
 		} else {
 			if listRequirements {
-				log.Info("Listing installed packs with dependencies")
+				output.infof("Listing installed packs with dependencies")
 			} else {
 				if listFilter != "" {
-					log.Infof("Listing installed packs, filtering by %q", listFilter)
+					output.infof("Listing installed packs, filtering by %q", listFilter)
 				} else {
-					log.Infof("Listing installed packs")
+					output.infof("Listing installed packs")
 				}
 			}
 		}
@@ -1333,8 +1634,8 @@ func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, lis
 		}
 
 		if len(installedPacks) == 0 {
-			log.Info("(no packs installed)")
-			return nil
+			output.infof("(no packs installed)")
+			return output.flush()
 		}
 
 		numErrors := 0
@@ -1345,7 +1646,23 @@ func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, lis
 			})
 		}
 		for _, pack := range installedPacks {
+			record := PackRecord{
+				Vendor:   pack.Vendor,
+				Name:     pack.Name,
+				Version:  pack.Version,
+				State:    PackStateInstalled,
+				PdscPath: pack.pdscPath,
+			}
+			registry, tags := Installation.findRegistry(pack.Vendor, pack.Name)
+			if len(tags) > 0 {
+				record.Deprecated = tags[0].Deprecated
+				record.Replacement = tags[0].Replacement
+			}
 			logMessage := pack.YamlPackID()
+			if registry != nil && !registry.IsPublic() && !pack.isPdscInstalled {
+				logMessage += fmt.Sprintf(" (registry %s)", registry.Name)
+				record.Registry = registry.Name
+			}
 			// List installed packs and their dependencies
 			p, err := preparePack(pack.Key(), false, listUpdates, listUpdates, false)
 			if err == nil {
@@ -1359,6 +1676,8 @@ func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, lis
 			if listUpdates {
 				logMessage = strings.Replace(logMessage, "@", " can be updated from \"", 1)
 				logMessage += "\" to \"" + p.targetVersion + "\""
+				record.State = PackStateOutdated
+				record.LatestVersion = p.targetVersion
 			}
 			if listRequirements {
 				p.Pdsc = xml.NewPdscXML(pack.pdscPath)
@@ -1380,6 +1699,7 @@ func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, lis
 							logMessage += " (missing) "
 						}
 					}
+					record.Dependencies = p.dependencyRecords()
 				} else {
 					// Not interested in packs with no dependencies
 					continue
@@ -1409,84 +1729,591 @@ func ListInstalledPacks(listCached, listPublic, listUpdates, listDeprecated, lis
 			if len(errors) > 0 {
 				numErrors += 1
 				logMessage += " - error: " + strings.Join(errors[:], ", ") + " incorrect format"
+				record.Errors = append(record.Errors, strings.Join(errors[:], ", ")+" incorrect format")
 				if pack.err != nil {
 					logMessage += fmt.Sprintf(", %v", pack.err)
+					record.Errors = append(record.Errors, pack.err.Error())
 				}
 				if listFilter != "" && utils.FilterPackID(logMessage, listFilter) != "" {
 					printWarning = false
 				}
-				log.Error(logMessage)
+				output.addError(record, logMessage)
 			} else if pack.err != nil {
 				numErrors += 1
 				logMessage += fmt.Sprintf(" - error: %v", pack.err)
+				record.Errors = append(record.Errors, pack.err.Error())
 				if listFilter != "" && utils.FilterPackID(logMessage, listFilter) != "" {
 					printWarning = false
 				}
-				log.Error(logMessage)
+				output.addError(record, logMessage)
 			} else {
 				if listFilter == "" || utils.FilterPackID(logMessage, listFilter) != "" {
-					log.Info(logMessage)
+					output.add(record, logMessage)
 				}
 			}
 		}
 
-		if numErrors > 0 && printWarning {
+		if numErrors > 0 && printWarning && !output.structured() {
 			log.Warnf("%d error(s) detected", numErrors)
 		}
 	}
 
-	return nil
+	return output.flush()
 }
 
-// FindPackURL uses pack.path as packID and try to find the pack URL
-// Finding step are as follows:
-// 1. Find pack.Vendor, pack.Name, pack.Version in Installation.PublicIndex
-// 1.1. if pack.IsPublic == true
-// 1.1.1. read .Web/PDSC file into pdscXML
-// 1.1.2. releastTag = pdscXML.FindReleaseTagByVersion(pack.Version)
-// 1.1.3. if releaseTag.URL != "", return releaseTag.URL
-// 1.1.4. return pdscTag.URL + pack.Vendor + "." + pack.Name + "." + pack.Version + ".pack"
-// 1.2. if pack.IsPublic == false
-// 1.2.1. if pack's pdsc file not found in Installation.LocalDir then raise errs.ErrPackURLCannotBeFound
-// 1.2.2. read .Local/PDSC file into pdscXML
-// 1.2.3. releastTag = pdscXML.FindReleaseTagByVersion(pack.Version)
-// 1.2.4. if releaseTag == nil then raise ErrPackVersionNotFoundInPdsc
-// 1.2.5. if releaseTag.URL != "", return releaseTag.URL
-// 1.2.6. return pdscTag.URL + pack.Vendor + "." + pack.Name + "." + pack.Version + ".pack"
-//
-// The function resolves the version modifier to determine the correct version of the pack to fetch.
-// It then checks the release tag for the specified version and returns the URL if found.
-// If the URL is not found or the version is not available, it returns an appropriate error.
-//
-// Parameters:
-//   - pack: A pointer to the PackType struct representing the pack to find the URL for.
-//   - insecureSkipVerify: A boolean flag indicating whether to skip TLS certificate verification for HTTPS downloads.
-//   - testing: A boolean flag indicating whether the function is being run in a testing environment.
-//
-// Returns:
-//   - string: The URL of the pack if found.
-//   - error: An error if the URL cannot be found or if there are issues with the pack's version.
-func FindPackURL(pack *PackType, insecureSkipVerify, testing bool) (string, error) {
-	log.Debugf("Finding URL for \"%v\"", pack.path)
+// requirementSatisfied tells whether version fulfills a requirement in the internal
+// version format returned by xml.PdscXML.Dependencies(): "latest", "a.b.c:x.y.z" or "x.y.z:_".
+// A "latest" requirement is only satisfied by the most recent version in the public index,
+// or by any version if the pack is not public.
+func requirementSatisfied(version string, requirement []string) bool {
+	if requirement[2] == "latest" {
+		_, pdscTags := Installation.findRegistry(requirement[1], requirement[0])
+		if len(pdscTags) == 0 {
+			return true
+		}
+		return utils.SemverCompare(version, pdscTags[0].Version) >= 0
+	}
+	return utils.SemverCompareRange(version, requirement[2]) == 0
+}
 
-	if pack.IsPublic {
-		packPdscFileName := filepath.Join(Installation.WebDir, pack.PdscFileName())
-		if pack.versionModifier == utils.ExactVersion {
-			pack.targetVersion = pack.Version
-			log.Debugf("- resolved(@) as %s", pack.targetVersion)
-			tags := Installation.PublicIndexXML.FindPdscTags(xml.PdscTag{
-				Vendor: pack.Vendor,
-				Name:   pack.Name,
-			})
+// dependentPack is an installed pack that lists another one in its <requirements>
+type dependentPack struct {
+	*PackType
+
+	// requirement is in [Name, Vendor, Version] format, as returned by xml.PdscXML.Dependencies()
+	requirement []string
+
+	// satisfied tells whether any installed version fulfills the requirement
+	satisfied bool
+}
+
+// loadInstalledPacks reads the PDSC files of all installed packs and returns them
+// with their dependencies already loaded.
+// Packs whose PDSC file cannot be read are returned without requirements.
+func loadInstalledPacks() ([]*PackType, error) {
+	installedPacks, err := findInstalledPacks(true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	packs := []*PackType{}
+	for _, installed := range installedPacks {
+		if installed.err != nil {
+			continue
+		}
+		pack := &PackType{PdscTag: installed.PdscTag}
+		pack.Pdsc = xml.NewPdscXML(installed.pdscPath)
+		if err := pack.Pdsc.Read(); err != nil {
+			log.Debugf("Ignoring the requirements of %q: %v", installed.pdscPath, err)
+			packs = append(packs, pack)
+			continue
+		}
+		if err := pack.loadDependencies(false); err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+	return packs, nil
+}
+
+// loadRequirers returns the installed packs that have requirements,
+// with their dependencies already loaded.
+func loadRequirers() ([]*PackType, error) {
+	packs, err := loadInstalledPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	requirers := []*PackType{}
+	for _, pack := range packs {
+		if len(pack.Requirements.packages) > 0 {
+			requirers = append(requirers, pack)
+		}
+	}
+	return requirers, nil
+}
+
+// requiredPacks walks the requirements of roots through the installed packs and returns
+// the lowercase keys ("vendor.name.x.y.z") of all packs they need, directly or transitively.
+// Every installed version satisfying a requirement is considered needed.
+func requiredPacks(packs, roots []*PackType) map[string]bool {
+	required := map[string]bool{}
+	var walk func(pack *PackType)
+	walk = func(pack *PackType) {
+		for _, req := range pack.Requirements.packages {
+			for _, candidate := range packs {
+				if !strings.EqualFold(candidate.Vendor, req.info[1]) || !strings.EqualFold(candidate.Name, req.info[0]) {
+					continue
+				}
+				key := strings.ToLower(candidate.Key())
+				if required[key] || !requirementSatisfied(candidate.Version, req.info) {
+					continue
+				}
+				required[key] = true
+				walk(candidate)
+			}
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	return required
+}
+
+// orphanedDependencies returns the installed packs that got installed as dependencies
+// and are not required anymore by any explicitly installed pack.
+// It expects "dependencies.pidx" to be loaded.
+func orphanedDependencies(packs []*PackType) []*PackType {
+	explicit := []*PackType{}
+	for _, pack := range packs {
+		if !Installation.isDependency(pack.Vendor, pack.Name, pack.Version) {
+			explicit = append(explicit, pack)
+		}
+	}
+
+	required := requiredPacks(packs, explicit)
+	orphans := []*PackType{}
+	for _, pack := range packs {
+		if Installation.isDependency(pack.Vendor, pack.Name, pack.Version) && !required[strings.ToLower(pack.Key())] {
+			orphans = append(orphans, pack)
+		}
+	}
+	return orphans
+}
+
+// removeOrphanedDependencies uninstalls the orphaned dependency packs. If candidates
+// is not nil, only orphans whose lowercase key is in it are removed.
+func removeOrphanedDependencies(candidates map[string]bool, purge, testing bool) error {
+	if err := Installation.readDependenciesPidx(); err != nil {
+		return err
+	}
+	packs, err := loadInstalledPacks()
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+	for _, orphan := range orphanedDependencies(packs) {
+		if candidates != nil && !candidates[strings.ToLower(orphan.Key())] {
+			continue
+		}
+		log.Infof("Removing %s, which is no longer required by any explicitly installed pack", orphan.YamlPackID())
+		if _, err := RemovePack(orphan.Key(), purge, true, false, false, testing); err != nil {
+			return err
+		}
+		removed++
+	}
+	if removed == 0 {
+		log.Info("(no orphaned dependency packs to remove)")
+	}
+	return nil
+}
+
+// Autoremove uninstalls all packs that got installed only to satisfy the requirements
+// of other packs, and that are no longer required by any explicitly installed pack.
+// Packs installed before install reasons were tracked count as explicitly installed.
+//
+// Parameters:
+//   - purge: A boolean indicating whether to also remove the cached pack files.
+//   - testing: A boolean indicating whether the function is being run in a testing environment.
+//
+// Returns:
+//   - error: An error if any pack could not be removed, or nil if successful.
+func Autoremove(purge, testing bool) error {
+	log.Info("Removing dependency packs no longer required")
+
+	if err := ReadIndexFiles(); err != nil {
+		return err
+	}
+
+	return inTransaction("autoremove", func() error {
+		return removeOrphanedDependencies(nil, purge, testing)
+	})
+}
+
+// directDependents returns one entry per requirement on Vendor.Name found in requirers
+func directDependents(requirers []*PackType, vendor, name string) []dependentPack {
+	dependents := []dependentPack{}
+	for _, pack := range requirers {
+		for _, req := range pack.Requirements.packages {
+			if strings.EqualFold(req.info[1], vendor) && strings.EqualFold(req.info[0], name) {
+				dependents = append(dependents, dependentPack{PackType: pack, requirement: req.info, satisfied: req.installed})
+			}
+		}
+	}
+	return dependents
+}
+
+// brokenDependents returns the dependents of Vendor.Name whose requirements are currently
+// satisfied, but would not be anymore once removedVersions get uninstalled
+func brokenDependents(requirers []*PackType, vendor, name string, removedVersions []string) []dependentPack {
+	_, installedVersions := Installation.PackIsInstalled(&PackType{
+		PdscTag:         xml.PdscTag{Vendor: vendor, Name: name},
+		versionModifier: utils.AnyVersion,
+	}, false)
+
+	remainingVersions := []string{}
+	for _, version := range installedVersions {
+		if !slices.Contains(removedVersions, version) {
+			remainingVersions = append(remainingVersions, version)
+		}
+	}
+
+	broken := []dependentPack{}
+	for _, dependent := range directDependents(requirers, vendor, name) {
+		// A pack requiring another version of itself is not a dependent
+		if strings.EqualFold(dependent.Vendor, vendor) && strings.EqualFold(dependent.Name, name) {
+			continue
+		}
+		if !dependent.satisfied {
+			continue
+		}
+		stillSatisfied := false
+		for _, version := range remainingVersions {
+			if requirementSatisfied(version, dependent.requirement) {
+				stillSatisfied = true
+				break
+			}
+		}
+		if !stillSatisfied {
+			broken = append(broken, dependent)
+		}
+	}
+	return broken
+}
+
+// ListDependents lists all installed packs that require the given pack, either directly
+// in their <requirements><packages> section or transitively through other installed packs.
+// Each entry tells whether the requirement is satisfied: if packPath specifies a version,
+// that version is checked against the requirement (useful before a downgrade), otherwise
+// the requirement is checked against the installed versions.
+//
+// Parameters:
+//   - packPath: The pack reference, e.g. "Vendor.Pack" or "Vendor::Pack@x.y.z".
+//   - testing: If true, skips reading index files (used for testing).
+//
+// If a structured output format is selected via utils.SetOutputFormat, each dependent is
+// printed as a PackRecord whose only dependency is the requirement that links it to the pack.
+//
+// Returns:
+//   - error: An error if the pack reference is invalid or installed packs cannot be listed.
+func ListDependents(packPath string, testing bool) error {
+	log.Debugf("Listing dependents of %q", packPath)
+
+	info, err := utils.ExtractPackInfo(packPath)
+	if err != nil {
+		return err
+	}
+
+	if !testing {
+		if err := ReadIndexFiles(); err != nil {
+			return err
+		}
+	}
+
+	targetID := info.Vendor + "::" + info.Pack
+	output := newListOutput()
+	output.infof("Listing packs depending on %s", targetID)
+
+	requirers, err := loadRequirers()
+	if err != nil {
+		return err
+	}
+
+	type dependee struct {
+		vendor, name string
+		via          []string // packs between a dependent and the queried pack
+	}
+	queue := []dependee{{vendor: info.Vendor, name: info.Pack}}
+	visited := map[string]bool{strings.ToLower(info.Vendor + "." + info.Pack): true}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, dependent := range directDependents(requirers, current.vendor, current.name) {
+			satisfied := dependent.satisfied
+			if len(current.via) == 0 && info.Version != "" && info.VersionModifier == utils.ExactVersion {
+				satisfied = requirementSatisfied(info.Version, dependent.requirement)
+			}
+			state := PackStateUnsatisfied
+			if satisfied {
+				state = PackStateSatisfied
+			}
+
+			record := PackRecord{
+				Vendor:  dependent.Vendor,
+				Name:    dependent.Name,
+				Version: dependent.Version,
+				State:   PackStateInstalled,
+				Dependencies: []DependencyRecord{{
+					Vendor:  dependent.requirement[1],
+					Name:    dependent.requirement[0],
+					Version: dependent.requirement[2],
+					State:   state,
+				}},
+				Via: current.via,
+			}
+			logMessage := fmt.Sprintf("%s requires %s (%s)", dependent.YamlPackID(), utils.FormatPackVersion(dependent.requirement), state)
+			if len(current.via) > 0 {
+				logMessage += ", via " + strings.Join(current.via, " -> ")
+			}
+			output.add(record, logMessage)
+
+			key := strings.ToLower(dependent.VName())
+			if !visited[key] {
+				visited[key] = true
+				queue = append(queue, dependee{
+					vendor: dependent.Vendor,
+					name:   dependent.Name,
+					via:    append([]string{dependent.Vendor + "::" + dependent.Name}, current.via...),
+				})
+			}
+		}
+	}
+
+	if len(visited) == 1 {
+		output.infof("(no installed pack depends on %s)", targetID)
+	}
+
+	return output.flush()
+}
+
+// findPackPdsc looks for a pack's PDSC file without accessing the network.
+// The candidates are checked in the following order:
+//   - ".Web/Vendor.Pack.pdsc" if the pack is public
+//   - ".Local/Vendor.Pack.pdsc" for packs installed from non-public pack files
+//   - the PDSC file referenced in ".Local/local_repository.pidx"
+//   - the most recent ".Download/Vendor.Pack.x.y.z.pdsc"
+//
+// Parameters:
+//   - pack: The pack whose PDSC file should be found.
+//
+// Returns:
+//   - string: The path to the PDSC file, or an empty string if none was found.
+func findPackPdsc(pack *PackType) string {
+	candidates := []string{}
+	if pack.IsPublic {
+		candidates = append(candidates, Installation.webPdscPath(pack.pdscRegistry(), pack.PdscFileName()))
+	}
+	candidates = append(candidates, filepath.Join(Installation.LocalDir, pack.PdscFileName()))
+
+	if err := Installation.readLocalPidx(); err != nil {
+		log.Warn("Could not read local index")
+	} else {
+		for _, pdscTag := range Installation.LocalPidx.FindPdscTags(xml.PdscTag{Vendor: pack.Vendor, Name: pack.Name}) {
+			parsedURL, err := url.ParseRequestURI(pdscTag.URL)
+			if err != nil {
+				continue
+			}
+			candidates = append(candidates, filepath.Join(utils.CleanPath(parsedURL.Path), pdscTag.PdscFileName()))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if utils.FileExists(candidate) {
+			return candidate
+		}
+	}
+
+	// Fall back to the versioned copies saved alongside cached pack files
+	matches, _ := filepath.Glob(filepath.Join(Installation.DownloadDir, pack.PackID()+".*"+utils.PdscExtension))
+	latestPdsc, latestVersion := "", ""
+	for _, match := range matches {
+		version := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), pack.PackID()+"."), utils.PdscExtension)
+		if !utils.IsPackVersionValid(version) {
+			continue
+		}
+		if latestVersion == "" || utils.SemverCompare(version, latestVersion) > 0 {
+			latestPdsc, latestVersion = match, version
+		}
+	}
+
+	return latestPdsc
+}
+
+// ShowPackInfo prints what is known about a pack without installing it: all
+// releases listed in its PDSC file with dates and URLs, license file, requirements,
+// deprecation and replacement, as well as installed versions and cached pack files.
+// Only files already present in the pack root are read, so this works offline.
+//
+// Parameters:
+//   - packPath: The pack reference, e.g. "Vendor.Pack" or "Vendor::Pack@x.y.z".
+//
+// Returns:
+//   - error: An error if the pack reference is invalid or its PDSC file cannot be found or read.
+func ShowPackInfo(packPath string) error {
+	log.Debugf("Showing info for %q", packPath)
+
+	pack, err := preparePack(packPath, false, false, false, false)
+	if err != nil {
+		return err
+	}
+
+	pdscFilePath := findPackPdsc(pack)
+	if pdscFilePath == "" {
+		return fmt.Errorf("%w: %q. Run \"cpackget update-index\" if it is a public pack", errs.ErrPdscFileNotFound, pack.PackID())
+	}
+
+	pack.Pdsc = xml.NewPdscXML(pdscFilePath)
+	if err := pack.Pdsc.Read(); err != nil {
+		return err
+	}
+
+	header := pack.Vendor + "::" + pack.Name
+	if pack.IsPublic && !pack.pdscRegistry().IsPublic() {
+		header += fmt.Sprintf(" (registry %s)", pack.pdscRegistry().Name)
+	} else if pack.IsPublic {
+		header += " (public)"
+	}
+	log.Info(header)
+	log.Infof("  Description file: %s", pdscFilePath)
+	if pack.Pdsc.URL != "" {
+		log.Infof("  URL: %s", pack.Pdsc.URL)
+	}
+	if pack.Pdsc.License != "" {
+		log.Infof("  License: %s", pack.Pdsc.License)
+	}
+
+	// The public index tells whether the pack as a whole got deprecated
+	deprecated, replacement := "", ""
+	if _, pdscTags := Installation.findRegistry(pack.Vendor, pack.Name); len(pdscTags) > 0 {
+		deprecated, replacement = pdscTags[0].Deprecated, pdscTags[0].Replacement
+	}
+	if releaseTag := pack.Pdsc.FindReleaseTagByVersion(""); releaseTag != nil && deprecated == "" {
+		deprecated, replacement = releaseTag.Deprecated, releaseTag.Replacement
+	}
+	if deprecated != "" {
+		message := "  Deprecated: " + deprecated
+		if replacement != "" {
+			message += ", replaced by " + replacement
+		}
+		log.Info(message)
+	}
+
+	_, installedVersions := Installation.PackIsInstalled(&PackType{
+		PdscTag:         xml.PdscTag{Vendor: pack.Vendor, Name: pack.Name},
+		versionModifier: utils.AnyVersion,
+	}, false)
+	sort.Slice(installedVersions, func(i, j int) bool {
+		return utils.SemverCompare(installedVersions[i], installedVersions[j]) > 0
+	})
+	installedVersions = slices.Compact(installedVersions)
+
+	log.Info("Releases:")
+	if len(pack.Pdsc.ReleasesTag.Releases) == 0 {
+		log.Info("  (no releases)")
+	}
+	for _, releaseTag := range pack.Pdsc.ReleasesTag.Releases {
+		version := utils.SemverStripMeta(releaseTag.Version)
+		message := "  " + releaseTag.Version
+		if releaseTag.Date != "" {
+			message += " (" + releaseTag.Date + ")"
+		}
+		if releaseTag.URL != "" {
+			message += " " + releaseTag.URL
+		} else {
+			message += " " + pack.Pdsc.PackURL(version)
+		}
+		if releaseTag.Deprecated != "" {
+			message += " (deprecated)"
+		}
+		if slices.Contains(installedVersions, version) {
+			message += " (installed)"
+		} else if utils.FileExists(filepath.Join(Installation.DownloadDir, pack.PackID()+"."+version+utils.PackExtension)) {
+			message += " (cached)"
+		}
+		log.Info(message)
+	}
+
+	log.Info("Requirements:")
+	if err := pack.loadDependencies(false); err != nil {
+		return err
+	}
+	if len(pack.Requirements.packages) == 0 {
+		log.Info("  (no requirements)")
+	}
+	for _, dependency := range pack.dependencyRecords() {
+		log.Infof("  %s (%s)", utils.FormatPackVersion([]string{dependency.Name, dependency.Vendor, dependency.Version}), dependency.State)
+	}
+
+	log.Info("Installed versions:")
+	if len(installedVersions) == 0 {
+		log.Info("  (not installed)")
+	}
+	for _, version := range installedVersions {
+		log.Infof("  %s", version)
+	}
+
+	log.Info("Cached pack files:")
+	cachedFiles := []string{}
+	for _, extension := range []string{utils.PackExtension, ".zip"} {
+		matches, err := filepath.Glob(filepath.Join(Installation.DownloadDir, pack.PackID()+".*"+extension))
+		if err != nil {
+			return err
+		}
+		cachedFiles = append(cachedFiles, matches...)
+	}
+	if len(cachedFiles) == 0 {
+		log.Info("  (no pack files cached)")
+	}
+	for _, cachedFile := range cachedFiles {
+		log.Infof("  %s", cachedFile)
+	}
+
+	return nil
+}
+
+// FindPackURL uses pack.path as packID and try to find the pack URL
+// Finding step are as follows:
+// 1. Find pack.Vendor, pack.Name, pack.Version in Installation.PublicIndex
+// 1.1. if pack.IsPublic == true
+// 1.1.1. read .Web/PDSC file into pdscXML
+// 1.1.2. releastTag = pdscXML.FindReleaseTagByVersion(pack.Version)
+// 1.1.3. if releaseTag.URL != "", return releaseTag.URL
+// 1.1.4. return pdscTag.URL + pack.Vendor + "." + pack.Name + "." + pack.Version + ".pack"
+// 1.2. if pack.IsPublic == false
+// 1.2.1. if pack's pdsc file not found in Installation.LocalDir then raise errs.ErrPackURLCannotBeFound
+// 1.2.2. read .Local/PDSC file into pdscXML
+// 1.2.3. releastTag = pdscXML.FindReleaseTagByVersion(pack.Version)
+// 1.2.4. if releaseTag == nil then raise ErrPackVersionNotFoundInPdsc
+// 1.2.5. if releaseTag.URL != "", return releaseTag.URL
+// 1.2.6. return pdscTag.URL + pack.Vendor + "." + pack.Name + "." + pack.Version + ".pack"
+//
+// The function resolves the version modifier to determine the correct version of the pack to fetch.
+// It then checks the release tag for the specified version and returns the URL if found.
+// If the URL is not found or the version is not available, it returns an appropriate error.
+//
+// Parameters:
+//   - pack: A pointer to the PackType struct representing the pack to find the URL for.
+//   - insecureSkipVerify: A boolean flag indicating whether to skip TLS certificate verification for HTTPS downloads.
+//   - testing: A boolean flag indicating whether the function is being run in a testing environment.
+//
+// Returns:
+//   - string: The URL of the pack if found.
+//   - error: An error if the URL cannot be found or if there are issues with the pack's version.
+func FindPackURL(pack *PackType, insecureSkipVerify, testing bool) (string, error) {
+	log.Debugf("Finding URL for \"%v\"", pack.path)
+
+	if pack.IsPublic {
+		registry := pack.pdscRegistry()
+		packPdscFileName := ""
+		if pack.versionModifier == utils.ExactVersion {
+			pack.targetVersion = pack.Version
+			log.Debugf("- resolved(@) as %s", pack.targetVersion)
+			tags := registry.indexXML.FindPdscTags(xml.PdscTag{
+				Vendor: pack.Vendor,
+				Name:   pack.Name,
+			})
 			if len(tags) != 0 {
-				if err := Installation.downloadPdscFile(tags[0], true, true, true, insecureSkipVerify, 0); err != nil {
+				if err := Installation.downloadPdscFile(registry, tags[0], true, true, true, insecureSkipVerify, 0); err != nil {
 					return "", err
 				}
 			} else {
 				return "", errs.ErrPdscEntryNotFound
 			}
 		} else {
-			if err := Installation.downloadPdscFile(xml.PdscTag{
+			if err := Installation.downloadPdscFile(registry, xml.PdscTag{
 				URL:    pack.URL,
 				Vendor: pack.Vendor,
 				Name:   pack.Name,
@@ -1494,6 +2321,7 @@ func FindPackURL(pack *PackType, insecureSkipVerify, testing bool) (string, erro
 				return "", err
 			}
 		}
+		packPdscFileName = Installation.webPdscPath(registry, pack.PdscFileName())
 		packPdscXML := xml.NewPdscXML(packPdscFileName)
 		if err := packPdscXML.Read(); err != nil {
 			if errors.Unwrap(err) == syscall.ENOENT {
@@ -1508,12 +2336,12 @@ func FindPackURL(pack *PackType, insecureSkipVerify, testing bool) (string, erro
 			Vendor: pack.Vendor,
 			Name:   pack.Name,
 		}
-		if !testing && packPdscXML.LatestVersion() != "" {
-			pidxVersions := Installation.PublicIndexXML.FindPdscTags(xmlTag)
+		if !testing && !utils.GetOffline() && packPdscXML.LatestVersion() != "" {
+			pidxVersions := registry.indexXML.FindPdscTags(xmlTag)
 			if len(pidxVersions) > 0 && pidxVersions[0].Version != packPdscXML.LatestVersion() {
 				logVersion := pidxVersions[0].Version
 				infoInsteadWarn := false
-				if err := Installation.downloadPdscFile(xml.PdscTag{
+				if err := Installation.downloadPdscFile(registry, xml.PdscTag{
 					URL:    pack.URL,
 					Vendor: pack.Vendor,
 					Name:   pack.Name,
@@ -1521,6 +2349,7 @@ func FindPackURL(pack *PackType, insecureSkipVerify, testing bool) (string, erro
 					log.Warnf("Latest pdsc %q does not exist in public index", xmlTag.Key())
 					return "", err
 				}
+				packPdscFileName = Installation.webPdscPath(registry, pack.PdscFileName())
 				packPdscXML = xml.NewPdscXML(packPdscFileName)
 				if err := packPdscXML.Read(); err != nil {
 					log.Warnf("Latest pdsc %q does not exist in public index", xmlTag.Key())
@@ -1532,22 +2361,22 @@ func FindPackURL(pack *PackType, insecureSkipVerify, testing bool) (string, erro
 				// Re-check the latest version after downloading the PDSC file
 				xmlTag.Version = packPdscXML.LatestVersion()
 				if xmlTag.Version != "" {
-					pidxVersions = Installation.PublicIndexXML.FindPdscTags(xmlTag)
-					if len(pidxVersions) == 0 {
-						filenameSave := Installation.PublicIndexXML.GetFileName()
-						defer Installation.PublicIndexXML.SetFileName(filenameSave)                                       // restore the original file name
-						Installation.PublicIndexXML.SetFileName(filepath.Join(Installation.DownloadDir, PublicIndexName)) // use cache as temporary store
-						_ = Installation.PublicIndexXML.ReplacePdscVersion(xmlTag)
-						if err := Installation.PublicIndexXML.Write(); err != nil {
+					pidxVersions = registry.indexXML.FindPdscTags(xmlTag)
+					if len(pidxVersions) == 0 && Installation.dryRunDir == "" {
+						filenameSave := registry.indexXML.GetFileName()
+						defer registry.indexXML.SetFileName(filenameSave)                                       // restore the original file name
+						registry.indexXML.SetFileName(filepath.Join(Installation.DownloadDir, PublicIndexName)) // use cache as temporary store
+						_ = registry.indexXML.ReplacePdscVersion(xmlTag)
+						if err := registry.indexXML.Write(); err != nil {
 							log.Warnf("Latest version of pdsc %q does not exist in public index", xmlTag.Key())
 							return "", err
 						}
-						utils.UnsetReadOnly(Installation.PublicIndex)
-						if err := utils.MoveFile(Installation.PublicIndexXML.GetFileName(), Installation.PublicIndex); err != nil {
+						utils.UnsetReadOnly(registry.index)
+						if err := utils.MoveFile(registry.indexXML.GetFileName(), registry.index); err != nil {
 							log.Warnf("Latest version of pdsc %q does not exist in public index", xmlTag.Key())
 							return "", err
 						}
-						utils.SetReadOnly(Installation.PublicIndex)
+						utils.SetReadOnly(registry.index)
 						infoInsteadWarn = true // if we downloaded the PDSC file and it has the same version as in the public index, we can log it as info
 					}
 				}
@@ -1595,11 +2424,13 @@ func FindPackURL(pack *PackType, insecureSkipVerify, testing bool) (string, erro
 			}
 		}
 
-		if releaseTag.URL != "" {
-			return releaseTag.URL, nil
+		packURL := releaseTag.URL
+		if packURL == "" {
+			packURL = packPdscXML.PackURL(pack.targetVersion)
 		}
 
-		return packPdscXML.PackURL(pack.targetVersion), nil
+		// Packs of a local registry, e.g. one written by "publish", get installed from where they are
+		return utils.FileURLToPath(packURL)
 	}
 
 	// if pack.IsPublic == false, it doesn't mean yet it's an actual non-Public pack, need to check in .Local
@@ -1684,10 +2515,12 @@ func SetPackRoot(packRoot string, create bool) error {
 		PackIdx:     filepath.Join(packRoot, "pack.idx"),
 	}
 	Installation.LocalPidx = xml.NewPidxXML(filepath.Join(Installation.LocalDir, "local_repository.pidx"), false)
+	Installation.DependenciesPidx = xml.NewPidxXML(filepath.Join(Installation.LocalDir, DependenciesIndexName), false)
 	Installation.PublicIndex = filepath.Join(Installation.WebDir, PublicIndexName)
 	Installation.PublicCacheIndex = filepath.Join(Installation.WebDir, PublicCacheIndex)
 	Installation.PublicIndexXML = xml.NewPidxXML(Installation.PublicIndex, false)
 	Installation.PublicCacheIndexXML = xml.NewPidxXML(Installation.PublicCacheIndex, true)
+	Installation.setRegistries()
 
 	missingDirs := []string{}
 	for _, dir := range []string{packRoot, Installation.DownloadDir, Installation.LocalDir, Installation.WebDir} {
@@ -1721,6 +2554,9 @@ func SetPackRoot(packRoot string, create bool) error {
 // to allow reading the files, and then locks it again.
 // It returns an error if reading either of the index files fails.
 func ReadIndexFiles() error {
+	if Installation.dryRunDir != "" {
+		return readExistingIndexFiles()
+	}
 	if Installation.ReadOnly {
 		UnlockPackRoot()
 		defer LockPackRoot()
@@ -1737,7 +2573,10 @@ func ReadIndexFiles() error {
 		return err
 	}
 	if Installation.PublicIndexXML.URL != "" {
-		ActualPublicIndex = Installation.PublicIndexXML.URL + PublicIndexName
+		ActualPublicIndex = publicIndexURL()
+	}
+	if err := Installation.readRegistries(); err != nil {
+		return err
 	}
 
 	err = Installation.LocalPidx.Read()
@@ -1748,6 +2587,36 @@ func ReadIndexFiles() error {
 	return nil
 }
 
+// readExistingIndexFiles is the dry run variant of ReadIndexFiles: index files
+// missing from the pack root are left empty instead of being created.
+func readExistingIndexFiles() error {
+	if utils.FileExists(Installation.PublicCacheIndex) {
+		_ = Installation.PublicCacheIndexXML.Read()
+	}
+	if utils.FileExists(Installation.PublicIndex) {
+		if err := Installation.PublicIndexXML.Read(); err != nil {
+			Installation.PublicIndexXML.URL = ""
+			return err
+		}
+		if Installation.PublicIndexXML.URL != "" {
+			ActualPublicIndex = publicIndexURL()
+		}
+	}
+	if err := Installation.readRegistries(); err != nil {
+		return err
+	}
+	return Installation.readLocalPidx()
+}
+
+// readLocalPidx reads "local_repository.pidx". Outside dry runs, the file gets created if missing.
+func (p *PacksInstallationType) readLocalPidx() error {
+	if p.dryRunDir != "" && !utils.FileExists(p.LocalPidx.GetFileName()) {
+		p.LocalPidx.Clear()
+		return nil
+	}
+	return p.LocalPidx.Read()
+}
+
 // PacksInstallationType is the struct that manages Open-CMSIS-Pack installation/deletion.
 type PacksInstallationType struct {
 	// PackRoot is the working directory if the packs installation
@@ -1784,9 +2653,29 @@ type PacksInstallationType struct {
 	// list of PDSC tags representing all packs installed via PDSC files.
 	LocalPidx *xml.PidxXML
 
+	// Registries are the indexes packs get resolved from, in priority order, including the public one
+	Registries []*Registry
+
+	// DependenciesPidx is a reference to "dependencies.pidx" that lists all packs
+	// installed only to satisfy the requirements of other packs. Packs not listed
+	// there were explicitly installed.
+	DependenciesPidx *xml.PidxXML
+
+	// dryRunDir receives the files downloaded while planning a dry run, so that the
+	// pack root is left untouched. It is empty unless a dry run is in progress.
+	dryRunDir string
+
 	// localIsLoaded is a flag that tells whether the local_repository.pidx has been loaded or not
 	localIsLoaded bool
 
+	// transaction journals the changes made to the pack root by the batch operation
+	// in progress, so that they can be rolled back. It is nil outside transactions.
+	transaction *transaction
+
+	// validators of the index and PDSC files downloaded to WebDir and LocalDir,
+	// see ValidatorsFileName
+	validators validatorsStore
+
 	// PackIdx is the "pack.idx" file used by other tools to be notified that
 	// the pack installation had changed.
 	PackIdx string
@@ -1904,6 +2793,102 @@ func (p *PacksInstallationType) touchPackIdx() error {
 	return err
 }
 
+// webPdscPath returns the path of a public PDSC file under ".Web", or under the folder of its
+// registry. During dry runs, the copy downloaded to the scratch directory is preferred, if there is one.
+func (p *PacksInstallationType) webPdscPath(registry *Registry, pdscFileName string) string {
+	if p.dryRunDir != "" {
+		if pdscFilePath := filepath.Join(p.dryRunDir, pdscFileName); utils.FileExists(pdscFilePath) {
+			return pdscFilePath
+		}
+	}
+	return filepath.Join(registry.webDir, pdscFileName)
+}
+
+// readDependenciesPidx loads "dependencies.pidx". The file is not created if it
+// does not exist yet, in which case no pack is considered a dependency.
+func (p *PacksInstallationType) readDependenciesPidx() error {
+	if !utils.FileExists(p.DependenciesPidx.GetFileName()) {
+		p.DependenciesPidx.Clear()
+		return nil
+	}
+	return p.DependenciesPidx.Read()
+}
+
+// findDependencyTags returns the tags in "dependencies.pidx" matching Vendor.Name.version,
+// or all versions of Vendor.Name if version is empty. It expects the file to be loaded.
+func (p *PacksInstallationType) findDependencyTags(vendor, name, version string) []xml.PdscTag {
+	tags := []xml.PdscTag{}
+	for _, tag := range p.DependenciesPidx.ListPdscTags() {
+		if strings.EqualFold(tag.Vendor, vendor) && strings.EqualFold(tag.Name, name) &&
+			(version == "" || tag.Version == utils.SemverStripMeta(version)) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// isDependency tells whether Vendor.Name.version got installed only to satisfy the
+// requirements of other packs. It expects "dependencies.pidx" to be loaded.
+func (p *PacksInstallationType) isDependency(vendor, name, version string) bool {
+	return len(p.findDependencyTags(vendor, name, version)) > 0
+}
+
+// installedAsDependency tells whether Vendor.Name is installed and all of its installed
+// versions got installed as dependencies of other packs.
+func (p *PacksInstallationType) installedAsDependency(vendor, name string) (bool, error) {
+	if err := p.readDependenciesPidx(); err != nil {
+		return false, err
+	}
+	_, installedVersions := p.PackIsInstalled(&PackType{
+		PdscTag:         xml.PdscTag{Vendor: vendor, Name: name},
+		versionModifier: utils.AnyVersion,
+	}, false)
+	if len(installedVersions) == 0 {
+		return false, nil
+	}
+	for _, version := range installedVersions {
+		if !p.isDependency(vendor, name, version) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// addDependencyTag records in "dependencies.pidx" that Vendor.Name.version got installed
+// only to satisfy the requirements of other packs.
+func (p *PacksInstallationType) addDependencyTag(vendor, name, version string) error {
+	if err := p.readDependenciesPidx(); err != nil {
+		return err
+	}
+	err := p.DependenciesPidx.AddPdsc(xml.PdscTag{Vendor: vendor, Name: name, Version: version})
+	if err == errs.ErrPdscEntryExists {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return p.DependenciesPidx.Write()
+}
+
+// removeDependencyTag removes Vendor.Name.version from "dependencies.pidx", or all versions
+// of Vendor.Name if version is empty, making them explicitly installed.
+// It returns whether any tag got removed.
+func (p *PacksInstallationType) removeDependencyTag(vendor, name, version string) (bool, error) {
+	if err := p.readDependenciesPidx(); err != nil {
+		return false, err
+	}
+	tags := p.findDependencyTags(vendor, name, version)
+	if len(tags) == 0 {
+		return false, nil
+	}
+	for _, tag := range tags {
+		if err := p.DependenciesPidx.RemovePdsc(tag); err != nil {
+			return false, err
+		}
+	}
+	return true, p.DependenciesPidx.Write()
+}
+
 // PackIsInstalled checks if a specific pack is installed based on the provided
 // pack information and version constraints.
 //
@@ -1940,7 +2925,7 @@ func (p *PacksInstallationType) PackIsInstalled(pack *PackType, noLocal bool) (f
 			return
 		}
 		// Gather all versions in local_repository.idx for local .pdsc installed packs
-		if err := p.LocalPidx.Read(); err != nil {
+		if err := p.readLocalPidx(); err != nil {
 			log.Warn("Could not read local index")
 			return
 		}
@@ -1975,7 +2960,7 @@ func (p *PacksInstallationType) PackIsInstalled(pack *PackType, noLocal bool) (f
 		}
 	} else {
 		// Gather all versions in local_repository.idx for local .psdc installed packs
-		if err := p.LocalPidx.Read(); err != nil {
+		if err := p.readLocalPidx(); err != nil {
 			log.Warn("Could not read local index")
 			return
 		}
@@ -2067,7 +3052,7 @@ func (p *PacksInstallationType) PackIsInstalled(pack *PackType, noLocal bool) (f
 		// version for that pack to then check if it's installed
 		var latestVersion string
 		if pack.IsPublic {
-			tags := Installation.PublicIndexXML.FindPdscTags(xml.PdscTag{
+			tags := pack.pdscRegistry().indexXML.FindPdscTags(xml.PdscTag{
 				Vendor: pack.Vendor,
 				Name:   pack.Name,
 			})
@@ -2125,15 +3110,16 @@ func (p *PacksInstallationType) packIsPublic(pack *PackType, pdscTag *xml.PdscTa
 		}
 	}
 
-	// Try to retrieve the packs's PDSC file out of the index.pidx
-	pdscTags := p.PublicIndexXML.FindPdscTags(xml.PdscTag{
-		Vendor: pack.Vendor,
-		Name:   pack.Name,
-	})
+	// Try to retrieve the packs's PDSC file out of the index.pidx of the registries
+	registry, pdscTags := p.findRegistry(pack.Vendor, pack.Name)
 	if len(pdscTags) == 0 {
-		log.Debugf("Not found %q tag in %q", pack.PdscFileName(), p.PublicIndex)
+		log.Debugf("Not found %q tag in the index of any registry", pack.PdscFileName())
 		return false, nil
 	}
+	pack.registry = registry
+	if !registry.IsPublic() {
+		log.Debugf("%s resolves from registry %q", pack.PackID(), registry.Name)
+	}
 
 	// Sometimes a pidx file might have multiple pdsc tags for same key
 	// which is not the case here, so we'll take only the first one
@@ -2168,9 +3154,9 @@ func (p *PacksInstallationType) packIsPublic(pack *PackType, pdscTag *xml.PdscTa
 //     the function switches to the cache URL for downloading.
 //   - The function downloads the PDSC file, temporarily saves it, and then moves it to the target location,
 //     ensuring proper file permissions are set before and after the operation.
-func (p *PacksInstallationType) downloadPdscFile(pdscTag xml.PdscTag, skipInstalledPdscFiles, showInfo, showProgressBar, insecureSkipVerify bool, timeout int) error {
+func (p *PacksInstallationType) downloadPdscFile(registry *Registry, pdscTag xml.PdscTag, skipInstalledPdscFiles, showInfo, showProgressBar, insecureSkipVerify bool, timeout int) error {
 	basePdscFile := fmt.Sprintf("%s%s", pdscTag.VName(), utils.PdscExtension)
-	pdscFilePath := filepath.Join(p.WebDir, basePdscFile)
+	pdscFilePath := p.webPdscPath(registry, basePdscFile)
 
 	if skipInstalledPdscFiles {
 		if utils.FileExists(pdscFilePath) {
@@ -2179,11 +3165,14 @@ func (p *PacksInstallationType) downloadPdscFile(pdscTag xml.PdscTag, skipInstal
 		}
 		log.Debugf("File does not exist and will be copied: %q", pdscFilePath)
 	}
+	if p.dryRunDir != "" {
+		pdscFilePath = filepath.Join(p.dryRunDir, basePdscFile)
+	}
 
 	pdscURL := pdscTag.URL
 
 	// switch  to keil.com cache for PDSC file
-	if pdscURL != KeilDefaultPackRoot && Installation.PublicIndexXML.URL == KeilDefaultPackRoot {
+	if registry.IsPublic() && pdscURL != KeilDefaultPackRoot && Installation.PublicIndexXML.URL == KeilDefaultPackRoot {
 		log.Debugf("Switching to cache: %q", KeilDefaultPackRoot)
 		pdscURL = KeilDefaultPackRoot
 	}
@@ -2198,19 +3187,33 @@ func (p *PacksInstallationType) downloadPdscFile(pdscTag xml.PdscTag, skipInstal
 
 	pdscFileURL.Path = path.Join(pdscFileURL.Path, basePdscFile)
 
-	localFileName, err := utils.DownloadFile(pdscFileURL.String(), true, showInfo, showProgressBar, insecureSkipVerify, timeout)
-	defer os.Remove(localFileName)
-
-	if err != nil {
-		//		log.Errorf("Could not download %q: %s", pdscFileURL, err)
-		//		return fmt.Errorf("%s: %w", pdscFileURL, errs.ErrPackPdscCannotBeFound)
-		return err
+	var validators utils.Validators
+	var localFileName string
+	if p.dryRunDir == "" {
+		// Keep the PDSC file downloaded before if it did not change since
+		validators = p.validatorsOf(pdscFilePath, pdscFileURL.String())
+		localFileName, err = utils.DownloadFileIfModified(pdscFileURL.String(), true, &validators, showInfo, showProgressBar, insecureSkipVerify, timeout)
+	} else {
+		localFileName, err = utils.DownloadFile(pdscFileURL.String(), true, showInfo, showProgressBar, insecureSkipVerify, timeout)
 	}
 
-	utils.UnsetReadOnly(pdscFilePath)
-	os.Remove(pdscFilePath)
-	err = utils.MoveFile(localFileName, pdscFilePath)
-	utils.SetReadOnly(pdscFilePath)
+	if errors.Is(err, errs.ErrNotModified) {
+		err = nil
+	} else {
+		defer os.Remove(localFileName)
+
+		if err != nil {
+			//		log.Errorf("Could not download %q: %s", pdscFileURL, err)
+			//		return fmt.Errorf("%s: %w", pdscFileURL, errs.ErrPackPdscCannotBeFound)
+			return err
+		}
+
+		if err := p.stash(pdscFilePath); err != nil {
+			return err
+		}
+		err = utils.MoveFile(localFileName, pdscFilePath)
+		utils.SetReadOnly(pdscFilePath)
+	}
 
 	pdscXML := xml.NewPdscXML(pdscFilePath)
 	if err := pdscXML.Read(); err != nil {
@@ -2218,6 +3221,12 @@ func (p *PacksInstallationType) downloadPdscFile(pdscTag xml.PdscTag, skipInstal
 		os.Remove(pdscFilePath)
 		return fmt.Errorf("%s: %w, file removed", pdscFilePath, err)
 	}
+	if p.dryRunDir != "" {
+		return err
+	}
+	if err == nil {
+		p.setValidators(pdscFilePath, pdscFileURL.String(), validators)
+	}
 	releaseTag := pdscXML.FindReleaseTagByVersion("")
 	cacheTag := xml.PdscTag{
 		Vendor:  pdscTag.Vendor,
@@ -2234,6 +3243,7 @@ func (p *PacksInstallationType) downloadPdscFile(pdscTag xml.PdscTag, skipInstal
 			cacheTag.URL = releaseTag.URL[:i+1]
 		}
 	}
+	cacheTag.URL = utils.RemoveCredentials(cacheTag.URL)
 
 	p.lock.Lock()
 	defer p.lock.Unlock()
@@ -2280,7 +3290,9 @@ func (p *PacksInstallationType) loadPdscFile(pdscTag xml.PdscTag, insecureSkipVe
 		if runtime.GOOS == "windows" && strings.HasPrefix(sourceFilePath, "/") {
 			sourceFilePath = sourceFilePath[1:]
 		}
-		utils.UnsetReadOnly(pdscFilePath)
+		if err := p.stash(pdscFilePath); err != nil {
+			return err
+		}
 		defer utils.SetReadOnly(pdscFilePath)
 		if err = utils.CopyFile(sourceFilePath, pdscFilePath); err != nil {
 			log.Errorf("Could not copy pdsc %q: %s", sourceFilePath, err)
@@ -2289,7 +3301,12 @@ func (p *PacksInstallationType) loadPdscFile(pdscTag xml.PdscTag, insecureSkipVe
 		return nil
 	}
 
-	localFileName, err := utils.DownloadFile(pdscFileURL.String(), true, true, true, insecureSkipVerify, timeout)
+	// Keep the PDSC file downloaded before if it did not change since
+	validators := p.validatorsOf(pdscFilePath, pdscFileURL.String())
+	localFileName, err := utils.DownloadFileIfModified(pdscFileURL.String(), true, &validators, true, true, insecureSkipVerify, timeout)
+	if errors.Is(err, errs.ErrNotModified) {
+		return nil
+	}
 	defer os.Remove(localFileName)
 
 	if err != nil {
@@ -2298,10 +3315,14 @@ func (p *PacksInstallationType) loadPdscFile(pdscTag xml.PdscTag, insecureSkipVe
 		return err
 	}
 
-	utils.UnsetReadOnly(pdscFilePath)
-	os.Remove(pdscFilePath)
+	if err := p.stash(pdscFilePath); err != nil {
+		return err
+	}
 	err = utils.MoveFile(localFileName, pdscFilePath)
 	utils.SetReadOnly(pdscFilePath)
+	if err == nil {
+		p.setValidators(pdscFilePath, pdscFileURL.String(), validators)
+	}
 
 	return err
 }
@@ -5,6 +5,8 @@ package installer_test
 
 import (
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -526,6 +528,40 @@ func TestAddPack(t *testing.T) {
 		})
 	})
 
+	t.Run("test installing non-public pack via remote file with credentials", func(t *testing.T) {
+		localTestingDir := "test-add-non-public-remote-pack-with-credentials"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+		defer utils.ResetCredentials()
+
+		packContent, err := os.ReadFile(nonPublicRemotePack123)
+		assert.Nil(err)
+		server := httptest.NewTLSServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					if username, password, _ := r.BasicAuth(); username != "user" || password != "secret" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					_, _ = w.Write(packContent)
+				},
+			),
+		)
+		defer server.Close()
+
+		packPath := strings.Replace(server.URL, "https://", "https://user:secret@", 1) + "/" + filepath.Base(nonPublicRemotePack123)
+		assert.Nil(installer.AddPack(packPath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+
+		// The credentials are kept out of the pack root
+		for _, pidxPath := range []string{installer.Installation.LocalPidx.GetFileName(), installer.Installation.PublicCacheIndex} {
+			if contents, err := os.ReadFile(pidxPath); err == nil {
+				assert.NotContains(string(contents), "secret")
+			}
+		}
+	})
+
 	// Test that cpackget will attempt to retrieve the PDSC file of public packs and place it under .Web/
 	t.Run("test installing public pack retrieving pdsc file", func(t *testing.T) {
 		localTestingDir := "test-add-public-pack-retrieving-pdsc-file"
@@ -2542,7 +2578,7 @@ func TestAddPack(t *testing.T) {
 		checkPackIsInstalled(t, pack)
 
 		// Clean up for second test
-		_, err = installer.RemovePack(pack.PackID(), true, true)
+		_, err = installer.RemovePack(pack.PackID(), true, !Force, !Cascade, !WithDeps, true)
 		assert.Nil(err)
 
 		// Test with insecureSkipVerify = false (default)
@@ -0,0 +1,133 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	"github.com/stretchr/testify/assert"
+)
+
+func dependencyTags(packRoot string) []xml.PdscTag {
+	fileName := filepath.Join(packRoot, ".Local", installer.DependenciesIndexName)
+	if !utils.FileExists(fileName) {
+		return nil
+	}
+	pidx := xml.NewPidxXML(fileName, false)
+	_ = pidx.Read()
+	return pidx.ListPdscTags()
+}
+
+func TestAutoremove(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test autoremove without dependency packs", func(t *testing.T) {
+		localTestingDir := "test-autoremove-no-dependencies"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "TheVendor", "B", "1.0.0")
+		installFakePack(localTestingDir, "TheVendor", "A", "1.0.0", []string{"TheVendor", "B", "1.0.0"})
+
+		assert.Nil(installer.Autoremove(false, true))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "A", "1.0.0")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "B", "1.0.0")))
+		assert.False(utils.FileExists(filepath.Join(localTestingDir, ".Local", installer.DependenciesIndexName)))
+	})
+
+	t.Run("test autoremove removes orphaned dependency packs only", func(t *testing.T) {
+		localTestingDir := "test-autoremove-orphans"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "TheVendor", "A", "1.0.0", []string{"TheVendor", "B", "1.0.0"})
+		installFakePack(localTestingDir, "TheVendor", "B", "1.0.0", []string{"TheVendor", "C", "1.0.0"})
+		installFakePack(localTestingDir, "TheVendor", "C", "1.0.0")
+		installFakePack(localTestingDir, "TheVendor", "Orphan", "1.0.0", []string{"TheVendor", "OrphanDep", "1.0.0"})
+		installFakePack(localTestingDir, "TheVendor", "OrphanDep", "1.0.0")
+		installFakePack(localTestingDir, "TheVendor", "Explicit", "1.0.0")
+		markFakeDependency(localTestingDir, "TheVendor", "B", "1.0.0")
+		markFakeDependency(localTestingDir, "TheVendor", "C", "1.0.0")
+		markFakeDependency(localTestingDir, "TheVendor", "Orphan", "1.0.0")
+		markFakeDependency(localTestingDir, "TheVendor", "OrphanDep", "1.0.0")
+
+		assert.Nil(installer.Autoremove(false, true))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "A", "1.0.0")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "B", "1.0.0")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "C", "1.0.0")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Explicit", "1.0.0")))
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Orphan")))
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "OrphanDep")))
+		assert.Equal(2, len(dependencyTags(localTestingDir)))
+	})
+
+	t.Run("test autoremove after removing the pack requiring them", func(t *testing.T) {
+		localTestingDir := "test-autoremove-after-rm"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "TheVendor", "A", "1.0.0", []string{"TheVendor", "B", "1.0.0"})
+		installFakePack(localTestingDir, "TheVendor", "B", "1.0.0")
+		markFakeDependency(localTestingDir, "TheVendor", "B", "1.0.0")
+
+		_, err := installer.RemovePack("TheVendor.A.1.0.0", false, !Force, !Cascade, !WithDeps, true)
+		assert.Nil(err)
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "B", "1.0.0")))
+
+		assert.Nil(installer.Autoremove(false, true))
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "B")))
+		assert.Equal(0, len(dependencyTags(localTestingDir)))
+	})
+
+	t.Run("test removing a pack with its dependencies", func(t *testing.T) {
+		localTestingDir := "test-autoremove-rm-with-deps"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "TheVendor", "A", "1.0.0", []string{"TheVendor", "B", "1.0.0"}, []string{"TheVendor", "Shared", "1.0.0"})
+		installFakePack(localTestingDir, "TheVendor", "B", "1.0.0")
+		installFakePack(localTestingDir, "TheVendor", "Shared", "1.0.0")
+		installFakePack(localTestingDir, "TheVendor", "Other", "1.0.0", []string{"TheVendor", "Shared", "1.0.0"})
+		installFakePack(localTestingDir, "TheVendor", "Unrelated", "1.0.0")
+		markFakeDependency(localTestingDir, "TheVendor", "B", "1.0.0")
+		markFakeDependency(localTestingDir, "TheVendor", "Shared", "1.0.0")
+		markFakeDependency(localTestingDir, "TheVendor", "Unrelated", "1.0.0")
+
+		_, err := installer.RemovePack("TheVendor::A", false, !Force, !Cascade, WithDeps, true)
+		assert.Nil(err)
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "A")))
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "B")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Shared", "1.0.0")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Other", "1.0.0")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Unrelated", "1.0.0")))
+	})
+
+	t.Run("test explicitly adding a dependency pack", func(t *testing.T) {
+		localTestingDir := "test-autoremove-promote"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		assert.Nil(installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		assert.Equal(0, len(dependencyTags(localTestingDir)))
+
+		markFakeDependency(localTestingDir, "TheVendor", "PublicLocalPack", "1.2.3")
+		assert.Equal(1, len(dependencyTags(localTestingDir)))
+
+		assert.Nil(installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		assert.Equal(0, len(dependencyTags(localTestingDir)))
+
+		assert.Nil(installer.Autoremove(false, true))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "PublicLocalPack", "1.2.3")))
+	})
+}
@@ -0,0 +1,108 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleShowPackInfo() {
+	localTestingDir := "test-show-pack-info"
+	_ = installer.SetPackRoot(localTestingDir, CreatePackRoot)
+	installer.UnlockPackRoot()
+	_ = installer.ReadIndexFiles()
+	defer removePackRoot(localTestingDir)
+
+	pdscFilePath := strings.ReplaceAll(publicLocalPack123, ".1.2.3.pack", ".pdsc")
+	_ = utils.CopyFile(pdscFilePath, filepath.Join(installer.Installation.WebDir, "TheVendor.PublicLocalPack.pdsc"))
+	_ = installer.Installation.PublicIndexXML.AddPdsc(xml.PdscTag{
+		Vendor:      "TheVendor",
+		Name:        "PublicLocalPack",
+		Version:     "1.2.3",
+		URL:         "http://vendor.com/packs/",
+		Deprecated:  "2020-01-01",
+		Replacement: "TheVendor.OtherPack",
+	})
+	_ = installer.Installation.PublicIndexXML.Write()
+
+	_ = installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
+
+	log.SetOutput(os.Stdout)
+	defer log.SetOutput(io.Discard)
+	_ = installer.ShowPackInfo("TheVendor::PublicLocalPack")
+	// Output:
+	// I: TheVendor::PublicLocalPack (public)
+	// I:   Description file: test-show-pack-info/.Web/TheVendor.PublicLocalPack.pdsc
+	// I:   URL: http://vendor.com/packs/
+	// I:   Deprecated: 2020-01-01, replaced by TheVendor.OtherPack
+	// I: Releases:
+	// I:   1.2.3 (2016-09-15) http://vendor.com/packs/TheVendor.PublicLocalPack.1.2.3.pack (installed)
+	// I:   1.2.2 http://vendor.com/packs/TheVendor.PublicLocalPack.1.2.2.pack
+	// I: Requirements:
+	// I:   (no requirements)
+	// I: Installed versions:
+	// I:   1.2.3
+	// I: Cached pack files:
+	// I:   test-show-pack-info/.Download/TheVendor.PublicLocalPack.1.2.3.pack
+}
+
+func TestShowPackInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("test showing info of a bad pack reference", func(t *testing.T) {
+		localTestingDir := "test-show-pack-info-bad-reference"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		err := installer.ShowPackInfo("not-a-pack")
+		assert.Equal(errs.ErrBadPackName, err)
+	})
+
+	t.Run("test showing info of a pack without pdsc file", func(t *testing.T) {
+		localTestingDir := "test-show-pack-info-no-pdsc"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		err := installer.ShowPackInfo("TheVendor.UnknownPack")
+		assert.True(errors.Is(err, errs.ErrPdscFileNotFound))
+	})
+
+	t.Run("test showing info of a non-public pack from its cached pdsc", func(t *testing.T) {
+		localTestingDir := "test-show-pack-info-cached-pdsc"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		pdscFilePath := strings.ReplaceAll(publicLocalPack123, ".1.2.3.pack", ".pdsc")
+		assert.Nil(utils.CopyFile(pdscFilePath, filepath.Join(installer.Installation.DownloadDir, "TheVendor.PublicLocalPack.1.2.2.pdsc")))
+		assert.Nil(utils.CopyFile(pdscFilePath, filepath.Join(installer.Installation.DownloadDir, "TheVendor.PublicLocalPack.1.2.3.pdsc")))
+
+		var buf strings.Builder
+		log.SetOutput(&buf)
+		defer log.SetOutput(io.Discard)
+		assert.Nil(installer.ShowPackInfo("TheVendor.PublicLocalPack"))
+
+		output := buf.String()
+		assert.Contains(output, "TheVendor.PublicLocalPack.1.2.3.pdsc")
+		assert.NotContains(output, "(public)")
+		assert.Contains(output, "(not installed)")
+		assert.Contains(output, "(no pack files cached)")
+	})
+}
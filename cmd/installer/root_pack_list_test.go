@@ -5,6 +5,7 @@ package installer_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"strings"
 	"testing"
 
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
 	"github.com/open-cmsis-pack/cpackget/cmd/installer"
 	"github.com/open-cmsis-pack/cpackget/cmd/utils"
 	"github.com/open-cmsis-pack/cpackget/cmd/xml"
@@ -117,7 +119,7 @@ func ExampleListInstalledPacks_list() {
 
 	_ = installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
 	_ = installer.AddPack(publicLocalPack124, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
-	_, _ = installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, true)
+	_, _ = installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, !Force, !Cascade, !WithDeps, true)
 
 	log.SetOutput(os.Stdout)
 	defer log.SetOutput(io.Discard)
@@ -129,6 +131,40 @@ func ExampleListInstalledPacks_list() {
 	// I: TheVendor::PublicLocalPack@1.2.5
 }
 
+func ExampleListInstalledPacks_listJSON() {
+	localTestingDir := "test-list-packs-json"
+	_ = installer.SetPackRoot(localTestingDir, CreatePackRoot)
+	installer.UnlockPackRoot()
+	_ = installer.ReadIndexFiles()
+	defer removePackRoot(localTestingDir)
+
+	pdscFilePath := strings.ReplaceAll(publicLocalPack123, ".1.2.3.pack", ".pdsc")
+	_ = utils.CopyFile(pdscFilePath, filepath.Join(installer.Installation.WebDir, "TheVendor.PublicLocalPack.pdsc"))
+	_ = installer.Installation.PublicIndexXML.AddPdsc(xml.PdscTag{
+		Vendor:  "TheVendor",
+		Name:    "PublicLocalPack",
+		Version: "1.2.4",
+	})
+	_ = installer.Installation.PublicIndexXML.Write()
+
+	_ = installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
+
+	_ = utils.SetOutputFormat(utils.OutputFormatJSON)
+	defer func() { _ = utils.SetOutputFormat("") }()
+	utils.SetOutputWriter(os.Stdout)
+	_ = installer.ListInstalledPacks(!ListCached, !ListPublic, !ListUpdates, !ListDeprecated, !ListRequirements, true, ListFilter)
+	// Output:
+	// [
+	//   {
+	//     "vendor": "TheVendor",
+	//     "name": "PublicLocalPack",
+	//     "version": "1.2.3",
+	//     "state": "installed",
+	//     "pdscPath": "test-list-packs-json/TheVendor/PublicLocalPack/1.2.3/TheVendor.PublicLocalPack.pdsc"
+	//   }
+	// ]
+}
+
 func ExampleListInstalledPacks_listCached() {
 	localTestingDir := "test-list-cached-packs"
 	_ = installer.SetPackRoot(localTestingDir, CreatePackRoot)
@@ -155,7 +191,7 @@ func ExampleListInstalledPacks_listCached() {
 	})
 	_ = installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
 	_ = installer.AddPack(publicLocalPack124, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
-	_, _ = installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, true)
+	_, _ = installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, !Force, !Cascade, !WithDeps, true)
 
 	log.SetOutput(os.Stdout)
 	defer log.SetOutput(io.Discard)
@@ -195,7 +231,7 @@ func TestListInstalledPacks(t *testing.T) {
 		}))
 		assert.Nil(installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
 		assert.Nil(installer.AddPack(publicLocalPack124, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
-		_, err := installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, true)
+		_, err := installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, !Force, !Cascade, !WithDeps, true)
 		assert.Nil(err)
 
 		// Install a pack via PDSC file
@@ -450,7 +486,7 @@ func ExampleListInstalledPacks_filter() {
 
 	_ = installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
 	_ = installer.AddPack(publicLocalPack124, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
-	_, _ = installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, true)
+	_, _ = installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, !Force, !Cascade, !WithDeps, true)
 
 	log.SetOutput(os.Stdout)
 	defer log.SetOutput(io.Discard)
@@ -534,7 +570,7 @@ func ExampleListInstalledPacks_filterInvalidChars() {
 
 	_ = installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
 	_ = installer.AddPack(publicLocalPack124, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
-	_, _ = installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, true)
+	_, _ = installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, !Force, !Cascade, !WithDeps, true)
 
 	log.SetOutput(os.Stdout)
 	defer log.SetOutput(io.Discard)
@@ -569,7 +605,7 @@ func ExampleListInstalledPacks_filteradditionalMessages() {
 	})
 	_ = installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
 	_ = installer.AddPack(publicLocalPack124, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
-	_, _ = installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, true)
+	_, _ = installer.RemovePack("TheVendor.PublicLocalPack.1.2.3", false /*no purge*/, !Force, !Cascade, !WithDeps, true)
 
 	log.SetOutput(os.Stdout)
 	defer log.SetOutput(io.Discard)
@@ -577,3 +613,191 @@ func ExampleListInstalledPacks_filteradditionalMessages() {
 	// Output:
 	// I: Listing cached packs, filtering by "(installed)"
 }
+
+func ExampleListDependents() {
+	localTestingDir := "test-list-dependents"
+	_ = installer.SetPackRoot(localTestingDir, CreatePackRoot)
+	installer.UnlockPackRoot()
+	_ = installer.ReadIndexFiles()
+	defer removePackRoot(localTestingDir)
+
+	installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+	installFakePack(localTestingDir, "Vendor", "A", "1.0.0", []string{"ARM", "CMSIS", "5.6.0"})
+	installFakePack(localTestingDir, "Vendor", "B", "2.0.0", []string{"Vendor", "A", "1.0.0:1.9.9"})
+	installFakePack(localTestingDir, "Vendor", "C", "1.0.0", []string{"ARM", "CMSIS", "6.0.0"})
+
+	log.SetOutput(os.Stdout)
+	defer log.SetOutput(io.Discard)
+	_ = installer.ListDependents("ARM::CMSIS", true)
+	// Output:
+	// I: Listing packs depending on ARM::CMSIS
+	// I: Vendor::A@1.0.0 requires ARM::CMSIS@>=5.6.0 (satisfied)
+	// I: Vendor::C@1.0.0 requires ARM::CMSIS@>=6.0.0 (unsatisfied)
+	// I: Vendor::B@2.0.0 requires Vendor::A@1.0.0:1.9.9 (satisfied), via Vendor::A
+}
+
+func TestListDependents(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("test listing dependents of a pack nobody requires", func(t *testing.T) {
+		localTestingDir := "test-list-dependents-none"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		defer log.SetOutput(io.Discard)
+		assert.Nil(installer.ListDependents("ARM.CMSIS", true))
+		assert.Contains(buf.String(), "(no installed pack depends on ARM::CMSIS)")
+	})
+
+	t.Run("test listing dependents against a specific version", func(t *testing.T) {
+		localTestingDir := "test-list-dependents-version"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		installFakePack(localTestingDir, "Vendor", "A", "1.0.0", []string{"ARM", "CMSIS", "5.6.0"})
+		installFakePack(localTestingDir, "Vendor", "C", "1.0.0", []string{"ARM", "CMSIS", "5.0.0:5.8.0"})
+
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		defer log.SetOutput(io.Discard)
+		assert.Nil(installer.ListDependents("ARM::CMSIS@5.7.0", true))
+		assert.Contains(buf.String(), "Vendor::A@1.0.0 requires ARM::CMSIS@>=5.6.0 (satisfied)")
+		assert.Contains(buf.String(), "Vendor::C@1.0.0 requires ARM::CMSIS@5.0.0:5.8.0 (satisfied)")
+
+		buf.Reset()
+		assert.Nil(installer.ListDependents("ARM::CMSIS@5.5.0", true))
+		assert.Contains(buf.String(), "Vendor::A@1.0.0 requires ARM::CMSIS@>=5.6.0 (unsatisfied)")
+		assert.Contains(buf.String(), "Vendor::C@1.0.0 requires ARM::CMSIS@5.0.0:5.8.0 (satisfied)")
+	})
+
+	t.Run("test listing dependents as json", func(t *testing.T) {
+		localTestingDir := "test-list-dependents-json"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		installFakePack(localTestingDir, "Vendor", "A", "1.0.0", []string{"ARM", "CMSIS", "5.6.0"})
+		installFakePack(localTestingDir, "Vendor", "B", "2.0.0", []string{"Vendor", "A", "1.0.0"})
+
+		assert.Nil(utils.SetOutputFormat(utils.OutputFormatJSON))
+		defer func() { _ = utils.SetOutputFormat("") }()
+		var buf bytes.Buffer
+		utils.SetOutputWriter(&buf)
+		defer utils.SetOutputWriter(os.Stdout)
+		assert.Nil(installer.ListDependents("ARM::CMSIS", true))
+
+		records := []installer.PackRecord{}
+		assert.Nil(json.Unmarshal(buf.Bytes(), &records))
+		assert.Len(records, 2)
+		assert.Equal("A", records[0].Name)
+		assert.Empty(records[0].Via)
+		assert.Equal(installer.PackStateSatisfied, records[0].Dependencies[0].State)
+		assert.Equal("B", records[1].Name)
+		assert.Equal([]string{"Vendor::A"}, records[1].Via)
+	})
+
+	t.Run("test listing dependents of a bad pack reference", func(t *testing.T) {
+		assert.Equal(errs.ErrBadPackName, installer.ListDependents("not-a-pack", true))
+	})
+}
+
+func ExampleListRequirementsGraph() {
+	localTestingDir := "test-list-requirements-graph"
+	_ = installer.SetPackRoot(localTestingDir, CreatePackRoot)
+	installer.UnlockPackRoot()
+	_ = installer.ReadIndexFiles()
+	defer removePackRoot(localTestingDir)
+
+	installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+	installFakePack(localTestingDir, "Vendor", "A", "1.0.0", []string{"ARM", "CMSIS", "5.6.0"}, []string{"ARM", "CMSIS-Driver", "2.0.0"})
+	installFakePack(localTestingDir, "Vendor", "B", "2.0.0", []string{"Vendor", "A", "1.0.0:1.9.9"})
+
+	utils.SetOutputWriter(os.Stdout)
+	_ = installer.ListRequirementsGraph(installer.GraphFormatDOT, false, true)
+	// Output:
+	// digraph requirements {
+	//   rankdir=LR;
+	//   node [shape=box];
+	//   "ARM::CMSIS-Driver" [style=dashed, color=red];
+	//   "ARM::CMSIS@5.9.0";
+	//   "Vendor::A@1.0.0";
+	//   "Vendor::B@2.0.0";
+	//   "Vendor::A@1.0.0" -> "ARM::CMSIS-Driver" [label=">=2.0.0", style=dashed, color=red];
+	//   "Vendor::A@1.0.0" -> "ARM::CMSIS@5.9.0" [label=">=5.6.0"];
+	//   "Vendor::B@2.0.0" -> "Vendor::A@1.0.0" [label="1.0.0:1.9.9"];
+	// }
+}
+
+func TestListRequirementsGraph(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("test listing the requirements graph as json", func(t *testing.T) {
+		localTestingDir := "test-list-requirements-graph-json"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		installFakePack(localTestingDir, "Vendor", "A", "1.0.0", []string{"ARM", "CMSIS", "6.0.0"}, []string{"Vendor", "Missing", "1.0.0"})
+
+		var buf bytes.Buffer
+		utils.SetOutputWriter(&buf)
+		defer utils.SetOutputWriter(os.Stdout)
+		assert.Nil(installer.ListRequirementsGraph("JSON", false, true))
+
+		graph := installer.RequirementsGraph{}
+		assert.Nil(json.Unmarshal(buf.Bytes(), &graph))
+		assert.Equal([]installer.GraphNode{
+			{ID: "ARM::CMSIS", Vendor: "ARM", Name: "CMSIS", State: installer.PackStateMissing},
+			{ID: "ARM::CMSIS@5.9.0", Vendor: "ARM", Name: "CMSIS", Version: "5.9.0", State: installer.PackStateInstalled},
+			{ID: "Vendor::A@1.0.0", Vendor: "Vendor", Name: "A", Version: "1.0.0", State: installer.PackStateInstalled},
+			{ID: "Vendor::Missing", Vendor: "Vendor", Name: "Missing", State: installer.PackStateMissing},
+		}, graph.Nodes)
+		assert.Equal([]installer.GraphEdge{
+			{From: "Vendor::A@1.0.0", To: "ARM::CMSIS", Version: "6.0.0:_", State: installer.PackStateUnsatisfied},
+			{From: "Vendor::A@1.0.0", To: "Vendor::Missing", Version: "1.0.0:_", State: installer.PackStateMissing},
+		}, graph.Edges)
+	})
+
+	t.Run("test listing the requirements graph including missing packs", func(t *testing.T) {
+		localTestingDir := "test-list-requirements-graph-missing"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "Vendor", "A", "1.0.0", []string{"Vendor", "Middle", "1.0.0"})
+
+		// Only the pdsc file of Vendor::Middle is available, it is not installed
+		installFakePack(localTestingDir, "Vendor", "Middle", "1.1.0", []string{"ARM", "CMSIS", "5.6.0"})
+		removePackRoot(filepath.Join(localTestingDir, "Vendor", "Middle"))
+
+		var buf bytes.Buffer
+		utils.SetOutputWriter(&buf)
+		defer utils.SetOutputWriter(os.Stdout)
+		assert.Nil(installer.ListRequirementsGraph(installer.GraphFormatJSON, true, true))
+
+		graph := installer.RequirementsGraph{}
+		assert.Nil(json.Unmarshal(buf.Bytes(), &graph))
+		assert.Equal([]installer.GraphEdge{
+			{From: "Vendor::A@1.0.0", To: "Vendor::Middle@1.1.0", Version: "1.0.0:_", State: installer.PackStateMissing},
+			{From: "Vendor::Middle@1.1.0", To: "ARM::CMSIS", Version: "5.6.0:_", State: installer.PackStateMissing},
+		}, graph.Edges)
+	})
+
+	t.Run("test listing the requirements graph in an unknown format", func(t *testing.T) {
+		assert.ErrorIs(installer.ListRequirementsGraph("svg", false, true), errs.ErrUnknownGraphFormat)
+	})
+}
@@ -0,0 +1,148 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	"github.com/stretchr/testify/assert"
+	"go.yaml.in/yaml/v3"
+)
+
+// packDigest returns the hex encoded SHA-256 digest of a pack file
+func packDigest(packPath string) string {
+	contents, _ := os.ReadFile(packPath)
+	digest := sha256.Sum256(contents)
+	return hex.EncodeToString(digest[:])
+}
+
+// writeLockFile writes a lock file with the given packs and returns its path
+func writeLockFile(dir string, packs ...installer.LockedPack) string {
+	contents, _ := yaml.Marshal(installer.LockFile{Packs: packs})
+	lockFilePath := filepath.Join(dir, installer.LockFileName)
+	_ = os.WriteFile(lockFilePath, contents, 0600)
+	return lockFilePath
+}
+
+func TestLockPacks(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test locking installed packs", func(t *testing.T) {
+		localTestingDir := "test-lock-packs"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		installer.Installation.WebDir = filepath.Join(testDir, "public_index")
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		assert.Nil(installer.Installation.PublicIndexXML.AddPdsc(xml.PdscTag{Vendor: "TheVendor", Name: "PublicLocalPack", Version: "1.2.3"}))
+		assert.Nil(installer.Installation.PublicIndexXML.Write())
+		assert.Nil(installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+
+		lockFilePath := filepath.Join(t.TempDir(), installer.LockFileName)
+		assert.Nil(installer.LockPacks(lockFilePath, !InsecureSkipVerify, true))
+
+		lockFile, err := installer.ReadLockFile(lockFilePath)
+		assert.Nil(err)
+		assert.Len(lockFile.Packs, 1)
+		locked := lockFile.Packs[0]
+		assert.Equal("TheVendor::PublicLocalPack@1.2.3", locked.YamlPackID())
+		assert.True(strings.HasSuffix(locked.URL, "TheVendor.PublicLocalPack.1.2.3.pack"))
+		assert.Equal(packDigest(publicLocalPack123), locked.SHA256)
+		assert.False(locked.Dependency)
+	})
+
+	t.Run("test locking a pack missing from the cache", func(t *testing.T) {
+		localTestingDir := "test-lock-packs-not-cached"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "TheVendor", "Fake", "1.0.0")
+
+		lockFilePath := filepath.Join(t.TempDir(), installer.LockFileName)
+		assert.Equal(errs.ErrAlreadyLogged, installer.LockPacks(lockFilePath, !InsecureSkipVerify, true))
+		assert.False(utils.FileExists(lockFilePath))
+	})
+}
+
+func TestAddLockedPacks(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test adding locked packs", func(t *testing.T) {
+		localTestingDir := "test-add-locked-packs"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		packContent, err := os.ReadFile(publicLocalPack123)
+		assert.Nil(err)
+		server := NewServer()
+		server.AddRoute(filepath.Base(publicLocalPack123), packContent)
+
+		lockFilePath := writeLockFile(t.TempDir(), installer.LockedPack{
+			Vendor:     "TheVendor",
+			Name:       "PublicLocalPack",
+			Version:    "1.2.3",
+			URL:        server.URL() + filepath.Base(publicLocalPack123),
+			SHA256:     packDigest(publicLocalPack123),
+			Dependency: true,
+		})
+
+		assert.Nil(installer.AddLockedPacks(lockFilePath, !CheckEula, !InsecureSkipVerify, true, Timeout))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "PublicLocalPack", "1.2.3")))
+		assert.Len(dependencyTags(localTestingDir), 1)
+
+		// Installed packs are left alone
+		assert.Nil(installer.AddLockedPacks(lockFilePath, !CheckEula, !InsecureSkipVerify, true, Timeout))
+	})
+
+	t.Run("test adding locked packs whose pack file changed", func(t *testing.T) {
+		localTestingDir := "test-add-locked-packs-mismatch"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		packContent, err := os.ReadFile(publicLocalPack123)
+		assert.Nil(err)
+		server := NewServer()
+		server.AddRoute(filepath.Base(publicLocalPack123), packContent)
+
+		lockFilePath := writeLockFile(t.TempDir(), installer.LockedPack{
+			Vendor:  "TheVendor",
+			Name:    "PublicLocalPack",
+			Version: "1.2.3",
+			URL:     server.URL() + filepath.Base(publicLocalPack123),
+			SHA256:  strings.Repeat("0", 64),
+		})
+
+		err = installer.AddLockedPacks(lockFilePath, !CheckEula, !InsecureSkipVerify, true, Timeout)
+		assert.ErrorIs(err, errs.ErrIntegrityCheckFailed)
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "PublicLocalPack")))
+		assert.False(utils.FileExists(filepath.Join(installer.Installation.DownloadDir, filepath.Base(publicLocalPack123))))
+	})
+
+	t.Run("test adding locked packs from a missing lock file", func(t *testing.T) {
+		localTestingDir := "test-add-locked-packs-no-lock-file"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		assert.NotNil(installer.AddLockedPacks(filepath.Join(t.TempDir(), installer.LockFileName), !CheckEula, !InsecureSkipVerify, true, Timeout))
+	})
+}
@@ -0,0 +1,271 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// createFakePackFile writes a pack file holding only a pdsc file with the given requirements
+func createFakePackFile(dir, vendor, name, version string, requirements ...[]string) string {
+	packages := ""
+	for _, req := range requirements {
+		packages += fmt.Sprintf(`<package vendor="%s" name="%s" version="%s"/>`, req[0], req[1], req[2])
+	}
+	pdsc := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package>
+  <vendor>%s</vendor>
+  <name>%s</name>
+  <url>https://vendor.com/</url>
+  <license>LICENSE.txt</license>
+  <releases>
+    <release version="%s"/>
+  </releases>
+  <requirements>
+    <packages>%s</packages>
+  </requirements>
+</package>`, vendor, name, version, packages)
+
+	packFilePath := filepath.Join(dir, vendor+"."+name+"."+version+utils.PackExtension)
+	packFile, _ := os.Create(packFilePath)
+	defer packFile.Close()
+	zipWriter := zip.NewWriter(packFile)
+	pdscFile, _ := zipWriter.Create(vendor + "." + name + utils.PdscExtension)
+	_, _ = pdscFile.Write([]byte(pdsc))
+	_ = zipWriter.Close()
+	return packFilePath
+}
+
+// stepTargets returns "action target" for each of the steps
+func stepTargets(steps []*installer.PlanStep) []string {
+	targets := []string{}
+	for _, step := range steps {
+		targets = append(targets, step.Action+" "+step.Target)
+	}
+	return targets
+}
+
+func TestPlanAddPack(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test planning to add a local pack file", func(t *testing.T) {
+		localTestingDir := "test-plan-add-pack"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		plan := installer.NewPlan()
+		assert.Nil(installer.PlanAddPack(plan, publicLocalPack123, CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true))
+		assert.Equal([]string{"add TheVendor::PublicLocalPack@1.2.3"}, stepTargets(plan.Steps))
+		assert.Equal([]string{"extract " + filepath.Join(localTestingDir, "TheVendor", "PublicLocalPack", "1.2.3")}, stepTargets(plan.Steps[0].Steps))
+
+		// Nothing got installed nor cached
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor")))
+		files, _ := os.ReadDir(filepath.Join(localTestingDir, ".Download"))
+		assert.Equal(0, len(files))
+	})
+
+	t.Run("test planning to add an installed pack", func(t *testing.T) {
+		localTestingDir := "test-plan-add-installed-pack"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		assert.Nil(installer.AddPack(publicLocalPack123, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+
+		plan := installer.NewPlan()
+		assert.Nil(installer.PlanAddPack(plan, publicLocalPack123, CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true))
+		assert.Equal(1, len(plan.Steps))
+		assert.Equal(installer.PlanSkip, plan.Steps[0].Action)
+		assert.Equal("already installed", plan.Steps[0].Details)
+
+		plan = installer.NewPlan()
+		assert.Nil(installer.PlanAddPack(plan, publicLocalPack123, CheckEula, !ExtractEula, ForceReinstall, !NoRequirements, !InsecureSkipVerify, true))
+		assert.Equal(installer.PlanAdd, plan.Steps[0].Action)
+		assert.Equal("reinstall", plan.Steps[0].Details)
+	})
+
+	t.Run("test planning to add a pack with license and requirements", func(t *testing.T) {
+		localTestingDir := "test-plan-add-pack-requirements"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		packFilePath := createFakePackFile(t.TempDir(), "TheVendor", "Dependent", "1.0.0", []string{"ARM", "CMSIS", "5.6.0"})
+
+		plan := installer.NewPlan()
+		assert.Nil(installer.PlanAddPack(plan, packFilePath, CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true))
+		assert.Equal([]string{"add TheVendor::Dependent@1.0.0"}, stepTargets(plan.Steps))
+		assert.Equal([]string{
+			"license LICENSE.txt",
+			"extract " + filepath.Join(localTestingDir, "TheVendor", "Dependent", "1.0.0"),
+			"require ARM::CMSIS@>=5.6.0",
+		}, stepTargets(plan.Steps[0].Steps))
+		assert.Equal("prompt for agreement", plan.Steps[0].Steps[0].Details)
+		assert.Equal("installed: 5.9.0", plan.Steps[0].Steps[2].Details)
+
+		plan = installer.NewPlan()
+		assert.Nil(installer.PlanAddPack(plan, packFilePath, !CheckEula, !ExtractEula, !ForceReinstall, NoRequirements, !InsecureSkipVerify, true))
+		assert.Equal([]string{
+			"license LICENSE.txt",
+			"extract " + filepath.Join(localTestingDir, "TheVendor", "Dependent", "1.0.0"),
+		}, stepTargets(plan.Steps[0].Steps))
+		assert.Equal("agreed", plan.Steps[0].Steps[0].Details)
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor")))
+	})
+
+	t.Run("test logging a plan", func(t *testing.T) {
+		localTestingDir := "test-plan-log"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		defer log.SetOutput(io.Discard)
+
+		installer.NewPlan().Log()
+		assert.Contains(buf.String(), "Dry run: nothing to do")
+
+		plan := installer.NewPlan()
+		assert.Nil(installer.PlanAddPack(plan, publicLocalPack123, CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true))
+		plan.Log()
+		assert.Contains(buf.String(), "Planned actions:")
+		assert.Contains(buf.String(), "  - add TheVendor::PublicLocalPack@1.2.3")
+		assert.Contains(buf.String(), "    - extract ")
+	})
+}
+
+func TestPlanUpdatePack(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test planning to update all packs stops at the first failure", func(t *testing.T) {
+		localTestingDir := "test-plan-update-all-packs-failure"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		// The latest version of the first pack is cached, but cannot be read
+		for _, name := range []string{"First", "Second"} {
+			installFakePack(localTestingDir, "TheVendor", name, "1.0.0")
+			assert.Nil(os.Remove(filepath.Join(installer.Installation.LocalDir, "TheVendor."+name+utils.PdscExtension)))
+		}
+		addFakePublicPack("TheVendor", "First", []string{"2.0.0", "1.0.0"})
+		addFakePublicPack("TheVendor", "Second", []string{"2.0.0", "1.0.0"})
+		assert.Nil(os.WriteFile(filepath.Join(installer.Installation.DownloadDir, "TheVendor.First.2.0.0.pack"), []byte("not a zip file"), 0600))
+
+		plan := installer.NewPlan()
+		assert.NotNil(installer.PlanUpdatePack(plan, "", !CheckEula, !NoRequirements, !InsecureSkipVerify, true))
+		assert.NotContains(stepTargets(plan.Steps), "add TheVendor::Second@2.0.0")
+	})
+}
+
+func TestPlanRemovePack(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test planning to remove a pack not installed", func(t *testing.T) {
+		localTestingDir := "test-plan-remove-not-installed"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		plan := installer.NewPlan()
+		assert.Equal(errs.ErrPackNotInstalled, installer.PlanRemovePack(plan, "TheVendor.PackName.1.2.3", false, !Force, !Cascade, !WithDeps))
+	})
+
+	t.Run("test planning to remove a pack required by other installed packs", func(t *testing.T) {
+		localTestingDir := "test-plan-remove-with-dependents"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		installFakePack(localTestingDir, "TheVendor", "A", "1.0.0", []string{"ARM", "CMSIS", "5.6.0"})
+		installFakePack(localTestingDir, "TheVendor", "B", "1.0.0", []string{"TheVendor", "A", "1.0.0"})
+
+		plan := installer.NewPlan()
+		assert.Equal(errs.ErrPackHasDependents, installer.PlanRemovePack(plan, "ARM::CMSIS", false, !Force, !Cascade, !WithDeps))
+
+		plan = installer.NewPlan()
+		assert.Nil(installer.PlanRemovePack(plan, "ARM::CMSIS", false, Force, !Cascade, !WithDeps))
+		assert.Equal([]string{"remove ARM::CMSIS@5.9.0"}, stepTargets(plan.Steps))
+
+		plan = installer.NewPlan()
+		assert.Nil(installer.PlanRemovePack(plan, "ARM::CMSIS", false, !Force, Cascade, !WithDeps))
+		assert.Equal([]string{
+			"remove TheVendor::B@1.0.0",
+			"remove TheVendor::A@1.0.0",
+			"remove ARM::CMSIS@5.9.0",
+		}, stepTargets(plan.Steps))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "ARM", "CMSIS", "5.9.0")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "A", "1.0.0")))
+	})
+
+	t.Run("test planning to remove a pack with its dependencies", func(t *testing.T) {
+		localTestingDir := "test-plan-remove-with-deps"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "TheVendor", "A", "1.0.0", []string{"TheVendor", "B", "1.0.0"}, []string{"TheVendor", "Shared", "1.0.0"})
+		installFakePack(localTestingDir, "TheVendor", "B", "1.0.0")
+		installFakePack(localTestingDir, "TheVendor", "Shared", "1.0.0")
+		installFakePack(localTestingDir, "TheVendor", "Other", "1.0.0", []string{"TheVendor", "Shared", "1.0.0"})
+		markFakeDependency(localTestingDir, "TheVendor", "B", "1.0.0")
+		markFakeDependency(localTestingDir, "TheVendor", "Shared", "1.0.0")
+
+		plan := installer.NewPlan()
+		assert.Nil(installer.PlanRemovePack(plan, "TheVendor::A", false, !Force, !Cascade, WithDeps))
+		assert.Equal([]string{
+			"remove TheVendor::A@1.0.0",
+			"remove TheVendor::B@1.0.0",
+		}, stepTargets(plan.Steps))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "A", "1.0.0")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "B", "1.0.0")))
+	})
+
+	t.Run("test planning to purge a pack", func(t *testing.T) {
+		localTestingDir := "test-plan-remove-purge"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "TheVendor", "A", "1.0.0")
+		cachedPackFile := filepath.Join(localTestingDir, ".Download", "TheVendor.A.1.0.0.pack")
+		assert.Nil(os.WriteFile(cachedPackFile, []byte(""), 0600))
+
+		plan := installer.NewPlan()
+		assert.Nil(installer.PlanRemovePack(plan, "TheVendor::A@1.0.0", true, !Force, !Cascade, !WithDeps))
+		assert.Equal([]string{
+			"remove TheVendor::A@1.0.0",
+			"purge " + cachedPackFile,
+		}, stepTargets(plan.Steps))
+		assert.True(utils.FileExists(cachedPackFile))
+	})
+}
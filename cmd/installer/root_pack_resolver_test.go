@@ -0,0 +1,162 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// addFakePublicPack writes the pdsc file of a public pack with the given releases, most recent first,
+// to ".Web/" and lists it in the public index. Requirements are in [Vendor, Name, Version] format.
+func addFakePublicPack(vendor, name string, versions []string, requirements ...[]string) {
+	releases := ""
+	for _, version := range versions {
+		releases += fmt.Sprintf(`<release version="%s"/>`, version)
+	}
+	packages := ""
+	for _, req := range requirements {
+		packages += fmt.Sprintf(`<package vendor="%s" name="%s" version="%s"/>`, req[0], req[1], req[2])
+	}
+	pdsc := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package>
+  <vendor>%s</vendor>
+  <name>%s</name>
+  <url>https://vendor.com/</url>
+  <releases>%s</releases>
+  <requirements>
+    <packages>%s</packages>
+  </requirements>
+</package>`, vendor, name, releases, packages)
+	_ = os.WriteFile(filepath.Join(installer.Installation.WebDir, vendor+"."+name+utils.PdscExtension), []byte(pdsc), 0600)
+
+	utils.UnsetReadOnly(installer.Installation.PublicIndex)
+	_ = installer.Installation.PublicIndexXML.AddPdsc(xml.PdscTag{
+		URL:     "https://vendor.com/",
+		Vendor:  vendor,
+		Name:    name,
+		Version: versions[0],
+	})
+	_ = installer.Installation.PublicIndexXML.Write()
+}
+
+func TestResolveRequirements(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test adding a pack whose requirements conflict", func(t *testing.T) {
+		localTestingDir := "test-resolve-conflict"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		installFakePack(localTestingDir, "TheVendor", "Legacy", "1.0.0", []string{"ARM", "CMSIS", "5.6.0:5.9.0"})
+		installFakePack(localTestingDir, "TheVendor", "Modern", "1.0.0", []string{"ARM", "CMSIS", "6.0.0"})
+		packFilePath := createFakePackFile(t.TempDir(), "TheVendor", "Root", "1.0.0",
+			[]string{"TheVendor", "Legacy", "1.0.0"}, []string{"TheVendor", "Modern", "1.0.0"})
+
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		defer log.SetOutput(io.Discard)
+
+		err := installer.AddPack(packFilePath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
+		assert.ErrorIs(err, errs.ErrDependencyConflict)
+		assert.Contains(err.Error(), "ARM::CMSIS")
+		assert.Contains(buf.String(), "No version of ARM::CMSIS satisfies all of its requirements")
+		assert.Contains(buf.String(), "ARM::CMSIS@5.6.0:5.9.0 required by TheVendor::Root@1.0.0 -> TheVendor::Legacy@1.0.0")
+		assert.Contains(buf.String(), "ARM::CMSIS@>=6.0.0 required by TheVendor::Root@1.0.0 -> TheVendor::Modern@1.0.0")
+		assert.Contains(buf.String(), "available versions: 5.9.0")
+
+		// Nothing gets installed when the requirements conflict
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Root")))
+
+		// Skipping the requirements skips the resolution as well
+		assert.Nil(installer.AddPack(packFilePath, !CheckEula, !ExtractEula, !ForceReinstall, NoRequirements, !InsecureSkipVerify, true, Timeout))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Root", "1.0.0")))
+	})
+
+	t.Run("test adding a pack whose requirements are installed", func(t *testing.T) {
+		localTestingDir := "test-resolve-installed"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		installFakePack(localTestingDir, "TheVendor", "Legacy", "1.0.0", []string{"ARM", "CMSIS", "5.6.0:5.9.0"})
+		packFilePath := createFakePackFile(t.TempDir(), "TheVendor", "Root", "1.0.0",
+			[]string{"TheVendor", "Legacy", "1.0.0"}, []string{"ARM", "CMSIS", "5.0.0"})
+
+		assert.Nil(installer.AddPack(packFilePath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Root", "1.0.0")))
+	})
+
+	t.Run("test planning the installation of requirements in topological order", func(t *testing.T) {
+		localTestingDir := "test-resolve-order"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		addFakePublicPack("ARM", "CMSIS", []string{"6.0.0", "5.9.0", "5.8.0"})
+		addFakePublicPack("TheVendor", "Middle", []string{"1.0.0"}, []string{"ARM", "CMSIS", "5.6.0:5.9.0"})
+		packFilePath := createFakePackFile(t.TempDir(), "TheVendor", "Root", "1.0.0",
+			[]string{"TheVendor", "Middle", "1.0.0"}, []string{"ARM", "CMSIS", "5.0.0"})
+
+		plan := installer.NewPlan()
+		assert.Nil(installer.PlanAddPack(plan, packFilePath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true))
+		assert.Equal([]string{"add TheVendor::Root@1.0.0"}, stepTargets(plan.Steps))
+
+		// The latest CMSIS release would not satisfy the range required by TheVendor::Middle
+		rootSteps := plan.Steps[0].Steps
+		assert.Equal([]string{
+			"license LICENSE.txt",
+			"extract " + filepath.Join(localTestingDir, "TheVendor", "Root", "1.0.0"),
+			"require TheVendor::Middle@>=1.0.0",
+			"require ARM::CMSIS@>=5.0.0",
+			"add ARM::CMSIS@5.9.0",
+			"add TheVendor::Middle@1.0.0",
+		}, stepTargets(rootSteps))
+		assert.Equal("resolved: 1.0.0", rootSteps[2].Details)
+		assert.Equal("resolved: 5.9.0", rootSteps[3].Details)
+		assert.Equal("dependency", rootSteps[4].Details)
+	})
+
+	t.Run("test reading the requirements of the version picked", func(t *testing.T) {
+		localTestingDir := "test-resolve-picked-version"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		// Only the latest release of TheVendor::Middle requires ARM::CMSIS
+		addFakePublicPack("TheVendor", "Middle", []string{"1.0.0"})
+		assert.Nil(os.Rename(filepath.Join(installer.Installation.WebDir, "TheVendor.Middle.pdsc"),
+			filepath.Join(installer.Installation.DownloadDir, "TheVendor.Middle.1.0.0.pdsc")))
+		addFakePublicPack("TheVendor", "Middle", []string{"2.0.0", "1.0.0"}, []string{"ARM", "CMSIS", "6.0.0"})
+		packFilePath := createFakePackFile(t.TempDir(), "TheVendor", "Root", "1.0.0", []string{"TheVendor", "Middle", "1.0.0:1.9.9"})
+
+		plan := installer.NewPlan()
+		assert.Nil(installer.PlanAddPack(plan, packFilePath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true))
+		assert.Equal([]string{
+			"license LICENSE.txt",
+			"extract " + filepath.Join(localTestingDir, "TheVendor", "Root", "1.0.0"),
+			"require TheVendor::Middle@1.0.0:1.9.9",
+			"add TheVendor::Middle@1.0.0",
+		}, stepTargets(plan.Steps[0].Steps))
+	})
+}
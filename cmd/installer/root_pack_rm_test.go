@@ -28,7 +28,7 @@ func TestRemovePack(t *testing.T) {
 		assert.Nil(installer.ReadIndexFiles())
 		defer removePackRoot(localTestingDir)
 
-		_, err := installer.RemovePack("TheVendor.PackName.no-a-valid-version", false, true)
+		_, err := installer.RemovePack("TheVendor.PackName.no-a-valid-version", false, !Force, !Cascade, !WithDeps, true)
 
 		// Sanity check
 		assert.NotNil(err)
@@ -42,7 +42,7 @@ func TestRemovePack(t *testing.T) {
 		assert.Nil(installer.ReadIndexFiles())
 		defer removePackRoot(localTestingDir)
 
-		_, err := installer.RemovePack("TheVendor.PackName.1.2.3", false, true)
+		_, err := installer.RemovePack("TheVendor.PackName.1.2.3", false, !Force, !Cascade, !WithDeps, true)
 
 		// Sanity check
 		assert.NotNil(err)
@@ -180,7 +180,7 @@ func TestRemovePack(t *testing.T) {
 		removePack(t, packPath, true, NotPublic, true) // withVersion=true, purge=true
 
 		// Make sure pack is not purgeable
-		ok, err := installer.RemovePack(shortenPackPath(packPath, false), true, true) // withVersion=false, purge=true
+		ok, err := installer.RemovePack(shortenPackPath(packPath, false), true, !Force, !Cascade, !WithDeps, true) // withVersion=false, purge=true
 		assert.Nil(err)
 		assert.Equal(ok, true)
 	})
@@ -212,7 +212,7 @@ func TestRemovePack(t *testing.T) {
 		removePack(t, packPath, true, NotPublic, true) // withVersion=true, purge=true
 
 		// Make sure pack is not purgeable
-		ok, err := installer.RemovePack(shortPackPath, true, true) // purge=true
+		ok, err := installer.RemovePack(shortPackPath, true, !Force, !Cascade, !WithDeps, true) // purge=true
 		assert.Nil(err)
 		assert.Equal(ok, true)
 
@@ -306,7 +306,7 @@ func TestRemovePack(t *testing.T) {
 		assert.Equal(1, len(tags))
 
 		// RemovePack using legacy PackID format Vendor::Name
-		_, err = installer.RemovePack("TheVendor::PackName", false, true)
+		_, err = installer.RemovePack("TheVendor::PackName", false, !Force, !Cascade, !WithDeps, true)
 		assert.Nil(err)
 
 		// Verify the entry was removed from local_repository.pidx
@@ -330,7 +330,7 @@ func TestRemovePack(t *testing.T) {
 		assert.Equal(1, len(tags))
 
 		// RemovePack using legacy PackID format Vendor::Name@Version
-		_, err = installer.RemovePack("TheVendor::PackName@1.2.3", false, true)
+		_, err = installer.RemovePack("TheVendor::PackName@1.2.3", false, !Force, !Cascade, !WithDeps, true)
 		assert.Nil(err)
 
 		// Verify the entry was removed from local_repository.pidx
@@ -354,7 +354,7 @@ func TestRemovePack(t *testing.T) {
 		assert.Equal(1, len(tags))
 
 		// RemovePack using dotted PackID format Vendor.Name
-		_, err = installer.RemovePack("TheVendor.PackName", false, true)
+		_, err = installer.RemovePack("TheVendor.PackName", false, !Force, !Cascade, !WithDeps, true)
 		assert.Nil(err)
 
 		// Verify the entry was removed from local_repository.pidx
@@ -388,7 +388,7 @@ func TestRemovePack(t *testing.T) {
 		assert.False(utils.FileExists(tempPdsc))
 
 		// RemovePack should still succeed
-		_, err = installer.RemovePack("TheVendor::PackName", false, true)
+		_, err = installer.RemovePack("TheVendor::PackName", false, !Force, !Cascade, !WithDeps, true)
 		assert.Nil(err)
 
 		// Verify the entry was removed from local_repository.pidx
@@ -412,7 +412,7 @@ func TestRemovePack(t *testing.T) {
 		assert.Equal(1, len(tags))
 
 		// RemovePack with a non-matching version should fail
-		_, err = installer.RemovePack("TheVendor::PackName@9.9.9", false, true)
+		_, err = installer.RemovePack("TheVendor::PackName@9.9.9", false, !Force, !Cascade, !WithDeps, true)
 		assert.NotNil(err)
 		assert.Equal(errs.ErrPdscEntryNotFound, err)
 
@@ -420,4 +420,78 @@ func TestRemovePack(t *testing.T) {
 		tags = installer.Installation.LocalPidx.ListPdscTags()
 		assert.Equal(1, len(tags))
 	})
+
+	t.Run("test removing a pack required by other installed packs", func(t *testing.T) {
+		localTestingDir := "test-remove-pack-with-dependents"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		installFakePack(localTestingDir, "TheVendor", "Dependent", "1.0.0", []string{"ARM", "CMSIS", "5.6.0"})
+
+		_, err := installer.RemovePack("ARM.CMSIS.5.9.0", false, !Force, !Cascade, !WithDeps, true)
+		assert.Equal(errs.ErrPackHasDependents, err)
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "ARM", "CMSIS", "5.9.0")))
+
+		_, err = installer.RemovePack("ARM::CMSIS", false, !Force, !Cascade, !WithDeps, true)
+		assert.Equal(errs.ErrPackHasDependents, err)
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "ARM", "CMSIS", "5.9.0")))
+	})
+
+	t.Run("test removing a pack version when another one still satisfies its dependents", func(t *testing.T) {
+		localTestingDir := "test-remove-pack-with-dependents-satisfied"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		installFakePack(localTestingDir, "ARM", "CMSIS", "6.0.0")
+		installFakePack(localTestingDir, "TheVendor", "Dependent", "1.0.0", []string{"ARM", "CMSIS", "5.6.0"})
+
+		_, err := installer.RemovePack("ARM.CMSIS.5.9.0", false, !Force, !Cascade, !WithDeps, true)
+		assert.Nil(err)
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "ARM", "CMSIS", "5.9.0")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "ARM", "CMSIS", "6.0.0")))
+	})
+
+	t.Run("test force removing a pack required by other installed packs", func(t *testing.T) {
+		localTestingDir := "test-remove-pack-with-dependents-force"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		installFakePack(localTestingDir, "TheVendor", "Dependent", "1.0.0", []string{"ARM", "CMSIS", "5.6.0"})
+
+		_, err := installer.RemovePack("ARM.CMSIS.5.9.0", false, Force, !Cascade, !WithDeps, true)
+		assert.Nil(err)
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "ARM", "CMSIS", "5.9.0")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Dependent", "1.0.0")))
+	})
+
+	t.Run("test cascade removing a pack and its dependents", func(t *testing.T) {
+		localTestingDir := "test-remove-pack-with-dependents-cascade"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "ARM", "CMSIS", "5.9.0")
+		installFakePack(localTestingDir, "TheVendor", "A", "1.0.0", []string{"ARM", "CMSIS", "5.6.0"}, []string{"TheVendor", "B", "1.0.0"})
+		installFakePack(localTestingDir, "TheVendor", "B", "1.0.0", []string{"TheVendor", "A", "1.0.0"})
+		installFakePack(localTestingDir, "TheVendor", "C", "1.0.0", []string{"TheVendor", "B", "1.0.0"})
+		installFakePack(localTestingDir, "TheVendor", "Unrelated", "1.0.0")
+
+		_, err := installer.RemovePack("ARM.CMSIS.5.9.0", false, !Force, Cascade, !WithDeps, true)
+		assert.Nil(err)
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "ARM", "CMSIS")))
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "A")))
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "B")))
+		assert.False(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "C")))
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Unrelated", "1.0.0")))
+	})
 }
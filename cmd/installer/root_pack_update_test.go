@@ -29,7 +29,7 @@ func TestUpdatePack(t *testing.T) {
 		defer removePackRoot(localTestingDir)
 
 		for i := range malformedPackNames {
-			err := installer.UpdatePack(malformedPackNames[i], !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Timeout)
+			err := installer.UpdatePack(malformedPackNames[i], !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Concurrency, Timeout)
 			// Sanity check
 			assert.NotNil(err)
 			assert.Equal(err, errs.ErrBadPackName)
@@ -48,7 +48,7 @@ func TestUpdatePack(t *testing.T) {
 		defer removePackRoot(localTestingDir)
 
 		packPath := publicLocalPack123
-		assert.Nil(installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Timeout))
+		assert.Nil(installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Concurrency, Timeout))
 	})
 
 	t.Run("test updating downloaded pack", func(t *testing.T) {
@@ -66,7 +66,7 @@ func TestUpdatePack(t *testing.T) {
 		assert.Nil(err)
 
 		// ensure downloaded pack remains valid
-		err = installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Timeout)
+		err = installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Concurrency, Timeout)
 		assert.Nil(err)
 
 		packToUpdate, err := utils.ExtractPackInfo(packPath)
@@ -85,7 +85,7 @@ func TestUpdatePack(t *testing.T) {
 		err := installer.AddPack(packPath, !CheckEula, !ExtractEula, ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
 		assert.Nil(err)
 
-		err = installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Timeout)
+		err = installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Concurrency, Timeout)
 		assert.Nil(err)
 
 		packToReinstall, err := utils.ExtractPackInfo(packPath)
@@ -102,7 +102,7 @@ func TestUpdatePack(t *testing.T) {
 
 		packPath := packThatDoesNotExist
 
-		err := installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Timeout)
+		err := installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Concurrency, Timeout)
 		assert.Nil(err)
 
 		// Make sure pack.idx never got touched
@@ -120,7 +120,7 @@ func TestUpdatePack(t *testing.T) {
 
 		packPath := notFoundServer.URL() + packThatDoesNotExist
 
-		err := installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Timeout)
+		err := installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Concurrency, Timeout)
 		assert.Nil(err)
 
 		// Make sure pack.idx never got touched
@@ -136,7 +136,7 @@ func TestUpdatePack(t *testing.T) {
 
 		packPath := packWithMalformedURL
 
-		err := installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Timeout)
+		err := installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Concurrency, Timeout)
 
 		// Sanity check
 		assert.NotNil(err)
@@ -201,7 +201,7 @@ func TestUpdatePack(t *testing.T) {
 		pdscXML.URL = server.URL()
 		assert.Nil(utils.WriteXML(packPdscFilePath, pdscXML))
 
-		err = installer.UpdatePack("", !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Timeout)
+		err = installer.UpdatePack("", !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Concurrency, Timeout)
 
 		// Sanity check
 		assert.Nil(err)
@@ -222,11 +222,11 @@ func TestUpdatePack(t *testing.T) {
 		assert.Nil(err)
 
 		// Test update with insecureSkipVerify = true
-		err = installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, InsecureSkipVerify, true, Timeout)
+		err = installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, InsecureSkipVerify, true, Concurrency, Timeout)
 		assert.Nil(err)
 
 		// Test update with insecureSkipVerify = false (default)
-		err = installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Timeout)
+		err = installer.UpdatePack(packPath, !CheckEula, !NoRequirements, SubCall, !InsecureSkipVerify, true, Concurrency, Timeout)
 		assert.Nil(err)
 	})
 
@@ -5,11 +5,13 @@ package installer_test
 
 import (
 	"bytes"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
@@ -179,7 +181,7 @@ func removePack(t *testing.T, packPath string, withVersion, isPublic, purge bool
 
 	purgeOnly := !isInstalled && purge
 
-	_, err = installer.RemovePack(shortPackPath, purge, true)
+	_, err = installer.RemovePack(shortPackPath, purge, !Force, !Cascade, !WithDeps, true)
 	assert.Nil(err)
 
 	removeAll := false
@@ -223,11 +225,15 @@ var (
 	CheckEula          = true
 	ExtractEula        = true
 	ForceReinstall     = true
+	Force              = true
+	Cascade            = true
+	WithDeps           = true
 	IsPublic           = true
 	NotPublic          = false
 	NoRequirements     = true
 	SubCall            = false
 	InsecureSkipVerify = true
+	Concurrency        = 0
 	Timeout            = 0
 
 	CreatePackRoot = true
@@ -342,6 +348,23 @@ func (s *Server) AddRoute(route string, content []byte) {
 	s.routes[route] = content
 }
 
+// TestMain trusts the certificate of the HTTPS test servers, which httptest shares between all of them,
+// as if it had been added to the certificate authorities of the system
+func TestMain(m *testing.M) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	pool.AddCert(server.Certificate())
+	server.Close()
+
+	options := utils.GetHTTPClientOptions()
+	options.RootCAs = pool
+	utils.SetHTTPClientOptions(options)
+	os.Exit(m.Run())
+}
+
 // NewServer is a generic dev server that takes in a routes map and returns 404 if the route[path] is nil
 // Ex:
 //
@@ -741,7 +764,6 @@ func TestUpdatePublicIndex(t *testing.T) {
 	var skipDeprecatedPdscFiles = true
 	var UpdatePrivatePdsc = true
 	var ShowInfo = true
-	var Concurrency = 0
 
 	// Re-enable this test when a flag --enforce-security is implemented
 	// t.Run("test add http server "+installer.PublicIndex, func(t *testing.T) {
@@ -1212,6 +1234,41 @@ func checkPackRoot(t *testing.T, path string) {
 	assert.True(utils.DirExists(installer.Installation.LocalDir))
 }
 
+// installFakePack creates a non-public installed pack whose pdsc requires
+// the given packages, each one in [Vendor, Name, Version] format
+func installFakePack(packRoot, vendor, name, version string, requirements ...[]string) {
+	packages := ""
+	for _, req := range requirements {
+		packages += fmt.Sprintf(`<package vendor="%s" name="%s" version="%s"/>`, req[0], req[1], req[2])
+	}
+	pdsc := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package>
+  <vendor>%s</vendor>
+  <name>%s</name>
+  <url>https://vendor.com/</url>
+  <releases>
+    <release version="%s"/>
+  </releases>
+  <requirements>
+    <packages>%s</packages>
+  </requirements>
+</package>`, vendor, name, version, packages)
+	packDir := filepath.Join(packRoot, vendor, name, version)
+	_ = os.MkdirAll(packDir, 0700)
+	_ = os.WriteFile(filepath.Join(packDir, vendor+"."+name+utils.PdscExtension), []byte(pdsc), 0600)
+
+	// Non-public packs keep a copy of their pdsc file in .Local
+	_ = os.WriteFile(filepath.Join(packRoot, ".Local", vendor+"."+name+utils.PdscExtension), []byte(pdsc), 0600)
+}
+
+// markFakeDependency records a pack as installed only to satisfy the requirements of other packs
+func markFakeDependency(packRoot, vendor, name, version string) {
+	pidx := xml.NewPidxXML(filepath.Join(packRoot, ".Local", installer.DependenciesIndexName), false)
+	_ = pidx.Read()
+	_ = pidx.AddPdsc(xml.PdscTag{Vendor: vendor, Name: name, Version: version})
+	_ = pidx.Write()
+}
+
 func removePackRoot(packRoot string) {
 	utils.UnsetReadOnlyR(packRoot)
 	os.RemoveAll(packRoot)
@@ -1445,3 +1502,119 @@ func init() {
 	log.SetLevel(logLevel)
 	log.SetFormatter(new(LogFormatter))
 }
+
+func TestUpdatePublicIndexFromMirror(t *testing.T) {
+
+	assert := assert.New(t)
+
+	localTestingDir := "test-update-public-index-mirror"
+	assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+	installer.UnlockPackRoot()
+	assert.Nil(installer.ReadIndexFiles())
+	defer removePackRoot(localTestingDir)
+
+	// The proxy reaches the mirror, but not keil.com
+	indexContent, err := os.ReadFile(samplePublicIndex)
+	assert.Nil(err)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host != "packs.acme.test" || r.URL.Path != "/"+installer.PublicIndexName {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write(indexContent)
+	}))
+	defer proxy.Close()
+	currOptions := utils.GetHTTPClientOptions()
+	defer utils.SetHTTPClientOptions(currOptions)
+	options := currOptions
+	options.Proxy, err = url.Parse(proxy.URL)
+	assert.Nil(err)
+	utils.SetHTTPClientOptions(options)
+
+	assert.Nil(installer.UpdatePublicIndex("http://packs.acme.test/"+installer.PublicIndexName, true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+	assert.False(installer.Installation.PublicIndexXML.Empty())
+}
+
+func TestOfflineMode(t *testing.T) {
+
+	assert := assert.New(t)
+
+	utils.SetOffline(true)
+	defer utils.SetOffline(false)
+
+	t.Run("test updating the public index in offline mode", func(t *testing.T) {
+		localTestingDir := "test-offline-update-public-index"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		indexContent, err := os.ReadFile(samplePublicIndex)
+		assert.Nil(err)
+		indexServer := NewServer()
+		indexServer.AddRoute(installer.PublicIndexName, indexContent)
+		indexPath := indexServer.URL() + installer.PublicIndexName
+
+		err = installer.UpdatePublicIndex(indexPath, true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout)
+		assert.Equal(errs.ErrOfflineMode, errors.Unwrap(err))
+		assert.Contains(err.Error(), indexPath)
+	})
+
+	t.Run("test installing a public pack whose pdsc file is missing in offline mode", func(t *testing.T) {
+		localTestingDir := "test-offline-add-pack-missing-pdsc"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		assert.Nil(installer.Installation.PublicIndexXML.AddPdsc(xml.PdscTag{
+			Vendor:  "TheVendor",
+			Name:    "PublicRemotePack",
+			Version: "1.2.3",
+			URL:     "https://127.0.0.1/",
+		}))
+		assert.Nil(installer.Installation.PublicIndexXML.Write())
+
+		err := installer.AddPack(publicRemotePack123PackID, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, false, Timeout)
+		assert.Equal(errs.ErrOfflineMode, errors.Unwrap(err))
+		assert.Contains(err.Error(), "TheVendor.PublicRemotePack.pdsc")
+	})
+
+	t.Run("test installing a public pack from the pack root in offline mode", func(t *testing.T) {
+		localTestingDir := "test-offline-add-pack-from-pack-root"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		// The server is not supposed to be reached
+		server := NewServer()
+
+		packPdscFilePath := filepath.Join(installer.Installation.WebDir, "TheVendor.PublicRemotePack.pdsc")
+		assert.Nil(utils.CopyFile(pdscPack123MissingVersion, packPdscFilePath))
+		pdscXML := xml.NewPdscXML(packPdscFilePath)
+		assert.Nil(pdscXML.Read())
+		pdscXML.ReleasesTag.Releases = append(pdscXML.ReleasesTag.Releases, xml.ReleaseTag{
+			URL:     server.URL() + publicRemotePack123PackID + ".pack",
+			Version: "1.2.3",
+		})
+		assert.Nil(pdscXML.Write())
+		assert.Nil(installer.Installation.PublicIndexXML.AddPdsc(xml.PdscTag{
+			Vendor:  "TheVendor",
+			Name:    "PublicRemotePack",
+			Version: "1.2.3",
+			URL:     server.URL(),
+		}))
+		assert.Nil(installer.Installation.PublicIndexXML.Write())
+
+		// Not downloaded yet
+		err := installer.AddPack(publicRemotePack123PackID, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, false, Timeout)
+		assert.Equal(errs.ErrOfflineMode, errors.Unwrap(err))
+		assert.Contains(err.Error(), publicRemotePack123PackID+".pack")
+
+		// Already downloaded
+		assert.Nil(utils.CopyFile(publicRemotePack123, filepath.Join(installer.Installation.DownloadDir, publicRemotePack123PackID+".pack")))
+		assert.Nil(installer.AddPack(publicRemotePack123PackID, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, false, Timeout))
+		assert.DirExists(filepath.Join(localTestingDir, "TheVendor", "PublicRemotePack", "1.2.3"))
+	})
+}
@@ -0,0 +1,195 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	stdlog "log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// RegistryServer serves the files of a registry directory, e.g. one written by PublishPack, over HTTP
+// or HTTPS. Range and conditional requests are supported, the ETag of a file telling its size and
+// modification time. Directories are not listed, and hidden files and directories are not served.
+type RegistryServer struct {
+	// Dir is the registry directory
+	Dir string
+
+	// Username and Password, if Username is not empty, are required from clients, with basic authentication
+	Username string
+	Password string
+
+	// Certificate, if not nil, makes the server use HTTPS
+	Certificate *tls.Certificate
+}
+
+// serverContentTypes are the content types of the files of a registry which the server cannot tell
+var serverContentTypes = map[string]string{
+	".pidx":             "application/xml",
+	".vidx":             "application/xml",
+	utils.PdscExtension: "application/xml",
+	utils.PackExtension: "application/zip",
+	".signed":           "application/zip",
+	".checksum":         "text/plain; charset=utf-8",
+}
+
+// authorized tells whether the request carries the credentials the server requires, if any
+func (s *RegistryServer) authorized(r *http.Request) bool {
+	if s.Username == "" {
+		return true
+	}
+	username, password, ok := r.BasicAuth()
+	usernameOk := subtle.ConstantTimeCompare([]byte(username), []byte(s.Username)) == 1
+	passwordOk := subtle.ConstantTimeCompare([]byte(password), []byte(s.Password)) == 1
+	return ok && usernameOk && passwordOk
+}
+
+// ServeHTTP serves the file of the registry at the path of the request
+func (s *RegistryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debugf("%s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="cpackget", charset="UTF-8"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	for segment := range strings.SplitSeq(name, "/") {
+		if segment == "" || strings.HasPrefix(segment, ".") {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	// The root keeps the requests from reaching files out of the registry, e.g. through symlinks
+	root, err := os.OpenRoot(s.Dir)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer root.Close()
+	file, err := root.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	if contentType, found := serverContentTypes[strings.ToLower(path.Ext(name))]; found {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// Serve serves the registry on listener until ctx is done, then lets the requests
+// being served finish, for up to 5 seconds.
+func (s *RegistryServer) Serve(ctx context.Context, listener net.Listener) error {
+	errorLog := log.StandardLogger().WriterLevel(log.DebugLevel)
+	defer errorLog.Close()
+	server := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 30 * time.Second,
+		ErrorLog:          stdlog.New(errorLog, "", 0),
+	}
+	if s.Certificate != nil {
+		server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{*s.Certificate},
+			MinVersion:   tls.VersionTLS12,
+		}
+		listener = tls.NewListener(listener, server.TLSConfig)
+	}
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(shutdownCtx)
+		case <-stopped:
+		}
+	}()
+
+	err := server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// GenerateSelfSignedCertificate generates a certificate, and its private key, valid for a year for the
+// given host names and IP addresses. The certificate can be trusted by clients as its own authority.
+//
+// Returns:
+//   - tls.Certificate: The certificate and its private key, for a server to use.
+//   - []byte: The certificate, PEM encoded, for clients to trust.
+//   - error: An error if the key or the certificate could not be generated.
+func GenerateSelfSignedCertificate(hosts []string) (tls.Certificate, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	notBefore := time.Now().Add(-time.Hour)
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"cpackget"}, CommonName: "cpackget serve"},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	certificate := tls.Certificate{Certificate: [][]byte{certDER}, PrivateKey: key}
+	return certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), nil
+}
@@ -0,0 +1,171 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryServer(t *testing.T) {
+
+	assert := assert.New(t)
+
+	registryDir := t.TempDir()
+	assert.Nil(os.WriteFile(filepath.Join(registryDir, installer.PublicIndexName), []byte("0123456789"), 0600))
+	assert.Nil(os.MkdirAll(filepath.Join(registryDir, "sub", ".hidden"), 0700))
+	assert.Nil(os.WriteFile(filepath.Join(registryDir, "sub", ".hidden", "secret.pdsc"), []byte("secret"), 0600))
+	assert.Nil(os.WriteFile(filepath.Join(filepath.Dir(registryDir), "outside.pdsc"), []byte("outside"), 0600))
+
+	get := func(URL string, header http.Header) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, URL, nil)
+		assert.Nil(err)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(err)
+		return resp
+	}
+
+	t.Run("test serving files with ranges and etags", func(t *testing.T) {
+		server := httptest.NewServer(&installer.RegistryServer{Dir: registryDir})
+		defer server.Close()
+
+		resp := get(server.URL+"/"+installer.PublicIndexName, nil)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal("0123456789", string(body))
+		assert.Equal("application/xml", resp.Header.Get("Content-Type"))
+		etag := resp.Header.Get("ETag")
+		assert.NotEmpty(etag)
+
+		resp = get(server.URL+"/"+installer.PublicIndexName, http.Header{"Range": {"bytes=4-"}})
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(http.StatusPartialContent, resp.StatusCode)
+		assert.Equal("456789", string(body))
+		assert.Equal("bytes 4-9/10", resp.Header.Get("Content-Range"))
+
+		resp = get(server.URL+"/"+installer.PublicIndexName, http.Header{"If-None-Match": {etag}})
+		resp.Body.Close()
+		assert.Equal(http.StatusNotModified, resp.StatusCode)
+
+		// The file changed since the range started
+		resp = get(server.URL+"/"+installer.PublicIndexName, http.Header{"Range": {"bytes=4-"}, "If-Range": {`"other"`}})
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("test not serving directories, hidden files and files out of the registry", func(t *testing.T) {
+		server := httptest.NewServer(&installer.RegistryServer{Dir: registryDir})
+		defer server.Close()
+
+		for _, urlPath := range []string{"/", "/sub", "/sub/.hidden/secret.pdsc", "/../outside.pdsc", "/%2e%2e/outside.pdsc", "/does-not-exist.pdsc"} {
+			resp := get(server.URL+urlPath, nil)
+			resp.Body.Close()
+			assert.Equal(http.StatusNotFound, resp.StatusCode, urlPath)
+		}
+
+		resp, err := http.Post(server.URL+"/"+installer.PublicIndexName, "text/plain", nil)
+		assert.Nil(err)
+		resp.Body.Close()
+		assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+
+	t.Run("test requiring basic authentication", func(t *testing.T) {
+		server := httptest.NewServer(&installer.RegistryServer{Dir: registryDir, Username: "me", Password: "secret"})
+		defer server.Close()
+
+		resp := get(server.URL+"/"+installer.PublicIndexName, nil)
+		resp.Body.Close()
+		assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+		assert.Contains(resp.Header.Get("WWW-Authenticate"), "Basic")
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/"+installer.PublicIndexName, nil)
+		req.SetBasicAuth("me", "wrong")
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(err)
+		resp.Body.Close()
+		assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+		req.SetBasicAuth("me", "secret")
+		resp, err = http.DefaultClient.Do(req)
+		assert.Nil(err)
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("test serving https with a self-signed certificate", func(t *testing.T) {
+		certificate, certPEM, err := installer.GenerateSelfSignedCertificate([]string{"localhost", "127.0.0.1"})
+		assert.Nil(err)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error)
+		go func() {
+			served <- (&installer.RegistryServer{Dir: registryDir, Certificate: &certificate}).Serve(ctx, listener)
+		}()
+
+		// Clients trusting the certificate
+		rootCAs := x509.NewCertPool()
+		assert.True(rootCAs.AppendCertsFromPEM(certPEM))
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}}}
+		resp, err := client.Get("https://" + listener.Addr().String() + "/" + installer.PublicIndexName)
+		if assert.Nil(err) {
+			resp.Body.Close()
+			assert.Equal(http.StatusOK, resp.StatusCode)
+		}
+
+		cancel()
+		assert.Nil(<-served)
+	})
+
+	t.Run("test adding packs from a served registry", func(t *testing.T) {
+		certificate, certificatePEM, err := installer.GenerateSelfSignedCertificate([]string{"127.0.0.1"})
+		assert.Nil(err)
+		currOptions := utils.GetHTTPClientOptions()
+		defer utils.SetHTTPClientOptions(currOptions)
+		options := currOptions
+		options.RootCAs = currOptions.RootCAs.Clone()
+		assert.True(options.RootCAs.AppendCertsFromPEM(certificatePEM))
+		utils.SetHTTPClientOptions(options)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(err)
+		registryURL := "https://" + listener.Addr().String() + "/"
+
+		servedDir := t.TempDir()
+		_, err = installer.PublishPack(publicLocalPack124, servedDir, registryURL)
+		assert.Nil(err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			_ = (&installer.RegistryServer{Dir: servedDir, Certificate: &certificate}).Serve(ctx, listener)
+		}()
+
+		localTestingDir := "test-add-from-served-registry"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		defer removePackRoot(localTestingDir)
+
+		assert.Nil(installer.UpdatePublicIndex(registryURL+installer.PublicIndexName, true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		assert.Nil(installer.AddPack(publicLocalPackLegacyPackID+"@1.2.4", !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		assert.DirExists(filepath.Join(localTestingDir, "TheVendor", "PublicLocalPack", "1.2.4"))
+	})
+}
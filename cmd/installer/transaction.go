@@ -0,0 +1,448 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// TransactionDirName is the directory under ".Local/" holding the journal of the
+// transaction in progress, along with the original contents of the files it changed
+const TransactionDirName = "transaction"
+
+// JournalFileName is the journal of the transaction in progress, in TransactionDirName.
+// Its first line describes the transaction, and every following line is a journalEntry.
+const JournalFileName = "journal.jsonl"
+
+// journalEntry is a file or directory of the pack root changed by a transaction
+type journalEntry struct {
+	// Path is relative to the pack root
+	Path string `json:"path"`
+
+	// Backup names the original contents of Path in the "backup" directory of the
+	// transaction. It is empty if Path did not exist before the transaction.
+	Backup string `json:"backup,omitempty"`
+}
+
+// transactionJournal is written to JournalFileName before every change to the pack root,
+// so that an interrupted transaction can be rolled back by the next cpackget run
+type transactionJournal struct {
+	Command  string `json:"command"`
+	PID      int    `json:"pid"`
+	Hostname string `json:"hostname"`
+	Started  string `json:"started"`
+
+	// Entries are appended to the journal one line at a time, see transaction.add
+	Entries []journalEntry `json:"-"`
+}
+
+// transaction groups the changes made to the pack root by a batch operation,
+// so that they either all apply or all get rolled back
+type transaction struct {
+	journal transactionJournal
+
+	// dir is the transaction directory, under ".Local/"
+	dir string
+
+	// tracked lists the paths of the entries, so that each path gets saved once
+	tracked map[string]bool
+
+	// packURLs keeps the pack URLs resolved by findPackURL, by pack path and version
+	packURLs map[string]resolvedPackURL
+
+	// staged lists the packs extracted ahead of their installation by PrefetchPacks, by Vendor.Pack.x.y.z
+	staged map[string]stagedPack
+
+	lock sync.Mutex
+}
+
+// transactionDir returns the path of the transaction directory of the pack root
+func (p *PacksInstallationType) transactionDir() string {
+	return filepath.Join(p.LocalDir, TransactionDirName)
+}
+
+// BeginTransaction starts journaling the changes made to the pack root, until
+// CommitTransaction or RollbackTransaction gets called. An interrupted transaction
+// left in the pack root is rolled back first.
+//
+// Parameters:
+//   - command: A short description of the operation, reported if it gets interrupted.
+//
+// Returns:
+//   - error: An error if a transaction is already in progress, possibly by another cpackget run,
+//     or the journal cannot be written.
+func BeginTransaction(command string) error {
+	if Installation.transaction != nil {
+		return errs.ErrTransactionInProgress
+	}
+	if err := RecoverTransaction(); err != nil {
+		return err
+	}
+
+	dir := Installation.transactionDir()
+	if utils.DirExists(dir) {
+		// Left alone by RecoverTransaction, as another cpackget run may still be using it
+		return fmt.Errorf("%q: %w", dir, errs.ErrTransactionInProgress)
+	}
+	if err := utils.EnsureDir(filepath.Join(dir, "backup")); err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	t := &transaction{
+		journal: transactionJournal{
+			Command:  command,
+			PID:      os.Getpid(),
+			Hostname: hostname,
+			Started:  time.Now().Format(time.RFC3339),
+			Entries:  []journalEntry{},
+		},
+		dir:      dir,
+		tracked:  map[string]bool{},
+		packURLs: map[string]resolvedPackURL{},
+		staged:   map[string]stagedPack{},
+	}
+	if err := t.write(); err != nil {
+		return err
+	}
+	log.Debugf("Started transaction %q", command)
+	Installation.transaction = t
+
+	// Index files are rewritten as a whole, keep a copy of them. The public index gets refreshed
+	// along with the PDSC files of .Web, which a rollback restores as well.
+	indexFiles := []string{
+		Installation.PublicIndex,
+		filepath.Join(Installation.WebDir, IndexSourcesFileName),
+		Installation.PublicCacheIndex,
+		Installation.LocalPidx.GetFileName(),
+		Installation.DependenciesPidx.GetFileName(),
+	}
+	for _, pidxPath := range indexFiles {
+		if err := Installation.snapshot(pidxPath); err != nil {
+			_ = RollbackTransaction()
+			return err
+		}
+	}
+	return nil
+}
+
+// CommitTransaction keeps the changes made since BeginTransaction and drops the journal
+func CommitTransaction() error {
+	t := Installation.transaction
+	if t == nil {
+		return nil
+	}
+	Installation.transaction = nil
+	log.Debugf("Committing transaction %q", t.journal.Command)
+
+	// Removing the journal first makes the commit final, even if the backups cannot be removed
+	if err := os.Remove(filepath.Join(t.dir, JournalFileName)); err != nil {
+		return err
+	}
+	utils.UnsetReadOnlyR(t.dir)
+	err := os.RemoveAll(t.dir)
+
+	// Keep the validators of the files downloaded in the transaction
+	if validatorsErr := Installation.writeValidators(); err == nil {
+		err = validatorsErr
+	}
+	return err
+}
+
+// RollbackTransaction undoes the changes made since BeginTransaction, restoring
+// the pack root and the index files to the state they were in
+func RollbackTransaction() error {
+	t := Installation.transaction
+	if t == nil {
+		return nil
+	}
+	Installation.transaction = nil
+	log.Infof("Rolling back the changes of %q", t.journal.Command)
+
+	err := t.rollback(Installation.PackRoot)
+	Installation.reloadIndexFiles()
+	return err
+}
+
+// EndTransaction commits the transaction in progress if err is nil, and rolls it back otherwise.
+// It returns err, or the error of the commit.
+func EndTransaction(err error) error {
+	if err != nil {
+		if rollbackErr := RollbackTransaction(); rollbackErr != nil {
+			log.Error(rollbackErr)
+		}
+		return err
+	}
+	return CommitTransaction()
+}
+
+// RecoverTransaction rolls back a transaction left in the pack root by an
+// interrupted cpackget run. It does nothing if there is none. Unless this process
+// holds the lock of the pack root, only the transactions of cpackget processes of
+// this machine that are not running anymore get rolled back.
+func RecoverTransaction() error {
+	dir := Installation.transactionDir()
+	journalPath := filepath.Join(dir, JournalFileName)
+	locked := holdsPackRootLock()
+	if !utils.FileExists(journalPath) {
+		if locked && utils.DirExists(dir) {
+			// Leftovers of a transaction interrupted while committing or before writing its journal
+			utils.UnsetReadOnlyR(dir)
+			return os.RemoveAll(dir)
+		}
+		return nil
+	}
+
+	t := &transaction{dir: dir}
+	if err := t.read(); err != nil {
+		return err
+	}
+	owner := packRootLockOwner{PID: t.journal.PID, Hostname: t.journal.Hostname}
+	if !locked && !owner.isStale() {
+		log.Debugf("Not rolling back %q, pid %d on %q may still be running it", t.journal.Command, owner.PID, owner.Hostname)
+		return nil
+	}
+
+	log.Warnf("Rolling back %q, interrupted on %s (pid %d)", t.journal.Command, t.journal.Started, t.journal.PID)
+	if Installation.ReadOnly {
+		UnlockPackRoot()
+		defer LockPackRoot()
+	}
+	return t.rollback(Installation.PackRoot)
+}
+
+// inTransaction runs fn in the transaction in progress, or in a transaction of its own
+// if there is none, so that a failing fn leaves the pack root untouched
+func inTransaction(command string, fn func() error) error {
+	if Installation.transaction != nil {
+		return fn()
+	}
+	if err := BeginTransaction(command); err != nil {
+		return err
+	}
+	return EndTransaction(fn())
+}
+
+// stash records the current state of path in the journal, for it to be restored on
+// rollback, and gets path out of the way: an existing file or directory is moved to
+// the transaction directory. The caller is then free to create path again.
+// Outside transactions, path is simply removed.
+func (p *PacksInstallationType) stash(path string) error {
+	t := p.transaction
+	rel, err := filepath.Rel(p.PackRoot, path)
+	if t == nil || err != nil || strings.HasPrefix(rel, "..") {
+		return removePath(path)
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.isTracked(rel) {
+		return removePath(path)
+	}
+
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		// Record the topmost directory the caller is about to create
+		for parent := filepath.Dir(rel); parent != "." && !utils.DirExists(filepath.Join(p.PackRoot, parent)); parent = filepath.Dir(parent) {
+			rel = parent
+		}
+		return t.add(journalEntry{Path: rel})
+	}
+
+	entry := journalEntry{Path: rel, Backup: strconv.Itoa(len(t.journal.Entries))}
+	if err := t.add(entry); err != nil {
+		return err
+	}
+	return utils.MoveFile(path, t.backupPath(entry))
+}
+
+// snapshot records a copy of the file in path in the journal, leaving path in place
+func (p *PacksInstallationType) snapshot(path string) error {
+	t := p.transaction
+	rel, err := filepath.Rel(p.PackRoot, path)
+	if t == nil || err != nil || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.isTracked(rel) {
+		return nil
+	}
+	if !utils.FileExists(path) {
+		return t.add(journalEntry{Path: rel})
+	}
+
+	// Copy first, so that the journal never refers to a partial copy
+	entry := journalEntry{Path: rel, Backup: strconv.Itoa(len(t.journal.Entries))}
+	if err := utils.CopyFile(path, t.backupPath(entry)); err != nil {
+		return err
+	}
+	return t.add(entry)
+}
+
+// backupPath returns where the original contents of the entry are kept. Backups sit two levels
+// below ".Local/", so that backed up packs are never mistaken for installed ones.
+func (t *transaction) backupPath(entry journalEntry) string {
+	return filepath.Join(t.dir, "backup", entry.Backup)
+}
+
+// isTracked tells whether rel, or one of the directories containing it, is in the journal
+func (t *transaction) isTracked(rel string) bool {
+	for ; rel != "." && rel != string(filepath.Separator); rel = filepath.Dir(rel) {
+		if t.tracked[rel] {
+			return true
+		}
+	}
+	return false
+}
+
+// add appends entry to the journal and writes it down, without writing the previous entries again
+func (t *transaction) add(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(t.dir, JournalFileName), os.O_APPEND|os.O_WRONLY, 0600) // #nosec
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	t.journal.Entries = append(t.journal.Entries, entry)
+	t.tracked[entry.Path] = true
+	return nil
+}
+
+// write saves the description of the transaction, starting a journal without entries
+func (t *transaction) write() error {
+	line, err := json.Marshal(t.journal)
+	if err != nil {
+		return err
+	}
+	journalPath := filepath.Join(t.dir, JournalFileName)
+	if err := os.WriteFile(journalPath+".tmp", append(line, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(journalPath+".tmp", journalPath)
+}
+
+// read loads the journal left in the transaction directory. A last entry cut short
+// by an interruption is ignored: the change it announced was not made yet.
+func (t *transaction) read() error {
+	journalPath := filepath.Join(t.dir, JournalFileName)
+	contents, err := os.ReadFile(journalPath) // #nosec
+	if err != nil {
+		return err
+	}
+
+	lines := bytes.Split(contents, []byte("\n"))
+	if err := json.Unmarshal(lines[0], &t.journal); err != nil {
+		return fmt.Errorf("%q: %w", journalPath, err)
+	}
+	for _, line := range lines[1:] {
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if len(line) > 0 {
+				log.Debugf("Ignoring the end of %q: %v", journalPath, err)
+			}
+			break
+		}
+		t.journal.Entries = append(t.journal.Entries, entry)
+	}
+	return nil
+}
+
+// rollback undoes the entries of the journal, most recent first, and removes the transaction directory
+func (t *transaction) rollback(packRoot string) error {
+	failed := false
+	for i := len(t.journal.Entries) - 1; i >= 0; i-- {
+		entry := t.journal.Entries[i]
+		path := filepath.Join(packRoot, entry.Path)
+
+		if entry.Backup == "" {
+			log.Debugf("Removing %q", path)
+			if err := removePath(path); err != nil {
+				log.Errorf("Cannot remove %q: %v", path, err)
+				failed = true
+			}
+			continue
+		}
+
+		backupPath := t.backupPath(entry)
+		if _, err := os.Lstat(backupPath); os.IsNotExist(err) {
+			// Interrupted before path got moved away, so it is still in place
+			continue
+		}
+		log.Debugf("Restoring %q", path)
+		if err := removePath(path); err != nil {
+			log.Errorf("Cannot remove %q: %v", path, err)
+			failed = true
+			continue
+		}
+		if err := utils.EnsureDir(filepath.Dir(path)); err != nil {
+			failed = true
+			continue
+		}
+		if err := utils.MoveFile(backupPath, path); err != nil {
+			failed = true
+		}
+	}
+
+	if failed {
+		log.Errorf("The original files are kept in %q", t.dir)
+		return errs.ErrTransactionRollbackFailed
+	}
+	utils.UnsetReadOnlyR(t.dir)
+	return os.RemoveAll(t.dir)
+}
+
+// removePath removes a file or a directory, even if read-only
+func removePath(path string) error {
+	utils.UnsetReadOnly(path)
+	utils.UnsetReadOnlyR(path)
+	return os.RemoveAll(path)
+}
+
+// reloadIndexFiles reads again the index files a rollback may have restored,
+// and drops the validators of the files it removed
+func (p *PacksInstallationType) reloadIndexFiles() {
+	p.validators.lock.Lock()
+	p.validators.files = nil
+	p.validators.lock.Unlock()
+
+	if utils.FileExists(p.PublicIndex) {
+		_ = p.PublicIndexXML.Read()
+	} else {
+		p.PublicIndexXML.Clear()
+	}
+	if utils.FileExists(p.PublicCacheIndex) {
+		_ = p.PublicCacheIndexXML.Read()
+	} else {
+		p.PublicCacheIndexXML.Clear()
+	}
+	if utils.FileExists(p.LocalPidx.GetFileName()) {
+		_ = p.LocalPidx.Read()
+	} else {
+		p.LocalPidx.Clear()
+	}
+	_ = p.readDependenciesPidx()
+}
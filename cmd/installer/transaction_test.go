@@ -0,0 +1,194 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test committing a transaction", func(t *testing.T) {
+		localTestingDir := "test-transaction-commit"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		packFilePath := createFakePackFile(t.TempDir(), "TheVendor", "First", "1.0.0")
+
+		assert.Nil(installer.BeginTransaction("add"))
+		assert.ErrorIs(installer.BeginTransaction("add"), errs.ErrTransactionInProgress)
+		assert.FileExists(filepath.Join(installer.Installation.LocalDir, installer.TransactionDirName, installer.JournalFileName))
+		assert.Nil(installer.AddPack(packFilePath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		assert.Nil(installer.EndTransaction(nil))
+
+		assert.DirExists(filepath.Join(localTestingDir, "TheVendor", "First", "1.0.0"))
+		assert.NoDirExists(filepath.Join(installer.Installation.LocalDir, installer.TransactionDirName))
+	})
+
+	t.Run("test rolling back a failed batch of packs", func(t *testing.T) {
+		localTestingDir := "test-transaction-rollback"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "TheVendor", "Installed", "1.0.0")
+		localPidx, err := os.ReadFile(installer.Installation.LocalPidx.GetFileName())
+		assert.Nil(err)
+
+		packFilePath := createFakePackFile(t.TempDir(), "TheVendor", "First", "1.0.0")
+		pdscFilePath := filepath.Join(testDir, "1.2.3", "TheVendor.PackName.pdsc")
+
+		assert.Nil(installer.BeginTransaction("add"))
+		assert.Nil(installer.AddPack("$"+packFilePath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		assert.Nil(installer.AddPdsc(pdscFilePath))
+		_, err = installer.RemovePack("TheVendor.Installed.1.0.0", false, Force, !Cascade, !WithDeps, true)
+		assert.Nil(err)
+		err = installer.AddPack(filepath.Join(t.TempDir(), "TheVendor.Missing.1.0.0.pack"), !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout)
+		assert.ErrorIs(installer.EndTransaction(err), errs.ErrFileNotFound)
+
+		// Nothing of the batch is left, and the removed pack is back
+		assert.NoDirExists(filepath.Join(localTestingDir, "TheVendor", "First"))
+		assert.NoFileExists(filepath.Join(installer.Installation.LocalDir, "TheVendor.First.pdsc"))
+		assert.NoFileExists(filepath.Join(installer.Installation.DownloadDir, "TheVendor.First.1.0.0.pack"))
+		assert.NoFileExists(filepath.Join(installer.Installation.DownloadDir, "TheVendor.First.1.0.0.pdsc"))
+		assert.DirExists(filepath.Join(localTestingDir, "TheVendor", "Installed", "1.0.0"))
+		assert.FileExists(filepath.Join(installer.Installation.LocalDir, "TheVendor.Installed.pdsc"))
+		assert.Len(dependencyTags(localTestingDir), 0)
+		restoredPidx, err := os.ReadFile(installer.Installation.LocalPidx.GetFileName())
+		assert.Nil(err)
+		assert.Equal(string(localPidx), string(restoredPidx))
+		assert.Len(installer.Installation.LocalPidx.ListPdscTags(), 0)
+		assert.NoDirExists(filepath.Join(installer.Installation.LocalDir, installer.TransactionDirName))
+	})
+
+	t.Run("test rolling back a pack failing on its own", func(t *testing.T) {
+		localTestingDir := "test-transaction-rollback-single"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		// Its requirement cannot be found, so the pack itself is rolled back
+		packFilePath := createFakePackFile(t.TempDir(), "TheVendor", "First", "1.0.0", []string{"TheVendor", "Unknown", "1.0.0"})
+		assert.NotNil(installer.AddPack(packFilePath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+
+		assert.NoDirExists(filepath.Join(localTestingDir, "TheVendor"))
+		assert.NoFileExists(filepath.Join(installer.Installation.LocalDir, "TheVendor.First.pdsc"))
+		assert.NoDirExists(filepath.Join(installer.Installation.LocalDir, installer.TransactionDirName))
+	})
+
+	t.Run("test recovering an interrupted transaction", func(t *testing.T) {
+		localTestingDir := "test-transaction-recover"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "TheVendor", "Installed", "1.0.0")
+		packFilePath := createFakePackFile(t.TempDir(), "TheVendor", "First", "1.0.0")
+
+		assert.Nil(installer.BeginTransaction("add"))
+		assert.Nil(installer.AddPack(packFilePath, !CheckEula, !ExtractEula, !ForceReinstall, !NoRequirements, !InsecureSkipVerify, true, Timeout))
+		_, err := installer.RemovePack("TheVendor.Installed.1.0.0", false, Force, !Cascade, !WithDeps, true)
+		assert.Nil(err)
+
+		// Simulate the next cpackget run, the transaction being left unfinished
+		assert.Nil(installer.SetPackRoot(localTestingDir, !CreatePackRoot))
+
+		// Its owner is still running, the transaction is left alone unless the pack root is locked
+		assert.Nil(installer.RecoverTransaction())
+		assert.FileExists(filepath.Join(localTestingDir, ".Local", installer.TransactionDirName, installer.JournalFileName))
+		assert.ErrorIs(installer.BeginTransaction("add"), errs.ErrTransactionInProgress)
+
+		assert.Nil(installer.AcquirePackRootLock(1))
+		defer installer.ReleasePackRootLock()
+		assert.Nil(installer.RecoverTransaction())
+
+		assert.NoDirExists(filepath.Join(localTestingDir, "TheVendor", "First"))
+		assert.NoFileExists(filepath.Join(localTestingDir, ".Local", "TheVendor.First.pdsc"))
+		assert.DirExists(filepath.Join(localTestingDir, "TheVendor", "Installed", "1.0.0"))
+		assert.NoDirExists(filepath.Join(localTestingDir, ".Local", installer.TransactionDirName))
+
+		// Nothing left to recover
+		assert.Nil(installer.RecoverTransaction())
+		assert.True(utils.DirExists(filepath.Join(localTestingDir, "TheVendor", "Installed", "1.0.0")))
+	})
+
+	t.Run("test rolling back a refreshed public index", func(t *testing.T) {
+		localTestingDir := "test-transaction-rollback-public-index"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		indexContent, err := os.ReadFile(samplePublicIndex)
+		assert.Nil(err)
+		previousIndex := append(append([]byte{}, indexContent...), []byte("<!-- previous -->\n")...)
+		assert.Nil(os.WriteFile(installer.Installation.PublicIndex, previousIndex, 0600))
+		indexSources := filepath.Join(installer.Installation.WebDir, installer.IndexSourcesFileName)
+		assert.Nil(os.WriteFile(indexSources, []byte(`{"sources": []}`), 0600))
+		indexServer := NewServer()
+		indexServer.AddRoute(installer.PublicIndexName, indexContent)
+
+		assert.Nil(installer.BeginTransaction("update"))
+		assert.Nil(installer.UpdatePublicIndex(indexServer.URL()+installer.PublicIndexName, true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		assert.Equal(errs.ErrFileNotFound, installer.EndTransaction(errs.ErrFileNotFound))
+
+		// The index goes back to the PDSC files the rollback restores
+		restoredIndex, err := os.ReadFile(installer.Installation.PublicIndex)
+		assert.Nil(err)
+		assert.Equal(string(previousIndex), string(restoredIndex))
+		assert.FileExists(indexSources)
+	})
+
+	t.Run("test recovering the transaction of a dead process", func(t *testing.T) {
+		localTestingDir := "test-transaction-recover-dead"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		installFakePack(localTestingDir, "TheVendor", "Installed", "1.0.0")
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		assert.Nil(cmd.Run())
+		hostname, _ := os.Hostname()
+
+		// The run adding the pack got interrupted before writing its files
+		transactionDir := filepath.Join(installer.Installation.LocalDir, installer.TransactionDirName)
+		assert.Nil(os.MkdirAll(filepath.Join(transactionDir, "backup"), 0700))
+		journal, _ := json.Marshal(map[string]any{
+			"command":  "add TheVendor.First.1.0.0.pack",
+			"pid":      cmd.ProcessState.Pid(),
+			"hostname": hostname,
+			"started":  "2024-01-01T00:00:00Z",
+		})
+		entry, _ := json.Marshal(map[string]string{"path": filepath.Join("TheVendor", "First")})
+		journal = append(append(append(journal, '\n'), entry...), '\n')
+
+		// Interrupted again while announcing its next change
+		journal = append(journal, []byte(`{"path": "TheVen`)...)
+		assert.Nil(os.WriteFile(filepath.Join(transactionDir, installer.JournalFileName), journal, 0600))
+		assert.Nil(os.MkdirAll(filepath.Join(localTestingDir, "TheVendor", "First", "1.0.0"), 0700))
+
+		// No need to hold the lock of the pack root to roll back what a dead process left
+		assert.Nil(installer.RecoverTransaction())
+		assert.NoDirExists(filepath.Join(localTestingDir, "TheVendor", "First"))
+		assert.DirExists(filepath.Join(localTestingDir, "TheVendor", "Installed", "1.0.0"))
+		assert.NoDirExists(transactionDir)
+	})
+}
@@ -0,0 +1,136 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// ValidatorsFileName is the file, in the .Web and .Local directories, keeping the validators
+// of the files downloaded there, so that refreshing them only downloads the ones that changed
+const ValidatorsFileName = "validators.json"
+
+// downloadValidators are the validators of a file downloaded to the pack root
+type downloadValidators struct {
+	// URL the file was downloaded from
+	URL string `json:"url"`
+
+	utils.Validators
+
+	// Size and ModTime of the file once downloaded. Validators of a file that changed
+	// since, e.g. restored by a rollback or written by another tool, are not used.
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// validatorsFile is the content of a ValidatorsFileName file, by file name
+type validatorsFile struct {
+	Files map[string]downloadValidators `json:"files"`
+
+	// changed tells whether the file needs to be written
+	changed bool
+}
+
+// validatorsStore holds the validators files read so far, by directory
+type validatorsStore struct {
+	lock  sync.Mutex
+	files map[string]*validatorsFile
+}
+
+// get returns the validators file of dir, reading it on first use. The store must be locked.
+func (s *validatorsStore) get(dir string) *validatorsFile {
+	if s.files == nil {
+		s.files = map[string]*validatorsFile{}
+	}
+	if file, found := s.files[dir]; found {
+		return file
+	}
+
+	file := &validatorsFile{Files: map[string]downloadValidators{}}
+	contents, err := os.ReadFile(filepath.Join(dir, ValidatorsFileName))
+	if err == nil {
+		if err := json.Unmarshal(contents, file); err != nil || file.Files == nil {
+			log.Debugf("Ignoring %q: %v", filepath.Join(dir, ValidatorsFileName), err)
+			file.Files = map[string]downloadValidators{}
+		}
+	}
+	s.files[dir] = file
+	return file
+}
+
+// validatorsOf returns the validators to download filePath from URL again, if it changed.
+// They are empty if filePath was not downloaded from URL or changed since it was.
+func (p *PacksInstallationType) validatorsOf(filePath, URL string) utils.Validators {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return utils.Validators{}
+	}
+
+	p.validators.lock.Lock()
+	defer p.validators.lock.Unlock()
+
+	validators, found := p.validators.get(filepath.Dir(filePath)).Files[filepath.Base(filePath)]
+	if !found || validators.URL != URL || validators.Size != info.Size() || !validators.ModTime.Equal(info.ModTime()) {
+		return utils.Validators{}
+	}
+	return validators.Validators
+}
+
+// setValidators records the validators of filePath, just downloaded from URL
+func (p *PacksInstallationType) setValidators(filePath, URL string, validators utils.Validators) {
+	info, err := os.Stat(filePath)
+
+	p.validators.lock.Lock()
+	defer p.validators.lock.Unlock()
+
+	file := p.validators.get(filepath.Dir(filePath))
+	if err != nil || validators.IsEmpty() {
+		if _, found := file.Files[filepath.Base(filePath)]; found {
+			delete(file.Files, filepath.Base(filePath))
+			file.changed = true
+		}
+		return
+	}
+
+	file.Files[filepath.Base(filePath)] = downloadValidators{
+		URL:        URL,
+		Validators: validators,
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+	}
+	file.changed = true
+}
+
+// writeValidators writes down the validators recorded since last written. In a transaction,
+// they are only written once committed: a rollback drops them, see reloadIndexFiles.
+func (p *PacksInstallationType) writeValidators() error {
+	if p.transaction != nil {
+		return nil
+	}
+
+	p.validators.lock.Lock()
+	defer p.validators.lock.Unlock()
+
+	for dir, file := range p.validators.files {
+		if !file.changed {
+			continue
+		}
+		contents, err := json.MarshalIndent(file, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, ValidatorsFileName), contents, 0600); err != nil {
+			return err
+		}
+		file.changed = false
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	"github.com/stretchr/testify/assert"
+)
+
+// conditionalServer serves files with an ETag, answering 304 to the conditional requests
+// for files that did not change, and counts both kinds of answers
+type conditionalServer struct {
+	server *httptest.Server
+
+	lock        sync.Mutex
+	files       map[string][]byte
+	full        int
+	notModified int
+}
+
+func newConditionalServer() *conditionalServer {
+	s := &conditionalServer{files: map[string][]byte{}}
+	s.server = httptest.NewTLSServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				s.lock.Lock()
+				defer s.lock.Unlock()
+
+				content, found := s.files[strings.TrimPrefix(r.URL.Path, "/")]
+				if !found {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				etag := fmt.Sprintf(`"%x"`, sha256.Sum256(content))
+				w.Header().Set("ETag", etag)
+				if r.Header.Get("If-None-Match") == etag {
+					s.notModified++
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				s.full++
+				_, _ = w.Write(content)
+			},
+		),
+	)
+	return s
+}
+
+func (s *conditionalServer) URL() string {
+	return s.server.URL + "/"
+}
+
+func (s *conditionalServer) set(file string, content []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files[file] = content
+}
+
+func (s *conditionalServer) counts() (full, notModified int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.full, s.notModified
+}
+
+func TestConditionalRefresh(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test refreshing an unchanged "+installer.PublicIndexName, func(t *testing.T) {
+		localTestingDir := "test-conditional-refresh-index"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		indexContent, err := os.ReadFile(samplePublicIndex)
+		assert.Nil(err)
+		server := newConditionalServer()
+		defer server.server.Close()
+		server.set(installer.PublicIndexName, indexContent)
+		indexPath := server.URL() + installer.PublicIndexName
+
+		assert.Nil(installer.UpdatePublicIndex(indexPath, true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		assert.FileExists(filepath.Join(installer.Installation.WebDir, installer.ValidatorsFileName))
+
+		// Validators get read back from the pack root
+		assert.Nil(installer.SetPackRoot(localTestingDir, !CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.UpdatePublicIndex(indexPath, true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		full, notModified := server.counts()
+		assert.Equal(1, full)
+		assert.Equal(1, notModified)
+		copied, err := os.ReadFile(installer.Installation.PublicIndex)
+		assert.Nil(err)
+		assert.Equal(indexContent, copied)
+
+		// A changed index gets downloaded again
+		updatedContent := bytes.Replace(indexContent, []byte("</index>"), []byte("</index>\n"), 1)
+		server.set(installer.PublicIndexName, updatedContent)
+		assert.Nil(installer.UpdatePublicIndex(indexPath, true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		full, _ = server.counts()
+		assert.Equal(2, full)
+		copied, err = os.ReadFile(installer.Installation.PublicIndex)
+		assert.Nil(err)
+		assert.Equal(updatedContent, copied)
+	})
+
+	t.Run("test refreshing unchanged PDSC files", func(t *testing.T) {
+		localTestingDir := "test-conditional-refresh-pdsc"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		pdscContent, err := os.ReadFile(publicLocalPack123Pdsc)
+		assert.Nil(err)
+		server := newConditionalServer()
+		defer server.server.Close()
+		server.set("TheVendor.PublicLocalPack.pdsc", pdscContent)
+		assert.Nil(installer.Installation.PublicIndexXML.AddPdsc(xml.PdscTag{
+			Vendor:  "TheVendor",
+			Name:    "PublicLocalPack",
+			Version: "1.2.3",
+			URL:     server.URL(),
+		}))
+
+		pdscFilePath := filepath.Join(installer.Installation.WebDir, "TheVendor.PublicLocalPack.pdsc")
+		assert.Nil(installer.DownloadPDSCFiles(false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		assert.FileExists(pdscFilePath)
+		assert.Nil(installer.DownloadPDSCFiles(false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		full, notModified := server.counts()
+		assert.Equal(1, full)
+		assert.Equal(1, notModified)
+		assert.FileExists(pdscFilePath)
+
+		// A PDSC file changed locally since downloaded gets downloaded again
+		utils.UnsetReadOnly(pdscFilePath)
+		assert.Nil(os.WriteFile(pdscFilePath, append(pdscContent, '\n'), 0600))
+		assert.Nil(installer.DownloadPDSCFiles(false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		full, _ = server.counts()
+		assert.Equal(2, full)
+		downloaded, err := os.ReadFile(pdscFilePath)
+		assert.Nil(err)
+		assert.Equal(pdscContent, downloaded)
+	})
+
+	t.Run("test keeping the validators of PDSC files downloaded in a transaction", func(t *testing.T) {
+		localTestingDir := "test-conditional-refresh-transaction"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		pdscContent, err := os.ReadFile(publicLocalPack123Pdsc)
+		assert.Nil(err)
+		server := newConditionalServer()
+		defer server.server.Close()
+		server.set("TheVendor.PublicLocalPack.pdsc", pdscContent)
+		addPdscTag := func() {
+			assert.Nil(installer.Installation.PublicIndexXML.AddPdsc(xml.PdscTag{
+				Vendor:  "TheVendor",
+				Name:    "PublicLocalPack",
+				Version: "1.2.3",
+				URL:     server.URL(),
+			}))
+		}
+		addPdscTag()
+		validatorsPath := filepath.Join(installer.Installation.WebDir, installer.ValidatorsFileName)
+
+		// Rolled back along with the PDSC file
+		assert.Nil(installer.BeginTransaction("update"))
+		assert.Nil(installer.DownloadPDSCFiles(false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		assert.NotNil(installer.EndTransaction(errs.ErrFileNotFound))
+		assert.NoFileExists(validatorsPath)
+
+		// Written once committed
+		addPdscTag()
+		assert.Nil(installer.BeginTransaction("update"))
+		assert.Nil(installer.DownloadPDSCFiles(false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		assert.NoFileExists(validatorsPath)
+		assert.Nil(installer.EndTransaction(nil))
+		assert.FileExists(validatorsPath)
+
+		// and read back by the next run
+		assert.Nil(installer.SetPackRoot(localTestingDir, !CreatePackRoot))
+		installer.UnlockPackRoot()
+		addPdscTag()
+		assert.Nil(installer.DownloadPDSCFiles(false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		full, notModified := server.counts()
+		assert.Equal(2, full)
+		assert.Equal(1, notModified)
+	})
+}
@@ -0,0 +1,262 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/semaphore"
+)
+
+// IndexSourcesFileName is the file, in the .Web directory, telling where the entries of the
+// public index come from when it got merged from a vendor index (.vidx) file
+const IndexSourcesFileName = "index_sources.json"
+
+// indexSource is an index merged into the public index
+type indexSource struct {
+	// URL of the vendor index file, or of the .vidx file for the pdsc tags it lists itself
+	URL string `json:"url"`
+
+	// Vendor the index file is listed for in the .vidx file
+	Vendor string `json:"vendor,omitempty"`
+
+	// Packs taken from the index, as Vendor.Pack
+	Packs []string `json:"packs,omitempty"`
+
+	// Error the index could not be read with, if any
+	Error string `json:"error,omitempty"`
+
+	// Stale tells that the packs of the index, which could not be read, are the ones of the previous public index
+	Stale bool `json:"stale,omitempty"`
+}
+
+// indexSources is the content of an IndexSourcesFileName file
+type indexSources struct {
+	// Vidx is the URL, or path, of the .vidx file the public index got merged from
+	Vidx string `json:"vidx"`
+
+	// Sources are the indexes merged, the .vidx file itself coming first
+	Sources []indexSource `json:"sources"`
+}
+
+// readIndexSources returns the sources of the public index, nil if it did not get merged from a .vidx file
+func readIndexSources() *indexSources {
+	filePath := filepath.Join(Installation.WebDir, IndexSourcesFileName)
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil
+	}
+	sources := &indexSources{}
+	if err := json.Unmarshal(contents, sources); err != nil || sources.Vidx == "" {
+		log.Debugf("Ignoring %q: %v", filePath, err)
+		return nil
+	}
+	return sources
+}
+
+// writeIndexSources writes down the sources of the public index, or removes them if nil
+func writeIndexSources(sources *indexSources) error {
+	filePath := filepath.Join(Installation.WebDir, IndexSourcesFileName)
+	if sources == nil {
+		if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	contents, err := json.MarshalIndent(sources, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, contents, 0600)
+}
+
+// publicIndexURL returns where the public index gets updated from: the .vidx file it got
+// merged from, if any, or else the index.pidx file at the URL it gives
+func publicIndexURL() string {
+	if sources := readIndexSources(); sources != nil {
+		return sources.Vidx
+	}
+	return strings.TrimSuffix(Installation.PublicIndexXML.URL, "/") + "/" + PublicIndexName
+}
+
+// isVidx tells whether indexPath refers to a vendor index (.vidx) file
+func isVidx(indexPath string) bool {
+	return strings.HasSuffix(strings.ToLower(indexPath), utils.VidxExtension)
+}
+
+// readVendorIndex reads the pdsc tags of the vendor index file at URL, either remote or local
+func readVendorIndex(URL string, insecureSkipVerify bool, timeout int) ([]xml.PdscTag, error) {
+	var filePath string
+	if strings.HasPrefix(URL, "http://") || strings.HasPrefix(URL, "https://") {
+		var err error
+		filePath, err = utils.DownloadFile(URL, false, false, false, insecureSkipVerify, timeout)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(filePath)
+	} else {
+		var err error
+		if filePath, err = utils.FileURLToPath(URL); err != nil {
+			return nil, err
+		}
+		if !utils.FileExists(filePath) {
+			return nil, fmt.Errorf("%q: %w", filePath, errs.ErrFileNotFound)
+		}
+	}
+
+	pidxXML := xml.NewPidxXML(filePath, false)
+	if err := pidxXML.Read(); err != nil {
+		return nil, err
+	}
+	tags := pidxXML.ListPdscTags()
+	slices.SortFunc(tags, func(a, b xml.PdscTag) int {
+		return strings.Compare(a.Key(), b.Key())
+	})
+	return tags, nil
+}
+
+// staleVendorTags returns the pdsc tags of the current public index which came from the vendor index
+// at URL, according to previous, or else the ones of vendor, for them to be kept while the vendor
+// index cannot be read
+func staleVendorTags(previous *indexSources, URL, vendor string) []xml.PdscTag {
+	publicXML := xml.NewPidxXML(Installation.PublicIndex, false)
+	if !utils.FileExists(Installation.PublicIndex) || publicXML.Read() != nil {
+		return nil
+	}
+
+	var packs map[string]bool
+	if previous != nil {
+		for _, source := range previous.Sources {
+			if source.URL == URL {
+				packs = map[string]bool{}
+				for _, pack := range source.Packs {
+					packs[strings.ToLower(pack)] = true
+				}
+				break
+			}
+		}
+	}
+
+	tags := []xml.PdscTag{}
+	for _, tag := range publicXML.ListPdscTags() {
+		if packs != nil && packs[strings.ToLower(tag.VName())] || packs == nil && strings.EqualFold(tag.Vendor, vendor) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// mergeVendorIndex merges the pdsc tags listed in the .vidx file at vidxPath, got from vidxURL,
+// with those of the vendor index files it lists, into a pidx file which path gets returned.
+// A pack listed by several indexes keeps its latest version. Vendor index files that cannot
+// be read are reported, without failing the merge, and the packs the current public index
+// got from them are kept.
+func mergeVendorIndex(vidxPath, vidxURL string, insecureSkipVerify bool, concurrency int, timeout int) (string, *indexSources, error) {
+	vidxXML := xml.NewVidxXML(vidxPath)
+	if err := vidxXML.Read(); err != nil {
+		return "", nil, err
+	}
+	log.Infof("Merging %d vendor index(es) listed in %q", len(vidxXML.Vindex.Pidxs), vidxURL)
+
+	// Fetch the vendor index files, keeping them in the order of the .vidx file
+	pidxTags := vidxXML.Vindex.Pidxs
+	vendorTags := make([][]xml.PdscTag, len(pidxTags))
+	vendorErrs := make([]error, len(pidxTags))
+	fetch := func(i int) {
+		vendorTags[i], vendorErrs[i] = readVendorIndex(pidxTags[i].PidxURL(), insecureSkipVerify, timeout)
+	}
+
+	ctx := context.TODO()
+	concurrency = CheckConcurrency(concurrency)
+	sem := semaphore.NewWeighted(int64(concurrency))
+	for i := range pidxTags {
+		if concurrency == 0 {
+			fetch(i)
+			continue
+		}
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return "", nil, err
+		}
+		go func(i int) {
+			defer sem.Release(1)
+			fetch(i)
+		}(i)
+	}
+	if concurrency > 0 {
+		if err := sem.Acquire(ctx, int64(concurrency)); err != nil {
+			return "", nil, err
+		}
+	}
+
+	mergedPath := filepath.Join(Installation.DownloadDir, PublicIndexName)
+	mergedXML := xml.NewPidxXML(mergedPath, false)
+	mergedXML.Clear()
+	mergedXML.SchemaVersion = "1.1.0"
+	mergedXML.Vendor = vidxXML.Vendor
+	mergedXML.URL = vidxXML.URL
+	mergedXML.TimeStamp = time.Now().Format(time.RFC3339Nano)
+
+	previous := readIndexSources()
+	sources := &indexSources{Vidx: vidxURL}
+	sourceOf := map[string]int{} // index in sources.Sources, by lowercase Vendor.Pack
+	merge := func(source int, tags []xml.PdscTag) {
+		for _, tag := range tags {
+			name := strings.ToLower(tag.VName())
+			if found := mergedXML.FindPdscNameTags(tag); len(found) > 0 {
+				if utils.SemverCompare(tag.Version, found[0].Version) <= 0 {
+					continue
+				}
+				log.Debugf("%q from %q replaces version %s from %q", tag.Key(), sources.Sources[source].URL, found[0].Version, sources.Sources[sourceOf[name]].URL)
+				_ = mergedXML.RemovePdsc(found[0])
+			}
+			_ = mergedXML.AddPdsc(tag)
+			sourceOf[name] = source
+		}
+	}
+
+	sources.Sources = append(sources.Sources, indexSource{URL: vidxURL, Vendor: vidxXML.Vendor})
+	merge(0, vidxXML.Pindex.Pdscs)
+	unreachable := 0
+	for i, pidxTag := range pidxTags {
+		source := indexSource{URL: pidxTag.PidxURL(), Vendor: pidxTag.Vendor}
+		if vendorErrs[i] != nil {
+			vendorTags[i] = staleVendorTags(previous, source.URL, pidxTag.Vendor)
+			log.Warnf("Cannot read the index of vendor %q at %q, keeping its %d pack(s) of the public index: %v", pidxTag.Vendor, source.URL, len(vendorTags[i]), vendorErrs[i])
+			source.Error = vendorErrs[i].Error()
+			source.Stale = true
+			unreachable++
+		}
+		sources.Sources = append(sources.Sources, source)
+		merge(len(sources.Sources)-1, vendorTags[i])
+	}
+	if unreachable > 0 {
+		log.Warnf("%d of %d vendor index(es) could not be read, the public index keeps the packs it had from them", unreachable, len(pidxTags))
+	}
+
+	for _, tag := range mergedXML.ListPdscTags() {
+		source := &sources.Sources[sourceOf[strings.ToLower(tag.VName())]]
+		source.Packs = append(source.Packs, tag.VName())
+	}
+	for i := range sources.Sources {
+		slices.Sort(sources.Sources[i].Packs)
+	}
+
+	if err := mergedXML.Write(); err != nil {
+		return "", nil, err
+	}
+	return mergedPath, sources, nil
+}
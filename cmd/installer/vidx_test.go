@@ -0,0 +1,156 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package installer_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/installer"
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	"github.com/stretchr/testify/assert"
+)
+
+// vendorPidx returns the content of the index file of a vendor listing a single pack
+func vendorPidx(vendor, name, version, URL string) []byte {
+	return fmt.Appendf(nil, `<?xml version="1.0" encoding="UTF-8"?>
+<index schemaVersion="1.1.0">
+  <vendor>%[1]s</vendor>
+  <url>%[4]s</url>
+  <pindex>
+    <pdsc vendor="%[1]s" name="%[2]s" version="%[3]s" url="%[4]s"/>
+  </pindex>
+</index>
+`, vendor, name, version, URL)
+}
+
+func TestVendorIndex(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test updating the public index from a vidx file", func(t *testing.T) {
+		localTestingDir := "test-update-public-index-vidx"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		server := newConditionalServer()
+		defer server.server.Close()
+		server.set("vendors.vidx", fmt.Appendf(nil, `<?xml version="1.0" encoding="UTF-8"?>
+<index schemaVersion="1.1.0">
+  <vendor>Vendors</vendor>
+  <url>%[1]s</url>
+  <vindex>
+    <pidx vendor="TheVendor" url="%[1]sthevendor/"/>
+    <pidx vendor="MissingVendor" url="%[1]smissingvendor/"/>
+  </vindex>
+  <pindex>
+    <pdsc vendor="InlineVendor" name="InlinePack" version="1.0.0" url="%[1]sinline/"/>
+    <pdsc vendor="TheVendor" name="ThePack" version="1.0.0" url="%[1]sinline/"/>
+  </pindex>
+</index>
+`, server.URL()))
+		server.set("thevendor/TheVendor.pidx", vendorPidx("TheVendor", "ThePack", "1.2.3", server.URL()+"thevendor/"))
+
+		// The unreachable vendor index does not fail the update
+		vidxURL := server.URL() + "vendors.vidx"
+		assert.Nil(installer.UpdatePublicIndex(vidxURL, true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+
+		tags := installer.Installation.PublicIndexXML.FindPdscNameTags(xml.PdscTag{Vendor: "TheVendor", Name: "ThePack"})
+		assert.Len(tags, 1)
+		assert.Equal("1.2.3", tags[0].Version)
+		assert.Equal(server.URL()+"thevendor/", tags[0].URL)
+		tags = installer.Installation.PublicIndexXML.FindPdscNameTags(xml.PdscTag{Vendor: "InlineVendor", Name: "InlinePack"})
+		assert.Len(tags, 1)
+
+		// The sources of the packs are kept
+		contents, err := os.ReadFile(filepath.Join(installer.Installation.WebDir, installer.IndexSourcesFileName))
+		assert.Nil(err)
+		var sources struct {
+			Vidx    string `json:"vidx"`
+			Sources []struct {
+				URL   string   `json:"url"`
+				Packs []string `json:"packs"`
+				Error string   `json:"error"`
+				Stale bool     `json:"stale"`
+			} `json:"sources"`
+		}
+		assert.Nil(json.Unmarshal(contents, &sources))
+		assert.Equal(vidxURL, sources.Vidx)
+		assert.Len(sources.Sources, 3)
+		assert.Equal([]string{"InlineVendor.InlinePack"}, sources.Sources[0].Packs)
+		assert.Equal(server.URL()+"thevendor/TheVendor.pidx", sources.Sources[1].URL)
+		assert.Equal([]string{"TheVendor.ThePack"}, sources.Sources[1].Packs)
+		assert.Empty(sources.Sources[1].Error)
+		assert.Empty(sources.Sources[2].Packs)
+		assert.NotEmpty(sources.Sources[2].Error)
+
+		// Updating the index again goes through the vidx file
+		server.set("thevendor/TheVendor.pidx", vendorPidx("TheVendor", "ThePack", "1.2.4", server.URL()+"thevendor/"))
+		server.set("missingvendor/MissingVendor.pidx", vendorPidx("MissingVendor", "ThePack", "1.0.0", server.URL()+"missingvendor/"))
+		assert.Nil(installer.UpdatePublicIndex("", true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		tags = installer.Installation.PublicIndexXML.FindPdscNameTags(xml.PdscTag{Vendor: "TheVendor", Name: "ThePack"})
+		assert.Len(tags, 1)
+		assert.Equal("1.2.4", tags[0].Version)
+		tags = installer.Installation.PublicIndexXML.FindPdscNameTags(xml.PdscTag{Vendor: "MissingVendor", Name: "ThePack"})
+		assert.Len(tags, 1)
+
+		// The packs of a vendor index that cannot be read anymore are kept
+		server.set("missingvendor/MissingVendor.pidx", []byte("not an index"))
+		assert.Nil(installer.UpdatePublicIndex("", true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		tags = installer.Installation.PublicIndexXML.FindPdscNameTags(xml.PdscTag{Vendor: "MissingVendor", Name: "ThePack"})
+		assert.Len(tags, 1)
+		assert.Equal(server.URL()+"missingvendor/", tags[0].URL)
+		contents, err = os.ReadFile(filepath.Join(installer.Installation.WebDir, installer.IndexSourcesFileName))
+		assert.Nil(err)
+		assert.Nil(json.Unmarshal(contents, &sources))
+		assert.Equal([]string{"MissingVendor.ThePack"}, sources.Sources[2].Packs)
+		assert.NotEmpty(sources.Sources[2].Error)
+		assert.True(sources.Sources[2].Stale)
+		assert.False(sources.Sources[1].Stale)
+
+		// Until a pidx file gets used
+		publicIndex, err := os.ReadFile(installer.Installation.PublicIndex)
+		assert.Nil(err)
+		server.set(installer.PublicIndexName, publicIndex)
+		assert.Nil(installer.UpdatePublicIndex(server.URL()+installer.PublicIndexName, true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+		assert.NoFileExists(filepath.Join(installer.Installation.WebDir, installer.IndexSourcesFileName))
+	})
+
+	t.Run("test refreshing the public index of a vidx file before adding packs", func(t *testing.T) {
+		localTestingDir := "test-update-public-index-vidx-if-online"
+		assert.Nil(installer.SetPackRoot(localTestingDir, CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.ReadIndexFiles())
+		defer removePackRoot(localTestingDir)
+
+		server := newConditionalServer()
+		defer server.server.Close()
+		server.set("vendors.vidx", fmt.Appendf(nil, `<?xml version="1.0" encoding="UTF-8"?>
+<index schemaVersion="1.1.0">
+  <vendor>Vendors</vendor>
+  <url>%[1]s</url>
+  <vindex>
+    <pidx vendor="TheVendor" url="%[1]sthevendor/"/>
+  </vindex>
+</index>
+`, server.URL()))
+		server.set("thevendor/TheVendor.pidx", vendorPidx("TheVendor", "ThePack", "1.2.3", server.URL()+"thevendor/"))
+		assert.Nil(installer.UpdatePublicIndex(server.URL()+"vendors.vidx", true, false, false, false, false, false, !InsecureSkipVerify, Concurrency, Timeout))
+
+		// The next run refreshes the index from the vidx file, not from keil.com
+		server.set("thevendor/TheVendor.pidx", vendorPidx("TheVendor", "ThePack", "1.2.4", server.URL()+"thevendor/"))
+		assert.Nil(installer.SetPackRoot(localTestingDir, !CreatePackRoot))
+		installer.UnlockPackRoot()
+		assert.Nil(installer.UpdatePublicIndexIfOnline())
+		assert.FileExists(filepath.Join(installer.Installation.WebDir, installer.IndexSourcesFileName))
+		tags := installer.Installation.PublicIndexXML.FindPdscNameTags(xml.PdscTag{Vendor: "TheVendor", Name: "ThePack"})
+		assert.Len(tags, 1)
+		assert.Equal("1.2.4", tags[0].Version)
+	})
+}
@@ -25,6 +25,7 @@ func main() {
 	utils.SetUserAgent("CMSIS-Toolbox cpackget/" + version)
 	cmd := commands.NewCli()
 	err := cmd.Execute()
+	utils.LogConnectionStats()
 	if err != nil {
 		if !errs.AlreadyLogged(err) {
 			log.Error(err)
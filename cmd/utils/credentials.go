@@ -0,0 +1,247 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CredentialsEnvPrefix starts the environment variables giving the credentials of a host,
+// e.g. CPACKGET_AUTH_PACKS_ACME_COM for packs.acme.com, or CPACKGET_AUTH_PACKS_ACME_COM_8443
+// for packs.acme.com:8443. Their value is either "<username>:<password>" or "Bearer <token>".
+const CredentialsEnvPrefix = "CPACKGET_AUTH_"
+
+// Credentials authenticate the requests to a host, either with a bearer token or with a username and password
+type Credentials struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// IsEmpty tells whether there is nothing to authenticate with
+func (c *Credentials) IsEmpty() bool {
+	return c.Username == "" && c.Password == "" && c.Token == ""
+}
+
+// apply adds the Authorization header to req
+func (c *Credentials) apply(req *http.Request) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+		return
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+}
+
+// parseCredentials parses the value of a CredentialsEnvPrefix environment variable
+func parseCredentials(value string) Credentials {
+	if token, found := strings.CutPrefix(value, "Bearer "); found {
+		return Credentials{Token: strings.TrimSpace(token)}
+	}
+	username, password, _ := strings.Cut(value, ":")
+	return Credentials{Username: username, Password: password}
+}
+
+// gCredentials holds the credentials of each host, by host name with an optional port
+var gCredentials struct {
+	lock  sync.Mutex
+	hosts map[string]Credentials
+}
+
+// SetCredentials sets the credentials of host, with an optional port, replacing the ones set before
+func SetCredentials(host string, credentials Credentials) {
+	gCredentials.lock.Lock()
+	defer gCredentials.lock.Unlock()
+	if gCredentials.hosts == nil {
+		gCredentials.hosts = map[string]Credentials{}
+	}
+	gCredentials.hosts[strings.ToLower(host)] = credentials
+}
+
+// ResetCredentials forgets the credentials set so far
+func ResetCredentials() {
+	gCredentials.lock.Lock()
+	defer gCredentials.lock.Unlock()
+	gCredentials.hosts = nil
+}
+
+// credentialsEnvVar returns the environment variable giving the credentials of host
+func credentialsEnvVar(host string) string {
+	return CredentialsEnvPrefix + strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || (!unicode.IsLetter(r) && !unicode.IsDigit(r)) {
+			return '_'
+		}
+		return unicode.ToUpper(r)
+	}, host)
+}
+
+// getCredentials returns the credentials to request u with. Those of the host and port
+// take precedence over those of the host only, and environment variables over the ones set.
+func getCredentials(u *url.URL) (Credentials, bool) {
+	hosts := []string{strings.ToLower(u.Host)}
+	if u.Port() != "" {
+		hosts = append(hosts, strings.ToLower(u.Hostname()))
+	}
+
+	for _, host := range hosts {
+		if value := os.Getenv(credentialsEnvVar(host)); value != "" {
+			return parseCredentials(value), true
+		}
+	}
+
+	gCredentials.lock.Lock()
+	defer gCredentials.lock.Unlock()
+	for _, host := range hosts {
+		if credentials, found := gCredentials.hosts[host]; found {
+			return credentials, true
+		}
+	}
+	return Credentials{}, false
+}
+
+// authenticate adds to req the credentials given, or else those of its host, if any.
+// They are not sent again when redirected to another host, see http.Client.
+func authenticate(req *http.Request, credentials *Credentials) {
+	if credentials == nil {
+		hostCredentials, found := getCredentials(req.URL)
+		if !found {
+			return
+		}
+		credentials = &hostCredentials
+	}
+	if !credentials.IsEmpty() {
+		credentials.apply(req)
+	}
+}
+
+// splitCredentials removes the user information from URL, returning it as credentials, nil if there is none
+func splitCredentials(URL string) (string, *Credentials) {
+	parsedURL, err := url.Parse(URL)
+	if err != nil || parsedURL.User == nil {
+		return URL, nil
+	}
+	password, _ := parsedURL.User.Password()
+	credentials := &Credentials{Username: parsedURL.User.Username(), Password: password}
+	parsedURL.User = nil
+	return parsedURL.String(), credentials
+}
+
+// RemoveCredentials removes the user information from URL, if any, so that it can be logged
+// and written down without leaking credentials
+func RemoveCredentials(URL string) string {
+	URL, _ = splitCredentials(URL)
+	return URL
+}
+
+// StripCredentials removes the user information from URL, if any, keeping it as the credentials
+// of its host for the next downloads
+func StripCredentials(URL string) string {
+	URL, credentials := splitCredentials(URL)
+	if credentials != nil {
+		parsedURL, _ := url.Parse(URL)
+		SetCredentials(parsedURL.Host, *credentials)
+	}
+	return URL
+}
+
+// DefaultNetrcFile returns the netrc file read when none is configured: the one in the
+// NETRC environment variable, or else .netrc (_netrc on Windows) in the home directory
+func DefaultNetrcFile() string {
+	if netrc := os.Getenv("NETRC"); netrc != "" {
+		return netrc
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// LoadNetrc sets the credentials of the machines listed in a netrc file, if it exists. A machine
+// with a password but no login gets it as a bearer token. The "default" entry is ignored, so
+// that credentials are only sent to the hosts they are meant for.
+func LoadNetrc(path string) error {
+	file, err := os.Open(path) // #nosec
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var host string
+	var credentials Credentials
+	addMachine := func() {
+		if host == "" {
+			return
+		}
+		if credentials.Username == "" {
+			credentials = Credentials{Token: credentials.Password}
+		}
+		SetCredentials(host, credentials)
+	}
+
+	scanner := bufio.NewScanner(file)
+	inMacro := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			// Macros end with an empty line
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			if strings.HasPrefix(fields[i], "#") {
+				break
+			}
+			value := ""
+			if i+1 < len(fields) {
+				value = fields[i+1]
+			}
+			switch fields[i] {
+			case "machine":
+				addMachine()
+				host, credentials = value, Credentials{}
+				i++
+			case "default":
+				addMachine()
+				host, credentials = "", Credentials{}
+			case "login":
+				credentials.Username = value
+				i++
+			case "password":
+				credentials.Password = value
+				i++
+			case "account":
+				i++
+			case "macdef":
+				inMacro = true
+				i = len(fields)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	addMachine()
+	log.Debugf("Read the credentials in %q", path)
+	return nil
+}
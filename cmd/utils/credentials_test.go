@@ -0,0 +1,151 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package utils_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// authServer only serves requests authenticated with the given Authorization header
+func authServer(authorization string) *httptest.Server {
+	return httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != authorization {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				fmt.Fprint(w, "all good")
+			},
+		),
+	)
+}
+
+func TestCredentials(t *testing.T) {
+	assert := assert.New(t)
+	defer utils.ResetCredentials()
+
+	t.Run("test download with the credentials of the host", func(t *testing.T) {
+		utils.ResetCredentials()
+		server := authServer("Bearer my-token")
+		defer server.Close()
+
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, false, false, false, false, 0)
+		assert.Equal(errs.ErrUnauthorized, errors.Unwrap(err))
+
+		utils.SetCredentials(strings.TrimPrefix(server.URL, "http://"), utils.Credentials{Token: "my-token"})
+		_, err = utils.DownloadFile(server.URL+"/"+fileName, false, false, false, false, 0)
+		assert.Nil(err)
+	})
+
+	t.Run("test download with the credentials of an environment variable", func(t *testing.T) {
+		utils.ResetCredentials()
+		server := authServer("Basic dXNlcjpwYXNz") // user:pass
+		defer server.Close()
+
+		// The variable of the host and port is used before the one of the host
+		t.Setenv(utils.CredentialsEnvPrefix+"127_0_0_1", "user:wrong")
+		t.Setenv(utils.CredentialsEnvPrefix+strings.ToUpper(strings.NewReplacer(".", "_", ":", "_").Replace(strings.TrimPrefix(server.URL, "http://"))), "user:pass")
+
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, false, false, false, false, 0)
+		assert.Nil(err)
+	})
+
+	t.Run("test credentials in the URL stay out of the logs", func(t *testing.T) {
+		utils.ResetCredentials()
+		server := authServer("Basic dXNlcjpzZWNyZXQ=") // user:secret
+		defer server.Close()
+
+		var logs bytes.Buffer
+		log.SetOutput(&logs)
+		log.SetLevel(log.DebugLevel)
+		defer log.SetOutput(os.Stdout)
+		defer log.SetLevel(log.InfoLevel)
+
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		URL := strings.Replace(server.URL, "http://", "http://user:secret@", 1) + "/" + fileName
+		_, err := utils.DownloadFile(URL, false, false, false, false, 0)
+		assert.Nil(err)
+		assert.Contains(logs.String(), server.URL+"/"+fileName)
+		assert.NotContains(logs.String(), "secret")
+
+		// Only the given URL is downloaded with them
+		_, err = utils.DownloadFile(server.URL+"/"+fileName, false, false, false, false, 0)
+		assert.Equal(errs.ErrUnauthorized, errors.Unwrap(err))
+
+		// Unless kept for the host
+		assert.Equal(server.URL+"/"+fileName, utils.StripCredentials(URL))
+		_, err = utils.DownloadFile(server.URL+"/"+fileName, false, false, false, false, 0)
+		assert.Nil(err)
+	})
+
+	t.Run("test credentials are not sent to other hosts on redirect", func(t *testing.T) {
+		utils.ResetCredentials()
+		var authorization string
+		otherServer := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					authorization = r.Header.Get("Authorization")
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		defer otherServer.Close()
+		server := httptest.NewServer(http.RedirectHandler(strings.Replace(otherServer.URL, "127.0.0.1", "localhost", 1)+"/file.txt", http.StatusFound))
+		defer server.Close()
+
+		utils.SetCredentials(strings.TrimPrefix(server.URL, "http://"), utils.Credentials{Token: "my-token"})
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, false, false, false, false, 0)
+		assert.Nil(err)
+		assert.Empty(authorization)
+	})
+
+	t.Run("test reading a netrc file", func(t *testing.T) {
+		utils.ResetCredentials()
+		basicServer := authServer("Basic dXNlcjpwYXNz") // user:pass
+		defer basicServer.Close()
+		tokenServer := authServer("Bearer my-token")
+		defer tokenServer.Close()
+
+		netrc := filepath.Join(t.TempDir(), ".netrc")
+		assert.Nil(os.WriteFile(netrc, []byte(fmt.Sprintf(`# Credentials
+machine %s login user password pass
+macdef init
+	machine not-a-machine login nobody
+
+machine %s
+	password my-token
+default login anonymous password guest
+`, strings.TrimPrefix(basicServer.URL, "http://"), strings.TrimPrefix(tokenServer.URL, "http://"))), 0600))
+		assert.Nil(utils.LoadNetrc(netrc))
+		assert.Nil(utils.LoadNetrc(filepath.Join(t.TempDir(), "missing")))
+
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err := utils.DownloadFile(basicServer.URL+"/"+fileName, false, false, false, false, 0)
+		assert.Nil(err)
+		_, err = utils.DownloadFile(tokenServer.URL+"/"+fileName, false, false, false, false, 0)
+		assert.Nil(err)
+	})
+}
@@ -0,0 +1,263 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// PartialDownloadExtension is appended to the name of a file being downloaded, the file
+// getting its final name once completely downloaded
+const PartialDownloadExtension = ".part"
+
+// partialDownloadInfoPath returns where the information needed to resume the download
+// of the partially downloaded file in partPath is kept
+func partialDownloadInfoPath(partPath string) string {
+	return partPath + ".json"
+}
+
+// partialDownload describes a partially downloaded file, written next to it
+// for the download to be resumed later on
+type partialDownload struct {
+	// URL the file is downloaded from
+	URL string `json:"url"`
+
+	// Validator is the strong ETag, or else the Last-Modified date, of the file when
+	// its download started. It goes in the If-Range header when resuming the download,
+	// for the server to send the whole file again if it changed in the meantime.
+	Validator string `json:"validator"`
+
+	// size is the number of bytes downloaded so far
+	size int64
+}
+
+// newPartialDownload returns the information needed to resume the download of the
+// response, or nil if the server does not allow resuming it
+func newPartialDownload(URL string, resp *http.Response) *partialDownload {
+	if resp.Header.Get("Accept-Ranges") == "none" {
+		return nil
+	}
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		// Weak ETags cannot be used in If-Range
+		validator = resp.Header.Get("Last-Modified")
+	}
+	if validator == "" {
+		return nil
+	}
+	return &partialDownload{URL: URL, Validator: validator}
+}
+
+// readPartialDownload returns the partial download of URL left in partPath,
+// or nil if there is none that can be resumed
+func readPartialDownload(partPath, URL string) *partialDownload {
+	info, err := os.Stat(partPath)
+	if err != nil || info.Size() == 0 {
+		return nil
+	}
+	contents, err := os.ReadFile(partialDownloadInfoPath(partPath)) // #nosec
+	if err != nil {
+		return nil
+	}
+	partial := &partialDownload{}
+	if err := json.Unmarshal(contents, partial); err != nil || partial.URL != URL || partial.Validator == "" {
+		return nil
+	}
+	partial.size = info.Size()
+	return partial
+}
+
+// write saves the information of the partial download in partPath
+func (p *partialDownload) write(partPath string) error {
+	contents, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(partialDownloadInfoPath(partPath), contents, 0600)
+}
+
+// removePartialDownload removes the partially downloaded file in partPath, along with its information
+func removePartialDownload(partPath string) {
+	log.Debugf("Removing %q", partPath)
+	_ = os.Remove(partPath)
+	_ = os.Remove(partialDownloadInfoPath(partPath))
+}
+
+// contentRangeStart returns the first byte of the range in a Content-Range
+// header, e.g. 100 for "bytes 100-199/200", or -1 if it cannot be parsed
+func contentRangeStart(contentRange string) int64 {
+	byteRange, found := strings.CutPrefix(contentRange, "bytes ")
+	if !found {
+		return -1
+	}
+	first, _, found := strings.Cut(byteRange, "-")
+	if !found {
+		return -1
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
+
+// Validators identify the version of a downloaded file, for the next download to only
+// happen if the file changed since, see DownloadFileIfModified
+type Validators struct {
+	// ETag is the entity tag of the file, sent back in the If-None-Match header
+	ETag string `json:"etag,omitempty"`
+
+	// LastModified is the modification date of the file, sent back in the If-Modified-Since header
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// newValidators returns the validators of the file in the response
+func newValidators(resp *http.Response) Validators {
+	return Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// IsEmpty tells whether there is nothing to make a conditional request with
+func (v *Validators) IsEmpty() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// setConditionalHeaders makes req download the file only if it changed
+func (v *Validators) setConditionalHeaders(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// update replaces the validators the server sent again in a 304 response
+func (v *Validators) update(resp *http.Response) {
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		v.ETag = etag
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		v.LastModified = lastModified
+	}
+}
+
+// gDownloadRetries is the number of times a download failing on a transient error gets retried
+var gDownloadRetries = 3
+
+// RetryBaseDelay is the delay before retrying a failing download the first time,
+// doubling on each retry
+var RetryBaseDelay = time.Second
+
+// MaxRetryDelay caps the delay before retrying a failing download, including
+// the one asked for by the server in a Retry-After header
+var MaxRetryDelay = time.Minute
+
+func SetDownloadRetries(retries int) {
+	gDownloadRetries = retries
+}
+
+func GetDownloadRetries() int {
+	return gDownloadRetries
+}
+
+// transientError is a download failure that might not happen again, e.g. a reset
+// connection or a server temporarily unavailable, so that the download is worth retrying
+type transientError struct {
+	// err is returned to the caller if the download is not retried
+	err error
+
+	// cause tells what went wrong, for the logs
+	cause error
+
+	// retryAfter is the delay the server asked to wait for before retrying, 0 if none
+	retryAfter time.Duration
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+// isTransientNetworkError tells whether the error of an HTTP request is a connection
+// error or a timeout, as opposed to e.g. an invalid URL or certificate
+func isTransientNetworkError(err error) bool {
+	// url.Error implements net.Error whatever the error it wraps
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, errs.ErrHTTPtimeout) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isTransientStatus tells whether the server might answer differently later on
+func isTransientStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests ||
+		(statusCode >= http.StatusInternalServerError && statusCode != http.StatusNotImplemented && statusCode != http.StatusHTTPVersionNotSupported)
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, in seconds or as
+// an HTTP date, or 0 if there is none
+func parseRetryAfter(retryAfter string) time.Duration {
+	if retryAfter == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(retryAfter); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// retryDelay returns how long to wait before the given retry, starting at 1: the delay
+// asked for by the server if any, or else an exponential backoff with jitter, so that
+// several cpackget runs failing together do not all retry at the same time
+func retryDelay(retry int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, MaxRetryDelay)
+	}
+	delay := min(RetryBaseDelay<<(retry-1), MaxRetryDelay)
+	if delay <= 0 {
+		// Overflow of the shift
+		delay = MaxRetryDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// readErrorRecorder keeps the error of the last read from r, to tell apart
+// failures to receive a download from failures to write it
+type readErrorRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrorRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
@@ -48,6 +48,7 @@ func (p *EncodedProgress) Write(bs []byte) (int, error) {
  * J: Total number of files beeing processed
  * L: License file follows
  * O: Online connection Status [offline|online]
+ * R: Retry number of the download of the file
  */
 func (p *EncodedProgress) Print() {
 	newPercent := int(float64(p.current) / float64(p.total) * 100)
@@ -0,0 +1,325 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// HTTPClientOptions configures the connections shared by all downloads
+type HTTPClientOptions struct {
+	// MaxIdleConnsPerHost is the number of connections kept open to each host
+	// for the next downloads, 0 meaning http.DefaultMaxIdleConnsPerHost
+	MaxIdleConnsPerHost int
+
+	// IdleConnTimeout is how long an unused connection is kept open
+	IdleConnTimeout time.Duration
+
+	// DisableHTTP2 sticks to HTTP/1.1, even with servers supporting HTTP/2
+	DisableHTTP2 bool
+
+	// Proxy is the proxy all requests go through, nil meaning the one set
+	// in the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+	Proxy *url.URL
+
+	// RootCAs are the certificate authorities servers are verified with, nil meaning
+	// the ones of the system, see LoadCACertificates
+	RootCAs *x509.CertPool
+
+	// ClientCertificates are the certificates presented to the servers asking for one,
+	// by host name with an optional port, see LoadClientCertificate
+	ClientCertificates map[string]tls.Certificate
+}
+
+var gHTTPClientOptions = HTTPClientOptions{
+	MaxIdleConnsPerHost: 20,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// transportKey tells apart the transports shared by downloads
+type transportKey struct {
+	// insecureSkipVerify tells whether the certificates of the servers are not verified
+	insecureSkipVerify bool
+
+	// clientCertificateHost is the key in HTTPClientOptions.ClientCertificates of the
+	// certificate presented to the server, "" if none
+	clientCertificateHost string
+}
+
+// sharedTransports holds the transports, and so the connection pools, shared by all downloads:
+// one verifying the certificates of the servers and one that does not, plus one for each host
+// with a client certificate
+var sharedTransports struct {
+	lock       sync.Mutex
+	transports map[transportKey]*http.Transport
+}
+
+// SetHTTPClientOptions changes the options of the connections shared by all downloads,
+// closing the connections opened so far
+func SetHTTPClientOptions(options HTTPClientOptions) {
+	sharedTransports.lock.Lock()
+	defer sharedTransports.lock.Unlock()
+
+	gHTTPClientOptions = options
+	for _, transport := range sharedTransports.transports {
+		transport.CloseIdleConnections()
+	}
+	sharedTransports.transports = nil
+}
+
+func GetHTTPClientOptions() HTTPClientOptions {
+	sharedTransports.lock.Lock()
+	defer sharedTransports.lock.Unlock()
+	return gHTTPClientOptions
+}
+
+// LoadCACertificates returns the certificate authorities of the system, plus the ones in the given PEM files
+func LoadCACertificates(files []string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		log.Debugf("Not using the certificate authorities of the system: %v", err)
+		pool = x509.NewCertPool()
+	}
+	for _, file := range files {
+		contents, err := os.ReadFile(file) // #nosec
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(contents) {
+			return nil, fmt.Errorf("%q: %w", file, errs.ErrInvalidCertificateFile)
+		}
+	}
+	return pool, nil
+}
+
+// LoadClientCertificate returns the client certificate in the given PEM files
+func LoadClientCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		log.Error(err)
+		return certificate, fmt.Errorf("%q: %w", certFile, errs.ErrInvalidCertificateFile)
+	}
+	return certificate, nil
+}
+
+// clientCertificateHost returns the key in HTTPClientOptions.ClientCertificates of the certificate
+// to present when requesting URL, "" if none. The host and port take precedence over the host only.
+func clientCertificateHost(URL string) string {
+	parsedURL, err := url.Parse(URL)
+	if err != nil || parsedURL.Scheme != "https" {
+		return ""
+	}
+	for _, host := range []string{strings.ToLower(parsedURL.Host), strings.ToLower(parsedURL.Hostname())} {
+		if _, found := gHTTPClientOptions.ClientCertificates[host]; found {
+			return host
+		}
+	}
+	return ""
+}
+
+// sharedTransport returns the transport shared by all downloads from the host of URL with the same TLS verification
+func sharedTransport(URL string, insecureSkipVerify bool) *http.Transport {
+	sharedTransports.lock.Lock()
+	defer sharedTransports.lock.Unlock()
+
+	key := transportKey{insecureSkipVerify: insecureSkipVerify, clientCertificateHost: clientCertificateHost(URL)}
+	if transport, found := sharedTransports.transports[key]; found {
+		return transport
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	proxy := http.ProxyFromEnvironment
+	if gHTTPClientOptions.Proxy != nil {
+		proxy = http.ProxyURL(gHTTPClientOptions.Proxy)
+	}
+	tlsConfig := &tls.Config{
+		RootCAs: gHTTPClientOptions.RootCAs,
+		// #nosec G402
+		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec
+	}
+	if key.clientCertificateHost != "" {
+		tlsConfig.Certificates = []tls.Certificate{gHTTPClientOptions.ClientCertificates[key.clientCertificateHost]}
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     !gHTTPClientOptions.DisableHTTP2,
+		MaxIdleConnsPerHost:   gHTTPClientOptions.MaxIdleConnsPerHost,
+		IdleConnTimeout:       gHTTPClientOptions.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	if gHTTPClientOptions.DisableHTTP2 {
+		// A non-nil empty map turns HTTP/2 off
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	if sharedTransports.transports == nil {
+		sharedTransports.transports = map[transportKey]*http.Transport{}
+	}
+	sharedTransports.transports[key] = transport
+	return transport
+}
+
+// hostTransport sends each request, redirects included, through the shared transport of its host,
+// so that a client certificate is only presented to the host it is configured for
+type hostTransport struct {
+	insecureSkipVerify bool
+}
+
+func (h *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return sharedTransport(req.URL.String(), h.insecureSkipVerify).RoundTrip(req)
+}
+
+// newHTTPClient returns a client using the connections shared by all downloads
+//
+// Parameters:
+//   - insecureSkipVerify: If true, skips TLS certificate verification.
+//   - timeout: The maximum duration, in seconds, to wait for the response of each request. 0 means no timeout.
+func newHTTPClient(insecureSkipVerify bool, timeout int) *http.Client {
+	return &http.Client{
+		Transport: &TimeoutTransport{
+			Transport:        &hostTransport{insecureSkipVerify: insecureSkipVerify},
+			RoundTripTimeout: time.Duration(timeout) * time.Second,
+		},
+	}
+}
+
+type TimeoutTransport struct {
+	Transport        http.RoundTripper
+	RoundTripTimeout time.Duration
+}
+
+// Helper function to set timeouts on HTTP connections
+// that use keep-alive connections (the most common one)
+func (t *TimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), connectionStatsTrace(req.URL.Host)))
+	if t.RoundTripTimeout <= 0 {
+		return t.Transport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(t.RoundTripTimeout, cancel)
+	resp, err := t.Transport.RoundTrip(req.WithContext(ctx))
+	if !timer.Stop() {
+		cancel()
+		if err == nil {
+			resp.Body.Close()
+		}
+		return nil, errs.ErrHTTPtimeout
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The body gets read after the timeout got disarmed, release the context along with it
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose cancels the context of a request once its response body gets closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// maxDiscardedBodySize is how much of an unused response body gets read
+// for its connection to be reused, rather than closed
+const maxDiscardedBodySize = 64 * 1024
+
+// discardResponse closes the body of a response that is not read any further,
+// reading what is left of small ones so that their connection can be reused
+func discardResponse(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDiscardedBodySize))
+	resp.Body.Close()
+}
+
+// connectionStats counts, per host, the connections opened and reused by the requests
+var connectionStats struct {
+	lock   sync.Mutex
+	opened map[string]int
+	reused map[string]int
+}
+
+// connectionStatsTrace counts the connection each request to host gets
+func connectionStatsTrace(host string) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			connectionStats.lock.Lock()
+			defer connectionStats.lock.Unlock()
+			if connectionStats.opened == nil {
+				connectionStats.opened = map[string]int{}
+				connectionStats.reused = map[string]int{}
+			}
+			if info.Reused {
+				connectionStats.reused[host]++
+			} else {
+				connectionStats.opened[host]++
+			}
+		},
+	}
+}
+
+// GetConnectionStats returns the number of connections opened and reused so far, over all hosts
+func GetConnectionStats() (opened, reused int) {
+	connectionStats.lock.Lock()
+	defer connectionStats.lock.Unlock()
+	for _, count := range connectionStats.opened {
+		opened += count
+	}
+	for _, count := range connectionStats.reused {
+		reused += count
+	}
+	return opened, reused
+}
+
+// LogConnectionStats prints, in debug messages, how many connections got opened and reused for each host
+func LogConnectionStats() {
+	connectionStats.lock.Lock()
+	defer connectionStats.lock.Unlock()
+
+	hosts := map[string]bool{}
+	for host := range connectionStats.opened {
+		hosts[host] = true
+	}
+	for host := range connectionStats.reused {
+		hosts[host] = true
+	}
+	sortedHosts := make([]string, 0, len(hosts))
+	for host := range hosts {
+		sortedHosts = append(sortedHosts, host)
+	}
+	slices.Sort(sortedHosts)
+
+	for _, host := range sortedHosts {
+		log.Debugf("Connections to %s: %d opened, %d reused", host, connectionStats.opened[host], connectionStats.reused[host])
+	}
+}
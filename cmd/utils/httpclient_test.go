@@ -0,0 +1,339 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package utils_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	errs "github.com/open-cmsis-pack/cpackget/cmd/errors"
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestCertificate writes a self-signed certificate for localhost and its key as PEM files in dir
+func writeTestCertificate(t *testing.T, dir, name string) (certFile, keyFile string, certificate tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	assert.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	certificate, err = tls.LoadX509KeyPair(certFile, keyFile)
+	assert.Nil(t, err)
+	return certFile, keyFile, certificate
+}
+
+// localhostURL returns the URL of server through localhost, so that certificates get verified
+func localhostURL(server *httptest.Server) string {
+	return strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+}
+
+func TestHTTPClient(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("test downloads reuse connections", func(t *testing.T) {
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		defer server.Close()
+
+		openedBefore, reusedBefore := utils.GetConnectionStats()
+		for _, fileName := range []string{"first.txt", "second.txt", "third.txt"} {
+			_, err := utils.DownloadFile(server.URL+"/"+fileName, false, false, false, false, 0)
+			assert.Nil(err)
+			os.Remove(fileName)
+		}
+
+		opened, reused := utils.GetConnectionStats()
+		assert.Equal(1, opened-openedBefore)
+		assert.Equal(2, reused-reusedBefore)
+	})
+
+	t.Run("test downloads reuse connections after errors", func(t *testing.T) {
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path == "/missing.txt" {
+						http.Error(w, "not found", http.StatusNotFound)
+						return
+					}
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		defer server.Close()
+
+		openedBefore, _ := utils.GetConnectionStats()
+		_, err := utils.DownloadFile(server.URL+"/missing.txt", false, false, false, false, 0)
+		assert.NotNil(err)
+		_, err = utils.DownloadFile(server.URL+"/found.txt", false, false, false, false, 0)
+		assert.Nil(err)
+		os.Remove("found.txt")
+
+		opened, _ := utils.GetConnectionStats()
+		assert.Equal(1, opened-openedBefore)
+	})
+
+	t.Run("test downloads use HTTP/2 when available", func(t *testing.T) {
+		var protoMajor int
+		server := httptest.NewUnstartedServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					protoMajor = r.ProtoMajor
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		defer server.Close()
+
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, false, false, false, true, 0)
+		assert.Nil(err)
+		assert.Equal(2, protoMajor)
+	})
+
+	t.Run("test downloads stick to HTTP/1.1 if asked to", func(t *testing.T) {
+		currOptions := utils.GetHTTPClientOptions()
+		options := currOptions
+		options.DisableHTTP2 = true
+		utils.SetHTTPClientOptions(options)
+		defer utils.SetHTTPClientOptions(currOptions)
+
+		var protoMajor int
+		server := httptest.NewUnstartedServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					protoMajor = r.ProtoMajor
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		defer server.Close()
+
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, false, false, false, true, 0)
+		assert.Nil(err)
+		assert.Equal(1, protoMajor)
+	})
+
+	t.Run("test downloads still verify certificates without insecure skip verify", func(t *testing.T) {
+		server := httptest.NewTLSServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		defer server.Close()
+
+		// A connection opened while skipping the verification must not be reused
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, false, false, false, true, 0)
+		assert.Nil(err)
+
+		url := "https://localhost:" + server.URL[len("https://127.0.0.1:"):] + "/" + fileName
+		_, err = utils.DownloadFile(url, false, false, false, false, 0)
+		assert.NotNil(err)
+
+		// Local servers are no exception
+		_, err = utils.DownloadFile(server.URL+"/"+fileName, false, false, false, false, 0)
+		assert.NotNil(err)
+	})
+
+	t.Run("test downloads time out", func(t *testing.T) {
+		utils.SetDownloadRetries(0)
+		defer utils.SetDownloadRetries(3)
+
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					time.Sleep(1500 * time.Millisecond)
+					fmt.Fprint(w, "too late")
+				},
+			),
+		)
+		defer server.Close()
+
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, false, false, false, false, 1)
+		assert.Equal(errs.ErrFailedDownloadingFile, errors.Unwrap(err))
+	})
+
+	t.Run("test downloads trust extra certificate authorities", func(t *testing.T) {
+		certFile, _, certificate := writeTestCertificate(t, t.TempDir(), "proxy")
+		server := httptest.NewUnstartedServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+		server.StartTLS()
+		defer server.Close()
+
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err := utils.DownloadFile(localhostURL(server)+"/"+fileName, false, false, false, false, 0)
+		assert.NotNil(err)
+
+		currOptions := utils.GetHTTPClientOptions()
+		defer utils.SetHTTPClientOptions(currOptions)
+		options := currOptions
+		options.RootCAs, err = utils.LoadCACertificates([]string{certFile})
+		assert.Nil(err)
+		utils.SetHTTPClientOptions(options)
+
+		_, err = utils.DownloadFile(localhostURL(server)+"/"+fileName, false, false, false, false, 0)
+		assert.Nil(err)
+		assert.Nil(utils.CheckConnection(localhostURL(server), 0))
+	})
+
+	t.Run("test downloads present the client certificate of the host", func(t *testing.T) {
+		dir := t.TempDir()
+		serverCertFile, _, serverCertificate := writeTestCertificate(t, dir, "server")
+		clientCertFile, clientKeyFile, _ := writeTestCertificate(t, dir, "client")
+		clientCAs, err := utils.LoadCACertificates([]string{clientCertFile})
+		assert.Nil(err)
+		server := httptest.NewUnstartedServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		server.TLS = &tls.Config{
+			Certificates: []tls.Certificate{serverCertificate},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+			MinVersion:   tls.VersionTLS12,
+		}
+		server.StartTLS()
+		defer server.Close()
+
+		currOptions := utils.GetHTTPClientOptions()
+		defer utils.SetHTTPClientOptions(currOptions)
+		options := currOptions
+		options.RootCAs, err = utils.LoadCACertificates([]string{serverCertFile})
+		assert.Nil(err)
+		clientCertificate, err := utils.LoadClientCertificate(clientCertFile, clientKeyFile)
+		assert.Nil(err)
+
+		// Only presented to the host it is meant for
+		options.ClientCertificates = map[string]tls.Certificate{"packs.acme.com": clientCertificate}
+		utils.SetHTTPClientOptions(options)
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err = utils.DownloadFile(localhostURL(server)+"/"+fileName, false, false, false, false, 0)
+		assert.NotNil(err)
+
+		options.ClientCertificates = map[string]tls.Certificate{"localhost": clientCertificate}
+		utils.SetHTTPClientOptions(options)
+		_, err = utils.DownloadFile(localhostURL(server)+"/"+fileName, false, false, false, false, 0)
+		assert.Nil(err)
+	})
+
+	t.Run("test downloads do not present the client certificate of the host to the hosts it redirects to", func(t *testing.T) {
+		dir := t.TempDir()
+		serverCertFile, _, serverCertificate := writeTestCertificate(t, dir, "server")
+		clientCertFile, clientKeyFile, _ := writeTestCertificate(t, dir, "client")
+
+		// The redirection target, e.g. a CDN, asks for a client certificate without requiring one
+		presented := 0
+		target := httptest.NewUnstartedServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					presented += len(r.TLS.PeerCertificates)
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		target.TLS = &tls.Config{ClientAuth: tls.RequestClientCert, MinVersion: tls.VersionTLS12}
+		target.StartTLS()
+		defer target.Close()
+
+		server := httptest.NewUnstartedServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					http.Redirect(w, r, target.URL+r.URL.Path, http.StatusFound)
+				},
+			),
+		)
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{serverCertificate}, ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+		server.StartTLS()
+		defer server.Close()
+
+		currOptions := utils.GetHTTPClientOptions()
+		defer utils.SetHTTPClientOptions(currOptions)
+		options := currOptions
+		var err error
+		options.RootCAs, err = utils.LoadCACertificates([]string{serverCertFile})
+		assert.Nil(err)
+		options.RootCAs.AddCert(target.Certificate())
+		clientCertificate, err := utils.LoadClientCertificate(clientCertFile, clientKeyFile)
+		assert.Nil(err)
+		options.ClientCertificates = map[string]tls.Certificate{"localhost": clientCertificate}
+		utils.SetHTTPClientOptions(options)
+
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		_, err = utils.DownloadFile(localhostURL(server)+"/"+fileName, false, false, false, false, 0)
+		assert.Nil(err)
+		assert.Equal(0, presented)
+	})
+
+	t.Run("test loading invalid certificate files", func(t *testing.T) {
+		invalidFile := filepath.Join(t.TempDir(), "invalid.pem")
+		assert.Nil(os.WriteFile(invalidFile, []byte("not a certificate"), 0600))
+
+		_, err := utils.LoadCACertificates([]string{invalidFile})
+		assert.Equal(errs.ErrInvalidCertificateFile, errors.Unwrap(err))
+		_, err = utils.LoadClientCertificate(invalidFile, invalidFile)
+		assert.Equal(errs.ErrInvalidCertificateFile, errors.Unwrap(err))
+	})
+}
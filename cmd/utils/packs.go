@@ -16,6 +16,8 @@ import (
 
 const PdscExtension = ".pdsc"
 const PackExtension = ".pack"
+const PidxExtension = ".pidx"
+const VidxExtension = ".vidx"
 
 // namePattern specifies a regular expression that matches Pack and Vendor names.
 // Ref: https://github.com/Open-CMSIS-Pack/Open-CMSIS-Pack-Spec/blob/4e2ef7dddc4bcd2a43b530d79908720c9c52da9e/schema/PACK.xsd#L1659
@@ -298,23 +300,26 @@ func ExtractPackInfo(packPath string) (PackInfo, error) {
 // Ref: https://github.com/Open-CMSIS-Pack/devtools/blob/main/tools/projmgr/docs/Manual/YML-Input-Format.md#pack-name-conventions
 func FormatPackVersion(pack []string) string {
 	name, vendor, version := pack[0], pack[1], pack[2]
+	return vendor + "::" + name + "@" + FormatRequiredVersion(version)
+}
+
+// FormatRequiredVersion returns the version part of FormatPackVersion,
+// e.g. 5.6.0:_ -> >=5.6.0, 5.6.0:5.6.0 -> 5.6.0 and 5.6.0:5.9.0 -> 5.6.0:5.9.0
+func FormatRequiredVersion(version string) string {
 	if version == "latest" {
-		return vendor + "::" + name + "@latest"
-	} else {
-		if string(version[len(version)-1]) == "_" {
-			// @>=<version>
-			return vendor + "::" + name + "@>=" + strings.Split(version, ":")[0]
-		} else {
-			minVersion, maxVersion := strings.Split(version, ":")[0], strings.Split(version, ":")[1]
-			if minVersion == maxVersion {
-				// @<version>
-				return vendor + "::" + name + "@" + strings.Split(version, ":")[0]
-			} else {
-				// @<minVersion>:<maxVersion> - unspecified yet, it should not cross major version boundaries
-				return vendor + "::" + name + "@" + strings.Split(version, ":")[0] + ":" + strings.Split(version, ":")[1]
-			}
-		}
+		return "latest"
+	}
+	if string(version[len(version)-1]) == "_" {
+		// >=<version>
+		return ">=" + strings.Split(version, ":")[0]
+	}
+	minVersion, maxVersion := strings.Split(version, ":")[0], strings.Split(version, ":")[1]
+	if minVersion == maxVersion {
+		// <version>
+		return minVersion
 	}
+	// <minVersion>:<maxVersion> - unspecified yet, it should not cross major version boundaries
+	return minVersion + ":" + maxVersion
 }
 
 func FormatVersions(version string) string {
@@ -368,3 +368,10 @@ func TestFormatPackVersion(t *testing.T) {
 	pack[2] = "1.0.0:1.2.0"
 	assert.Equal(t, "TheVendor::Pack@1.0.0:1.2.0", utils.FormatPackVersion(pack))
 }
+
+func TestFormatRequiredVersion(t *testing.T) {
+	assert.Equal(t, "1.0.0", utils.FormatRequiredVersion("1.0.0:1.0.0"))
+	assert.Equal(t, "latest", utils.FormatRequiredVersion("latest"))
+	assert.Equal(t, ">=1.0.0", utils.FormatRequiredVersion("1.0.0:_"))
+	assert.Equal(t, "1.0.0:1.2.0", utils.FormatRequiredVersion("1.0.0:1.2.0"))
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// ProcessExists tells whether a process with the given PID is running on this machine
+func ProcessExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	// Signal 0 only checks the process exists; EPERM means it belongs to another user
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
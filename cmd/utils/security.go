@@ -32,6 +32,13 @@ var ShouldAbortFunction func() bool
 // a file.
 // Ref: G110: Potential DoS vulnerability via decompression bomb (https://cwe.mitre.org/data/definitions/409.html)
 func SecureCopy(dst io.Writer, src io.Reader) (int64, error) {
+	return SecureCopyFrom(dst, src, 0)
+}
+
+// SecureCopyFrom is SecureCopy resuming a copy of which offset bytes were written
+// already, these counting towards MaxDownloadSize. It returns the number of bytes copied
+// from src.
+func SecureCopyFrom(dst io.Writer, src io.Reader, offset int64) (int64, error) {
 	bytesRead := int64(0)
 	for {
 		if ShouldAbortFunction != nil && ShouldAbortFunction() {
@@ -44,7 +51,7 @@ func SecureCopy(dst io.Writer, src io.Reader) (int64, error) {
 
 		// Check if copy limit has explode before checking for errors
 		bytesRead += int64(partialRead)
-		if bytesRead > MaxDownloadSize {
+		if offset+bytesRead > MaxDownloadSize {
 			log.Errorf("Attempted to copy a file over %v bytes", MaxDownloadSize)
 			return bytesRead, errs.ErrFileTooBig
 		}
@@ -37,6 +37,23 @@ func TestSecureCopy(t *testing.T) {
 		assert.True(errs.Is(err, errs.ErrFileTooBig))
 	})
 
+	t.Run("test fail to resume copying extra large files", func(t *testing.T) {
+		currMaxDownloadSize := utils.MaxDownloadSize
+		utils.MaxDownloadSize = 5
+		defer func() {
+			utils.MaxDownloadSize = currMaxDownloadSize
+		}()
+
+		var outBuffer bytes.Buffer
+		writer := bufio.NewWriter(&outBuffer)
+
+		// The bytes copied before count towards the limit
+		_, err := utils.SecureCopyFrom(writer, strings.NewReader("abc"), 0)
+		assert.Nil(err)
+		_, err = utils.SecureCopyFrom(writer, strings.NewReader("abc"), 3)
+		assert.True(errs.Is(err, errs.ErrFileTooBig))
+	})
+
 	t.Run("test abort copy due to user termination request", func(t *testing.T) {
 		// Fake a user termination request
 		utils.ShouldAbortFunction = func() bool {
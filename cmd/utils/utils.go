@@ -6,16 +6,13 @@ package utils
 import (
 	"bufio"
 	"bytes"
-	"crypto/tls"
 	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"math"
 	"math/rand"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -23,6 +20,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -76,9 +74,37 @@ func FileURLToPath(fileURL string) (string, error) {
 	return decodedPath, nil
 }
 
+// PathToFileURL converts a local file system path to an absolute file:// URL,
+// e.g. file:///path/to/file or file:///C:/path/to/file on Windows.
+func PathToFileURL(filePath string) (string, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+	urlPath := filepath.ToSlash(absPath)
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+	return (&url.URL{Scheme: "file", Path: urlPath}).String(), nil
+}
+
 var gEncodedProgress = false
 var gSkipTouch = false
 var gUserAgent string
+var gOutputFormat = ""
+var gOutputWriter io.Writer = os.Stdout
+var gOffline = false
+
+// Supported formats for machine-readable output, an empty format means
+// the regular log lines are printed
+const (
+	OutputFormatJSON  = "json"
+	OutputFormatYAML  = "yaml"
+	OutputFormatTable = "table"
+)
+
+// OutputFormats lists all accepted values of the "--output" flag
+var OutputFormats = []string{OutputFormatJSON, OutputFormatYAML, OutputFormatTable}
 
 func SetEncodedProgress(encodedProgress bool) {
 	gEncodedProgress = encodedProgress
@@ -88,6 +114,16 @@ func GetEncodedProgress() bool {
 	return gEncodedProgress
 }
 
+// SetOffline keeps cpackget from accessing the network, downloads
+// failing unless the file is already in the cache
+func SetOffline(offline bool) {
+	gOffline = offline
+}
+
+func GetOffline() bool {
+	return gOffline
+}
+
 func SetSkipTouch(skipTouch bool) {
 	gSkipTouch = skipTouch
 }
@@ -100,47 +136,38 @@ func SetUserAgent(userAgent string) {
 	gUserAgent = userAgent
 }
 
-// CacheDir is used for cpackget to temporarily host downloaded pack files
-// before moving it to CMSIS_PACK_ROOT
-var CacheDir string
-
-var instCnt = 0
+// SetOutputFormat selects the machine-readable output format, or resets
+// to log lines if outputFormat is empty
+func SetOutputFormat(outputFormat string) error {
+	outputFormat = strings.ToLower(outputFormat)
+	if outputFormat != "" && !slices.Contains(OutputFormats, outputFormat) {
+		return fmt.Errorf("%q: %w", outputFormat, errs.ErrUnknownOutputFormat)
+	}
+	gOutputFormat = outputFormat
+	return nil
+}
 
-var HTTPClient *http.Client
+func GetOutputFormat() string {
+	return gOutputFormat
+}
 
-type TimeoutTransport struct {
-	http.Transport
-	RoundTripTimeout time.Duration
+// SetOutputWriter sets where structured output is written, which the
+// log lines are kept out of for it to be parsed
+func SetOutputWriter(w io.Writer) {
+	gOutputWriter = w
 }
 
-// Helper function to set timeouts on HTTP connections
-// that use keep-alive connections (the most common one)
-func (t *TimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	type respAndErr struct {
-		resp *http.Response
-		err  error
-	}
+func GetOutputWriter() io.Writer {
+	return gOutputWriter
+}
 
-	timeout := time.After(t.RoundTripTimeout)
-	resp := make(chan respAndErr, 1)
+// CacheDir is used for cpackget to temporarily host downloaded pack files
+// before moving it to CMSIS_PACK_ROOT
+var CacheDir string
 
-	go func() {
-		r, e := t.Transport.RoundTrip(req)
-		resp <- respAndErr{
-			resp: r,
-			err:  e,
-		}
-	}()
+var instCnt = 0
 
-	select {
-	case <-timeout:
-		//nolint:staticcheck
-		t.Transport.CancelRequest(req)
-		return nil, errs.ErrHTTPtimeout
-	case r := <-resp:
-		return r.resp, r.err
-	}
-}
+var HTTPClient *http.Client
 
 var (
 	// File RO (ReadOnly) and RW (Read + Write) modes
@@ -170,58 +197,147 @@ var (
 // The function handles special cases for localhost HTTPS downloads by skipping TLS verification,
 // retries the request without a user agent if a 404 is received, and handles cookies if a 403 is returned.
 // It also supports progress reporting and secure file writing.
+//
+// The file is downloaded to a ".part" file, renamed once complete. If the download fails or gets
+// aborted, the ".part" file is kept when the server provided an ETag or a Last-Modified date, and
+// the next download of the same URL resumes it with a Range request. The If-Range header makes
+// the server send the whole file instead if it changed in the meantime.
 func DownloadFile(URL string, useCache, showInfo, showProgressBar, insecureSkipVerify bool, timeout int) (string, error) {
+	return download(URL, CacheDir, useCache, nil, showInfo, showProgressBar, insecureSkipVerify, timeout)
+}
+
+// DownloadFileTo downloads a file like DownloadFile does, to dir instead of the cache directory,
+// e.g. to keep it out of any pack root.
+//
+// Parameters:
+//   - URL: The URL of the file to download.
+//   - dir: The directory the file gets downloaded to, under the base name of URL.
+//   - showInfo: If true, logs informational messages about the download.
+//   - showProgressBar: If true, shows the progress bar during download.
+//   - insecureSkipVerify: If true, skips TLS certificate verification for HTTPS downloads.
+//   - timeout: The download timeout in seconds. If 0, no timeout is set.
+//
+// Returns:
+//   - The local file path where the downloaded file is saved.
+//   - Any error of DownloadFile.
+func DownloadFileTo(URL, dir string, showInfo, showProgressBar, insecureSkipVerify bool, timeout int) (string, error) {
+	return download(URL, dir, false, nil, showInfo, showProgressBar, insecureSkipVerify, timeout)
+}
+
+// DownloadFileIfModified downloads a file like DownloadFile does, unless it did not change
+// since it was last downloaded.
+//
+// Parameters:
+//   - URL: The URL of the file to download.
+//   - useCache: If true and validators are empty, uses the cached file if it exists instead of downloading again.
+//   - validators: The validators of the file the last time it was downloaded, sent in a conditional
+//     request. Empty validators make the download unconditional. Once downloaded, they get replaced
+//     with the validators of the file, and left empty if the cached file got used.
+//   - showInfo: If true, logs informational messages about the download.
+//   - showProgressBar: If true, shows the progress bar during download.
+//   - insecureSkipVerify: If true, skips TLS certificate verification for HTTPS downloads.
+//   - timeout: The download timeout in seconds. If 0, no timeout is set.
+//
+// Returns:
+//   - The local file path where the downloaded file is saved.
+//   - errs.ErrNotModified if the server answered that the file did not change, nothing
+//     being downloaded, or any error of DownloadFile.
+func DownloadFileIfModified(URL string, useCache bool, validators *Validators, showInfo, showProgressBar, insecureSkipVerify bool, timeout int) (string, error) {
+	return download(URL, CacheDir, useCache && validators.IsEmpty(), validators, showInfo, showProgressBar, insecureSkipVerify, timeout)
+}
+
+// download downloads a file to dir, retrying on transient errors, see DownloadFile and DownloadFileIfModified
+func download(URL, dir string, useCache bool, validators *Validators, showInfo, showProgressBar, insecureSkipVerify bool, timeout int) (string, error) {
+	// Credentials in the URL are only used for this download, and must not end up in the logs
+	URL, credentials := splitCredentials(URL)
+
 	parsedURL, _ := url.Parse(URL)
 	fileBase := path.Base(parsedURL.Path)
-	filePath := filepath.Join(CacheDir, fileBase)
+	filePath := filepath.Join(dir, fileBase)
 	log.Debugf("Downloading %s to %s", URL, filePath)
 	if useCache && FileExists(filePath) {
 		log.Debugf("Download not required, using the one from cache")
 		return filePath, nil
 	}
-
-	// For now, skip insecure HTTPS downloads verification only for localhost
-	var tls tls.Config
-	if strings.Contains(URL, "https://127.0.0.1") {
-		// #nosec G402
-		tls.InsecureSkipVerify = true //nolint:gosec
-	} else {
-		// #nosec G402
-		tls.InsecureSkipVerify = insecureSkipVerify //nolint:gosec
+	if parsedURL != nil && parsedURL.Scheme == "file" {
+		// Files of a local registry get copied, even offline
+		return copyFileURL(URL, filePath)
+	}
+	if gOffline {
+		return "", fmt.Errorf("%q: %w", URL, errs.ErrOfflineMode)
 	}
 
-	var rtt time.Duration
-	if timeout == 0 {
-		rtt = time.Duration(math.MaxInt64)
-	} else {
-		rtt = time.Second * time.Duration(timeout)
+	client := newHTTPClient(insecureSkipVerify, timeout)
+
+	for retry := 1; ; retry++ {
+		filePath, err := downloadFile(client, URL, credentials, filePath, validators, showInfo, showProgressBar)
+		var transient *transientError
+		if !errors.As(err, &transient) {
+			return filePath, err
+		}
+		if retry > gDownloadRetries {
+			log.Error(transient.cause)
+			return filePath, transient.err
+		}
+
+		delay := retryDelay(retry, transient.retryAfter)
+		log.Debugf("Retrying the download of %s in %v (retry %d of %d): %v", fileBase, delay.Round(time.Millisecond), retry, gDownloadRetries, transient.cause)
+		if GetEncodedProgress() {
+			log.Infof("[R%d:F%q]", retry, fileBase)
+		}
+		time.Sleep(delay)
 	}
+}
 
-	client := &http.Client{
-		Transport: &TimeoutTransport{
-			Transport: http.Transport{
-				Dial: func(netw, addr string) (net.Conn, error) {
-					return net.Dial(netw, addr)
-				},
-				TLSClientConfig: &tls,
-				Proxy:           http.ProxyFromEnvironment,
-			},
-			RoundTripTimeout: rtt,
-		},
+// copyFileURL copies the file at the file:// URL to filePath, as if downloaded
+func copyFileURL(URL, filePath string) (string, error) {
+	sourcePath, err := FileURLToPath(URL)
+	if err != nil {
+		return "", err
+	}
+	if !FileExists(sourcePath) {
+		return "", fmt.Errorf("%q: %w", URL, errs.ErrFileNotFound)
+	}
+	if err := CopyFile(sourcePath, filePath); err != nil {
+		return "", err
 	}
+	return filePath, nil
+}
+
+// downloadFile makes one attempt at downloading URL to filePath, see DownloadFile.
+// Failures worth retrying are returned as a transientError.
+func downloadFile(client *http.Client, URL string, credentials *Credentials, filePath string, validators *Validators, showInfo, showProgressBar bool) (string, error) {
+	fileBase := filepath.Base(filePath)
+
+	// Resume the download left by a previous attempt, if the file did not change since
+	partPath := filePath + PartialDownloadExtension
+	partial := readPartialDownload(partPath, URL)
 
 	req, _ := http.NewRequest("GET", URL, nil)
 	req.Header.Add("User-Agent", gUserAgent)
+	authenticate(req, credentials)
+	if partial != nil {
+		log.Debugf("Resuming the download of %s after %d bytes", fileBase, partial.size)
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", partial.size))
+		req.Header.Set("If-Range", partial.Validator)
+	} else if validators != nil {
+		validators.setConditionalHeaders(req)
+	}
 	//nolint:gosec // G704: URL is provided as function parameter and validated by caller
 	resp, err := client.Do(req)
 	if err != nil {
+		if isTransientNetworkError(err) {
+			return "", &transientError{err: fmt.Errorf("%q: %w", URL, errs.ErrFailedDownloadingFile), cause: err}
+		}
 		log.Error(err)
 		return "", fmt.Errorf("%q: %w", URL, errs.ErrFailedDownloadingFile)
 	}
-	defer resp.Body.Close()
+	// Close whichever response comes last
+	defer func() { resp.Body.Close() }()
 
 	if resp.StatusCode == http.StatusNotFound {
 		// resend GET request without user agent header
+		discardResponse(resp)
 		req.Header.Del("User-Agent")
 		//nolint:gosec // G704: URL is provided as function parameter and validated by caller
 		resp, err = client.Do(req)
@@ -236,6 +352,7 @@ func DownloadFile(URL string, useCache, showInfo, showProgressBar, insecureSkipV
 		if len(cookie) > 0 {
 			// add cookie and resend GET request
 			log.Debugf("Cookie: %s", cookie)
+			discardResponse(resp)
 			req.Header.Add("Cookie", cookie)
 			//nolint:gosec // G704: URL is provided as function parameter and validated by caller
 			resp, err = client.Do(req)
@@ -246,13 +363,51 @@ func DownloadFile(URL string, useCache, showInfo, showProgressBar, insecureSkipV
 		}
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	rangeMismatch := resp.StatusCode == http.StatusPartialContent && partial != nil && contentRangeStart(resp.Header.Get("Content-Range")) != partial.size
+	if (resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && partial != nil) || rangeMismatch {
+		// The partially downloaded file is no good, start over
+		discardResponse(resp)
+		removePartialDownload(partPath)
+		return downloadFile(client, URL, credentials, filePath, validators, showInfo, showProgressBar)
+	}
+
+	if resp.StatusCode == http.StatusNotModified && validators != nil {
+		log.Debugf("%s did not change since last downloaded", fileBase)
+		validators.update(resp)
+		return "", errs.ErrNotModified
+	}
+
+	// The server sends the whole file if it does not support ranges or the file changed
+	offset := int64(0)
+	if resp.StatusCode == http.StatusPartialContent && partial != nil {
+		offset = partial.size
+	} else if resp.StatusCode != http.StatusOK {
 		log.Debugf("bad status: %s", resp.Status)
-		return "", fmt.Errorf("%q: %w", URL, errs.ErrBadRequest)
+		discardResponse(resp)
+		err := fmt.Errorf("%q: %w", URL, errs.ErrBadRequest)
+		if resp.StatusCode == http.StatusUnauthorized {
+			err = fmt.Errorf("%q: %w", URL, errs.ErrUnauthorized)
+		}
+		if isTransientStatus(resp.StatusCode) {
+			return "", &transientError{err: err, cause: fmt.Errorf("%s: %s", URL, resp.Status), retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		}
+		return "", err
+	} else {
+		partial = newPartialDownload(URL, resp)
 	}
 
-	//nolint:gosec // G703: filePath is safely constructed using path.Base() which prevents directory traversal
-	out, err := os.Create(filePath)
+	var out *os.File
+	if offset > 0 {
+		out, err = os.OpenFile(partPath, os.O_WRONLY|os.O_APPEND, 0666) // #nosec
+	} else {
+		//nolint:gosec // G703: partPath is safely constructed using path.Base() which prevents directory traversal
+		out, err = os.Create(partPath)
+		if err == nil && partial != nil {
+			err = partial.write(partPath)
+		} else if err == nil {
+			_ = os.Remove(partialDownloadInfoPath(partPath))
+		}
+	}
 	if err != nil {
 		log.Error(err)
 		return "", errs.ErrFailedCreatingFile
@@ -260,7 +415,11 @@ func DownloadFile(URL string, useCache, showInfo, showProgressBar, insecureSkipV
 	defer out.Close()
 
 	if showInfo {
-		log.Infof("Downloading %s...", fileBase)
+		if offset > 0 {
+			log.Infof("Resuming download of %s...", fileBase)
+		} else {
+			log.Infof("Downloading %s...", fileBase)
+		}
 	}
 	writers := []io.Writer{out}
 	if log.GetLevel() != log.ErrorLevel {
@@ -278,17 +437,37 @@ func DownloadFile(URL string, useCache, showInfo, showProgressBar, insecureSkipV
 	}
 
 	// Download file in smaller bits straight to a local file
-	written, err := SecureCopy(io.MultiWriter(writers...), resp.Body)
+	body := &readErrorRecorder{r: resp.Body}
+	written, err := SecureCopyFrom(io.MultiWriter(writers...), body, offset)
 	//	fmt.Printf("\n")
 	log.Debugf("Downloaded %d bytes", written)
+	out.Close()
 
 	if err != nil {
-		out.Close()
-		//nolint:gosec // G703: filePath is safely constructed using path.Base() which prevents directory traversal
-		_ = os.Remove(filePath)
+		if partial != nil && err != errs.ErrFileTooBig {
+			log.Debugf("Keeping %q to resume the download", partPath)
+		} else {
+			removePartialDownload(partPath)
+		}
+		if body.err != nil && err == errs.ErrFailedWrittingToLocalFile {
+			// The connection got lost while downloading
+			return filePath, &transientError{err: err, cause: body.err}
+		}
+		return filePath, err
 	}
 
-	return filePath, err
+	//nolint:gosec // G703: filePath is safely constructed using path.Base() which prevents directory traversal
+	if err := os.Rename(partPath, filePath); err != nil {
+		log.Error(err)
+		return filePath, errs.ErrFailedCreatingFile
+	}
+	_ = os.Remove(partialDownloadInfoPath(partPath))
+
+	if validators != nil {
+		*validators = newValidators(resp)
+	}
+
+	return filePath, nil
 }
 
 var onlineInfo struct {
@@ -297,17 +476,32 @@ var onlineInfo struct {
 }
 
 func CheckConnection(url string, timeOut int) error {
+	if gOffline {
+		log.Debugf("Offline mode: not checking the connection to %s", url)
+		if GetEncodedProgress() {
+			log.Infof("[O:offline]")
+		}
+		return fmt.Errorf("%q: %w", url, errs.ErrOffline)
+	}
 	if onlineInfo.url == url && onlineInfo.connStatus == "online" { // already checked
 		return nil
 	}
 	onlineInfo.url = url
 	timeout := time.Duration(timeOut) * time.Second
 	client := http.Client{
-		Timeout: timeout,
+		Transport: &hostTransport{},
+		Timeout:   timeout,
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
 	}
-	resp, err := client.Get(url)
+	authenticate(req, nil)
+	//nolint:gosec // G704: URL is provided as function parameter and validated by caller
+	resp, err := client.Do(req)
 	onlineInfo.connStatus = "offline"
 	if err == nil {
+		resp.Body.Close()
 		onlineInfo.connStatus = "online"
 		if !GetEncodedProgress() {
 			log.Debugf("Respond: %v (%v)", resp.Status, onlineInfo.connStatus)
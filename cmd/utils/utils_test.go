@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -34,6 +35,13 @@ func (s *LogFormatter) Format(entry *log.Entry) ([]byte, error) {
 func TestDownloadFile(t *testing.T) {
 	assert := assert.New(t)
 
+	// Do not wait for long between retries
+	currRetryBaseDelay := utils.RetryBaseDelay
+	utils.RetryBaseDelay = time.Millisecond
+	defer func() {
+		utils.RetryBaseDelay = currRetryBaseDelay
+	}()
+
 	t.Run("test fail to create temporary file", func(t *testing.T) {
 		oldCache := utils.CacheDir
 		utils.CacheDir = "non-existant-path"
@@ -114,6 +122,26 @@ func TestDownloadFile(t *testing.T) {
 		assert.Equal(bytes, goodResponse)
 	})
 
+	t.Run("test download to a directory", func(t *testing.T) {
+		fileName := "file.txt"
+		goodResponse := []byte("all good")
+		goodServer := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, string(goodResponse))
+				},
+			),
+		)
+		dir := t.TempDir()
+		filePath, err := utils.DownloadFileTo(goodServer.URL+"/"+fileName, dir, false, false, false, 0)
+		assert.Nil(err)
+		assert.Equal(filepath.Join(dir, fileName), filePath)
+		bytes, err := os.ReadFile(filePath)
+		assert.Nil(err)
+		assert.Equal(goodResponse, bytes)
+		assert.False(utils.FileExists(filepath.Join(utils.CacheDir, fileName)))
+	})
+
 	t.Run("test download user agent not allowed", func(t *testing.T) {
 		fileName := "file.txt"
 		defer os.Remove(fileName)
@@ -197,6 +225,336 @@ func TestDownloadFile(t *testing.T) {
 		assert.Equal(1, requestCount)
 	})
 
+	t.Run("test download resumes a partial file", func(t *testing.T) {
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		content := []byte("all good, even after an interrupted download")
+		modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		var ranges []string
+		interrupt := true
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					ranges = append(ranges, r.Header.Get("Range"))
+					w.Header().Set("ETag", `"v1"`)
+					if interrupt {
+						// Send half of the file then drop the connection
+						interrupt = false
+						w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+						_, _ = w.Write(content[:10])
+						return
+					}
+					http.ServeContent(w, r, fileName, modTime, strings.NewReader(string(content)))
+				},
+			),
+		)
+		defer server.Close()
+
+		url := server.URL + "/" + fileName
+		utils.SetDownloadRetries(0)
+		_, err := utils.DownloadFile(url, true, true, true, false, 0)
+		utils.SetDownloadRetries(3)
+		assert.True(errs.Is(err, errs.ErrFailedWrittingToLocalFile))
+		assert.False(utils.FileExists(fileName))
+		assert.True(utils.FileExists(fileName + utils.PartialDownloadExtension))
+
+		_, err = utils.DownloadFile(url, true, true, true, false, 0)
+		assert.Nil(err)
+		assert.Equal([]string{"", "bytes=10-"}, ranges)
+		bytes, err := os.ReadFile(fileName)
+		assert.Nil(err)
+		assert.Equal(content, bytes)
+		assert.False(utils.FileExists(fileName + utils.PartialDownloadExtension))
+		assert.False(utils.FileExists(fileName + utils.PartialDownloadExtension + ".json"))
+	})
+
+	t.Run("test download starts over if the file changed", func(t *testing.T) {
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		content := []byte("the file changed since the download got interrupted")
+		modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		etag := `"v1"`
+		interrupt := true
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("ETag", etag)
+					if interrupt {
+						interrupt = false
+						w.Header().Set("Content-Length", "100")
+						_, _ = w.Write([]byte("old content"))
+						return
+					}
+					http.ServeContent(w, r, fileName, modTime, strings.NewReader(string(content)))
+				},
+			),
+		)
+		defer server.Close()
+
+		url := server.URL + "/" + fileName
+		utils.SetDownloadRetries(0)
+		_, err := utils.DownloadFile(url, true, true, true, false, 0)
+		utils.SetDownloadRetries(3)
+		assert.NotNil(err)
+		assert.True(utils.FileExists(fileName + utils.PartialDownloadExtension))
+
+		etag = `"v2"`
+		_, err = utils.DownloadFile(url, true, true, true, false, 0)
+		assert.Nil(err)
+		bytes, err := os.ReadFile(fileName)
+		assert.Nil(err)
+		assert.Equal(content, bytes)
+	})
+
+	t.Run("test download without validator is not resumable", func(t *testing.T) {
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Length", "100")
+					_, _ = w.Write([]byte("half"))
+				},
+			),
+		)
+		defer server.Close()
+
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, true, true, true, false, 0)
+		assert.NotNil(err)
+		assert.False(utils.FileExists(fileName + utils.PartialDownloadExtension))
+	})
+
+	t.Run("test download retries on server errors", func(t *testing.T) {
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		goodResponse := []byte("all good, at last")
+		requestCount := 0
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					requestCount++
+					if requestCount < 3 {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					fmt.Fprint(w, string(goodResponse))
+				},
+			),
+		)
+		defer server.Close()
+
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, true, true, true, false, 0)
+		assert.Nil(err)
+		assert.Equal(3, requestCount)
+		bytes, err := os.ReadFile(fileName)
+		assert.Nil(err)
+		assert.Equal(goodResponse, bytes)
+	})
+
+	t.Run("test download retries after the delay asked for by the server", func(t *testing.T) {
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		var requestTimes []time.Time
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					requestTimes = append(requestTimes, time.Now())
+					if len(requestTimes) == 1 {
+						w.Header().Set("Retry-After", "1")
+						w.WriteHeader(http.StatusTooManyRequests)
+						return
+					}
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		defer server.Close()
+
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, true, true, true, false, 0)
+		assert.Nil(err)
+		assert.Len(requestTimes, 2)
+		assert.GreaterOrEqual(requestTimes[1].Sub(requestTimes[0]), time.Second)
+	})
+
+	t.Run("test download retries resume the partial file", func(t *testing.T) {
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		content := []byte("all good, even after a dropped connection")
+		modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		var ranges []string
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					ranges = append(ranges, r.Header.Get("Range"))
+					w.Header().Set("ETag", `"v1"`)
+					if len(ranges) == 1 {
+						w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+						_, _ = w.Write(content[:10])
+						return
+					}
+					http.ServeContent(w, r, fileName, modTime, strings.NewReader(string(content)))
+				},
+			),
+		)
+		defer server.Close()
+
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, true, true, true, false, 0)
+		assert.Nil(err)
+		assert.Equal([]string{"", "bytes=10-"}, ranges)
+		bytes, err := os.ReadFile(fileName)
+		assert.Nil(err)
+		assert.Equal(content, bytes)
+	})
+
+	t.Run("test download gives up after all retries", func(t *testing.T) {
+		fileName := "file.txt"
+		requestCount := 0
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					requestCount++
+					w.WriteHeader(http.StatusBadGateway)
+				},
+			),
+		)
+		defer server.Close()
+
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, true, true, true, false, 0)
+		assert.Equal(errs.ErrBadRequest, errors.Unwrap(err))
+		assert.Equal(1+utils.GetDownloadRetries(), requestCount)
+		assert.False(utils.FileExists(fileName))
+	})
+
+	t.Run("test download is not retried with retries disabled", func(t *testing.T) {
+		fileName := "file.txt"
+		utils.SetDownloadRetries(0)
+		defer utils.SetDownloadRetries(3)
+
+		requestCount := 0
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					requestCount++
+					w.WriteHeader(http.StatusInternalServerError)
+				},
+			),
+		)
+		defer server.Close()
+
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, true, true, true, false, 0)
+		assert.Equal(errs.ErrBadRequest, errors.Unwrap(err))
+		assert.Equal(1, requestCount)
+	})
+
+	t.Run("test download is not retried on client errors", func(t *testing.T) {
+		fileName := "file.txt"
+		requestCount := 0
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					requestCount++
+					w.WriteHeader(http.StatusForbidden)
+				},
+			),
+		)
+		defer server.Close()
+
+		_, err := utils.DownloadFile(server.URL+"/"+fileName, true, true, true, false, 0)
+		assert.Equal(errs.ErrBadRequest, errors.Unwrap(err))
+		assert.Equal(1, requestCount)
+	})
+
+	t.Run("test download retries on connection errors", func(t *testing.T) {
+		fileName := "file.txt"
+		server := httptest.NewServer(http.NotFoundHandler())
+		url := server.URL + "/" + fileName
+		server.Close()
+
+		var output strings.Builder
+		log.SetOutput(&output)
+		log.SetLevel(log.DebugLevel)
+		utils.SetEncodedProgress(true)
+		defer func() {
+			log.SetOutput(os.Stderr)
+			log.SetLevel(log.InfoLevel)
+			utils.SetEncodedProgress(false)
+		}()
+
+		_, err := utils.DownloadFile(url, true, true, true, false, 0)
+		assert.Equal(errs.ErrFailedDownloadingFile, errors.Unwrap(err))
+		assert.Contains(output.String(), "retry 3 of 3")
+		assert.Contains(output.String(), `[R1:F"file.txt"]`)
+		assert.Contains(output.String(), `[R3:F"file.txt"]`)
+	})
+
+	t.Run("test download only if modified", func(t *testing.T) {
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		content := "all good"
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("ETag", fmt.Sprintf("%q", content))
+					if r.Header.Get("If-None-Match") == fmt.Sprintf("%q", content) {
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+					fmt.Fprint(w, content)
+				},
+			),
+		)
+		defer server.Close()
+
+		url := server.URL + "/" + fileName
+		var validators utils.Validators
+		_, err := utils.DownloadFileIfModified(url, false, &validators, true, true, false, 0)
+		assert.Nil(err)
+		assert.Equal(`"all good"`, validators.ETag)
+		os.Remove(fileName)
+
+		_, err = utils.DownloadFileIfModified(url, false, &validators, true, true, false, 0)
+		assert.Equal(errs.ErrNotModified, err)
+		assert.False(utils.FileExists(fileName))
+
+		content = "all changed"
+		_, err = utils.DownloadFileIfModified(url, false, &validators, true, true, false, 0)
+		assert.Nil(err)
+		assert.Equal(`"all changed"`, validators.ETag)
+		bytes, err := os.ReadFile(fileName)
+		assert.Nil(err)
+		assert.Equal("all changed", string(bytes))
+	})
+
+	t.Run("test download in offline mode", func(t *testing.T) {
+		fileName := "file.txt"
+		defer os.Remove(fileName)
+		requestCount := 0
+		server := httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					requestCount++
+					fmt.Fprint(w, "all good")
+				},
+			),
+		)
+		defer server.Close()
+
+		utils.SetOffline(true)
+		defer utils.SetOffline(false)
+
+		url := server.URL + "/" + fileName
+		_, err := utils.DownloadFile(url, true, true, true, false, 0)
+		assert.Equal(errs.ErrOfflineMode, errors.Unwrap(err))
+		assert.Contains(err.Error(), fileName)
+		assert.Equal(errs.ErrOffline, errors.Unwrap(utils.CheckConnection(server.URL, 0)))
+
+		// Files already in the cache are still available
+		assert.Nil(os.WriteFile(fileName, []byte("cached"), 0600))
+		_, err = utils.DownloadFile(url, true, true, true, false, 0)
+		assert.Nil(err)
+		assert.Equal(0, requestCount)
+	})
+
 	t.Run("test download with insecure skip verify", func(t *testing.T) {
 		fileName := "file.txt"
 		defer os.Remove(fileName)
@@ -745,6 +1103,18 @@ func TestGetListFiles(t *testing.T) {
 	})
 }
 
+func TestProcessExists(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(utils.ProcessExists(os.Getpid()))
+	assert.False(utils.ProcessExists(0))
+
+	// Run the test binary without any test, so that its pid belongs to a process gone by now
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	assert.Nil(cmd.Run())
+	assert.False(utils.ProcessExists(cmd.ProcessState.Pid()))
+}
+
 func init() {
 	logLevel := log.InfoLevel
 	if os.Getenv("LOG_LEVEL") == "debug" {
@@ -35,10 +35,13 @@ type PdscXML struct {
 
 // ReleaseTag maps the <release> tag of a PDSC file.
 type ReleaseTag struct {
-	XMLName xml.Name `xml:"release"`
-	Version string   `xml:"version,attr"`
-	Date    string   `xml:"Date,attr"`
-	URL     string   `xml:"url,attr"`
+	XMLName     xml.Name `xml:"release"`
+	Version     string   `xml:"version,attr"`
+	Date        string   `xml:"date,attr"`
+	URL         string   `xml:"url,attr"`
+	Deprecated  string   `xml:"deprecated,attr,omitempty"`
+	Replacement string   `xml:"replacement,attr,omitempty"`
+	Description string   `xml:",chardata"`
 }
 
 // PackagesTag only has one possible child, which is <package>
@@ -106,6 +106,16 @@ func TestPdscXML(t *testing.T) {
 		assert.Equal("1.2.3", pdsc.LatestVersion())
 	})
 
+	t.Run("test reading release attributes", func(t *testing.T) {
+		pdsc := xml.NewPdscXML("../../testdata/integration/1.2.3/TheVendor.PublicLocalPack.pdsc")
+		assert.Nil(pdsc.Read())
+		releaseTag := pdsc.FindReleaseTagByVersion("1.2.3")
+		assert.NotNil(releaseTag)
+		assert.Equal("2016-09-15", releaseTag.Date)
+		assert.Equal("New release.", releaseTag.Description)
+		assert.Equal("", releaseTag.Deprecated)
+	})
+
 	t.Run("test finding release tag", func(t *testing.T) {
 		pdsc := xml.NewPdscXML("../../testdata/devpack/1.2.3/TheVendor.DevPack.pdsc")
 		assert.Nil(pdsc.Read())
@@ -5,8 +5,13 @@ package xml
 
 import (
 	"encoding/xml"
+	"fmt"
+	"maps"
+	"net/url"
 	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,6 +24,14 @@ var (
 	PdscIndexNotFound = -1
 )
 
+var (
+	// semanticVersionPattern is the SemanticVersionType of PackIndex.xsd
+	semanticVersionPattern = regexp.MustCompile(`^(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-(0|[1-9][0-9]*|[0-9]*[a-zA-Z-][0-9a-zA-Z-]*)(\.(0|[1-9][0-9]*|[0-9]*[a-zA-Z-][0-9a-zA-Z-]*))*)?(\+[0-9a-zA-Z-]+(\.[0-9a-zA-Z-]+)*)?$`)
+
+	// restrictedStringPattern is the RestrictedString of PackIndex.xsd
+	restrictedStringPattern = regexp.MustCompile(`^\S(.*\S)?$`)
+)
+
 // PidxXML maps the PIDX file format.
 // Ref: https://github.com/ARM-software/CMSIS_5/blob/develop/CMSIS/Utilities/PackIndex.xsd
 type PidxXML struct {
@@ -467,9 +480,9 @@ func (p *PidxXML) Read() error {
 func (p *PidxXML) Write() error {
 	log.Debugf("Writing pidx file to %q", p.fileName)
 
-	// Use p.pdscList as the main source of pdsc tags
-	for _, pdscs := range p.pdscList {
-		p.Pindex.Pdscs = append(p.Pindex.Pdscs, pdscs...)
+	// Use p.pdscList as the main source of pdsc tags, sorted to keep the file stable
+	for _, key := range slices.Sorted(maps.Keys(p.pdscList)) {
+		p.Pindex.Pdscs = append(p.Pindex.Pdscs, p.pdscList[key]...)
 	}
 
 	err := utils.WriteXML(p.fileName, p)
@@ -480,6 +493,70 @@ func (p *PidxXML) Write() error {
 	return err
 }
 
+// Validate checks the index and its pdsc tags against the types of PackIndex.xsd,
+// returning the first violation found wrapped in errs.ErrInvalidPackIndex. Urls have
+// to be absolute as well, which xs:anyURI does not require.
+func (p *PidxXML) Validate() error {
+	invalid := func(what, value string) error {
+		return fmt.Errorf("%s %q: %w", what, value, errs.ErrInvalidPackIndex)
+	}
+	if !semanticVersionPattern.MatchString(p.SchemaVersion) {
+		return invalid("schemaVersion", p.SchemaVersion)
+	}
+	if !restrictedStringPattern.MatchString(p.Vendor) {
+		return invalid("vendor", p.Vendor)
+	}
+	if !isAbsoluteURL(p.URL) {
+		return invalid("url", p.URL)
+	}
+	if p.TimeStamp != "" {
+		if _, err := time.Parse(time.RFC3339Nano, p.TimeStamp); err != nil {
+			return invalid("timestamp", p.TimeStamp)
+		}
+	}
+
+	tags := p.ListPdscTags()
+	if len(tags) == 0 {
+		return fmt.Errorf("no pdsc tag: %w", errs.ErrInvalidPackIndex)
+	}
+	for _, tag := range tags {
+		if !isAbsoluteURL(tag.URL) {
+			return invalid("url of "+tag.Key(), tag.URL)
+		}
+		if !restrictedStringPattern.MatchString(tag.Vendor) {
+			return invalid("vendor of "+tag.Key(), tag.Vendor)
+		}
+		if !restrictedStringPattern.MatchString(tag.Name) {
+			return invalid("name of "+tag.Key(), tag.Name)
+		}
+		if !semanticVersionPattern.MatchString(tag.Version) {
+			return invalid("version of "+tag.Key(), tag.Version)
+		}
+		if tag.Deprecated != "" {
+			if _, err := time.Parse("2006-01-02", tag.Deprecated); err != nil {
+				return invalid("deprecated of "+tag.Key(), tag.Deprecated)
+			}
+		}
+		if tag.Replacement != "" && !restrictedStringPattern.MatchString(tag.Replacement) {
+			return invalid("replacement of "+tag.Key(), tag.Replacement)
+		}
+	}
+	return nil
+}
+
+// isAbsoluteURL tells whether rawURL has a scheme and a host, or is a file:// URL, e.g. of a local registry.
+// Unlike xs:anyURI, relative references are rejected: the files of an index get downloaded from its urls.
+func isAbsoluteURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() {
+		return false
+	}
+	if u.Scheme == "file" {
+		return u.Path != ""
+	}
+	return u.Host != ""
+}
+
 // Key generates a unique key for the PdscTag by concatenating the Vendor, Name, and Version fields
 // with periods ('.') as separators. The resulting key is in the format "Vendor.Name.Version".
 func (p *PdscTag) Key() string {
@@ -4,8 +4,13 @@
 package xml_test
 
 import (
+	stdxml "encoding/xml"
+	"errors"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -16,6 +21,37 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// xsdTypeMatches tells whether value matches one of the patterns of the simple type typeName of PackIndex.xsd
+func xsdTypeMatches(t *testing.T, typeName, value string) bool {
+	contents, err := os.ReadFile(filepath.Join("..", "..", "testdata", "PackIndex.xsd"))
+	assert.Nil(t, err)
+	var schema struct {
+		SimpleTypes []struct {
+			Name     string `xml:"name,attr"`
+			Patterns []struct {
+				Value string `xml:"value,attr"`
+			} `xml:"restriction>pattern"`
+		} `xml:"simpleType"`
+	}
+	assert.Nil(t, stdxml.Unmarshal(contents, &schema))
+
+	for _, simpleType := range schema.SimpleTypes {
+		if simpleType.Name != typeName {
+			continue
+		}
+		assert.NotEmpty(t, simpleType.Patterns)
+		for _, pattern := range simpleType.Patterns {
+			// XSD patterns match whole values
+			if regexp.MustCompile(`^(?:` + pattern.Value + `)$`).MatchString(value) {
+				return true
+			}
+		}
+		return false
+	}
+	assert.Fail(t, "type not found in PackIndex.xsd", typeName)
+	return false
+}
+
 func TestPdscTag(t *testing.T) {
 
 	assert := assert.New(t)
@@ -503,4 +539,132 @@ func TestPidxXML(t *testing.T) {
 		assert.Equal(foundTags[0], pdscTag2)
 
 	})
+
+	t.Run("test validating a PIDX file against PackIndex.xsd", func(t *testing.T) {
+		pidx := xml.NewPidxXML("validate.pidx", false)
+		pidx.SchemaVersion = "1.1.0"
+		pidx.Vendor = "TheVendor"
+		pidx.URL = "http://vendor.com/"
+		pidx.TimeStamp = time.Now().Format(time.RFC3339Nano)
+
+		// At least one pdsc tag is required
+		assert.True(errors.Is(pidx.Validate(), errs.ErrInvalidPackIndex))
+
+		pdscTag := xml.PdscTag{
+			Vendor:      "TheVendor",
+			URL:         "http://vendor.com/",
+			Name:        "ThePack",
+			Version:     "1.2.3-rc.1",
+			Deprecated:  "2025-06-30",
+			Replacement: "TheVendor.NewPack",
+		}
+		assert.Nil(pidx.AddPdsc(pdscTag))
+		assert.Nil(pidx.Validate())
+
+		pidx.TimeStamp = "yesterday"
+		assert.True(errors.Is(pidx.Validate(), errs.ErrInvalidPackIndex))
+		pidx.TimeStamp = ""
+		assert.Nil(pidx.Validate())
+		pidx.Vendor = " TheVendor"
+		assert.True(errors.Is(pidx.Validate(), errs.ErrInvalidPackIndex))
+		pidx.Vendor = "TheVendor"
+
+		assert.Nil(pidx.RemovePdsc(pdscTag))
+		pdscTag.Version = "1.2"
+		assert.Nil(pidx.AddPdsc(pdscTag))
+		assert.True(errors.Is(pidx.Validate(), errs.ErrInvalidPackIndex))
+
+		assert.Nil(pidx.RemovePdsc(pdscTag))
+		pdscTag.Version = "1.2.3"
+		pdscTag.Deprecated = "30.06.2025"
+		assert.Nil(pidx.AddPdsc(pdscTag))
+		assert.True(errors.Is(pidx.Validate(), errs.ErrInvalidPackIndex))
+	})
+
+	t.Run("test validating the urls of a PIDX file", func(t *testing.T) {
+		pidx := xml.NewPidxXML("validate.pidx", false)
+		pidx.SchemaVersion = "1.1.0"
+		pidx.Vendor = "TheVendor"
+		validates := func(indexURL, pdscURL string) bool {
+			pidx.Clear()
+			pidx.URL = indexURL
+			assert.Nil(pidx.AddPdsc(xml.PdscTag{Vendor: "TheVendor", Name: "ThePack", Version: "1.2.3", URL: pdscURL}))
+			return pidx.Validate() == nil
+		}
+
+		validURLs := []string{"http://vendor.com/", "https://vendor.com:8443/packs/", "file:///path/to/registry/"}
+		for _, validURL := range validURLs {
+			assert.True(validates(validURL, "http://vendor.com/"), "index url %q", validURL)
+			assert.True(validates("http://vendor.com/", validURL), "pdsc url %q", validURL)
+		}
+
+		// xs:anyURI accepts relative references, which cannot be downloaded from
+		invalidURLs := []string{"", "vendor.com/packs/", "/packs/", "packs", "http://", "http:///packs/", "file://", "http://vendor.com/%zz"}
+		for _, invalidURL := range invalidURLs {
+			assert.False(validates(invalidURL, "http://vendor.com/"), "index url %q", invalidURL)
+			assert.False(validates("http://vendor.com/", invalidURL), "pdsc url %q", invalidURL)
+		}
+	})
+
+	t.Run("test validating a PIDX file agrees with the types of PackIndex.xsd", func(t *testing.T) {
+		pidx := xml.NewPidxXML("validate.pidx", false)
+		pidx.SchemaVersion = "1.1.0"
+		pidx.Vendor = "TheVendor"
+		pidx.URL = "http://vendor.com/"
+		validates := func(tag xml.PdscTag) bool {
+			pidx.Clear()
+			assert.Nil(pidx.AddPdsc(tag))
+			return pidx.Validate() == nil
+		}
+
+		versions := []string{"1.2.3", "0.0.0", "1.2", "01.2.3", "1.2.3-rc.1", "1.2.3-rc1", "1.2.3-01", "1.2.3-", "1.2.3+meta", "1.2.3-rc.1+build.5", "v1.2.3", " 1.2.3"}
+		for _, version := range versions {
+			tag := xml.PdscTag{Vendor: "TheVendor", Name: "ThePack", Version: version, URL: "http://vendor.com/"}
+			assert.Equal(xsdTypeMatches(t, "SemanticVersionType", version), validates(tag), "version %q", version)
+		}
+
+		names := []string{"ThePack", "The_Pack-2", "The.Pack", " ThePack", "ThePack ", "The Pack", ""}
+		for _, name := range names {
+			tag := xml.PdscTag{Vendor: "TheVendor", Name: name, Version: "1.2.3", URL: "http://vendor.com/"}
+			assert.Equal(xsdTypeMatches(t, "RestrictedString", name), validates(tag), "name %q", name)
+		}
+	})
+
+	t.Run("test validating a PIDX file agrees with xmllint and PackIndex.xsd", func(t *testing.T) {
+		xmllint, err := exec.LookPath("xmllint")
+		if err != nil {
+			t.Skip("xmllint not found")
+		}
+		schema := filepath.Join("..", "..", "testdata", "PackIndex.xsd")
+
+		pidx := xml.NewPidxXML(filepath.Join(t.TempDir(), "validate.pidx"), false)
+		pidx.SchemaVersion = "1.1.0"
+		pidx.Vendor = "TheVendor"
+		pidx.URL = "http://vendor.com/"
+		pidx.TimeStamp = time.Now().Format(time.RFC3339Nano)
+		agrees := func(tag xml.PdscTag) {
+			pidx.Clear()
+			assert.Nil(pidx.AddPdsc(tag))
+			assert.Nil(pidx.Write())
+			output, err := exec.Command(xmllint, "--noout", "--schema", schema, pidx.GetFileName()).CombinedOutput() // #nosec
+			assert.Equal(err == nil, pidx.Validate() == nil, "%+v: %s", tag, output)
+		}
+
+		tag := xml.PdscTag{Vendor: "TheVendor", Name: "ThePack", Version: "1.2.3", URL: "http://vendor.com/"}
+		agrees(tag)
+		for _, version := range []string{"0.0.0", "1.2", "01.2.3", "1.2.3-rc.1", "1.2.3-", "1.2.3+meta", "v1.2.3"} {
+			tag.Version = version
+			agrees(tag)
+		}
+		tag.Version = "1.2.3"
+		for _, name := range []string{"The_Pack-2", "The.Pack", " ThePack", "The Pack"} {
+			tag.Name = name
+			agrees(tag)
+		}
+		tag.Name = "ThePack"
+		for _, deprecated := range []string{"2025-06-30", "30.06.2025"} {
+			tag.Deprecated = deprecated
+			agrees(tag)
+		}
+	})
 }
@@ -0,0 +1,71 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package xml
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// VidxXML maps the VIDX file format, listing the index files of vendors along with
+// PDSC tags of its own.
+// Ref: https://github.com/ARM-software/CMSIS_5/blob/develop/CMSIS/Utilities/PackIndex.xsd
+type VidxXML struct {
+	XMLName       xml.Name `xml:"index"`
+	SchemaVersion string   `xml:"schemaVersion,attr"`
+	Vendor        string   `xml:"vendor"`
+	URL           string   `xml:"url"`
+	TimeStamp     string   `xml:"timestamp,omitempty"`
+
+	Pindex struct {
+		XMLName xml.Name  `xml:"pindex"`
+		Pdscs   []PdscTag `xml:"pdsc"`
+	} `xml:"pindex"`
+
+	Vindex struct {
+		XMLName xml.Name  `xml:"vindex"`
+		Pidxs   []PidxTag `xml:"pidx"`
+	} `xml:"vindex"`
+
+	fileName string
+}
+
+// PidxTag maps a <pidx> tag that goes in VIDX files.
+type PidxTag struct {
+	XMLName xml.Name `xml:"pidx"`
+	URL     string   `xml:"url,attr"`
+	Vendor  string   `xml:"vendor,attr"`
+	Date    string   `xml:"date,attr,omitempty"`
+}
+
+// NewVidxXML initializes a new VidxXML object with the given file name.
+func NewVidxXML(fileName string) *VidxXML {
+	log.Debugf("Initializing VidxXML object for %q", fileName)
+	v := new(VidxXML)
+	v.fileName = fileName
+	return v
+}
+
+// GetFileName returns the file name associated with the VidxXML instance.
+func (v *VidxXML) GetFileName() string {
+	return v.fileName
+}
+
+// Read reads the VidxXML from the file specified by v.fileName.
+func (v *VidxXML) Read() error {
+	log.Debugf("Reading vidx from file %q", v.fileName)
+	return utils.ReadXML(v.fileName, v)
+}
+
+// PidxURL returns the URL of the vendor index file. The url attribute is either the URL
+// of the folder holding Vendor.pidx, as specified, or the URL of the file itself.
+func (p *PidxTag) PidxURL() string {
+	if strings.HasSuffix(strings.ToLower(p.URL), utils.PidxExtension) {
+		return p.URL
+	}
+	return strings.TrimSuffix(p.URL, "/") + "/" + p.Vendor + utils.PidxExtension
+}
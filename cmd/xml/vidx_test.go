@@ -0,0 +1,53 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright Contributors to the cpackget project. */
+
+package xml_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-cmsis-pack/cpackget/cmd/xml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVidxXML(t *testing.T) {
+
+	assert := assert.New(t)
+
+	t.Run("test reading a vidx file", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "vendors.vidx")
+		assert.Nil(os.WriteFile(fileName, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<index schemaVersion="1.1.0">
+  <vendor>Vendors</vendor>
+  <url>https://vendors.com/</url>
+  <timestamp>2024-01-01T00:00:00Z</timestamp>
+  <vindex>
+    <pidx vendor="TheVendor" url="https://thevendor.com/packs/"/>
+    <pidx vendor="OtherVendor" url="https://othervendor.com/index/OtherVendor.pidx" date="2024-01-01"/>
+  </vindex>
+  <pindex>
+    <pdsc vendor="InlineVendor" name="ThePack" version="1.2.3" url="https://inlinevendor.com/"/>
+  </pindex>
+</index>
+`), 0600))
+
+		vidx := xml.NewVidxXML(fileName)
+		assert.Equal(fileName, vidx.GetFileName())
+		assert.Nil(vidx.Read())
+		assert.Equal("Vendors", vidx.Vendor)
+		assert.Equal("https://vendors.com/", vidx.URL)
+		assert.Len(vidx.Vindex.Pidxs, 2)
+		assert.Equal("https://thevendor.com/packs/TheVendor.pidx", vidx.Vindex.Pidxs[0].PidxURL())
+		assert.Equal("https://othervendor.com/index/OtherVendor.pidx", vidx.Vindex.Pidxs[1].PidxURL())
+		assert.Equal("2024-01-01", vidx.Vindex.Pidxs[1].Date)
+		assert.Len(vidx.Pindex.Pdscs, 1)
+		assert.Equal("InlineVendor.ThePack.1.2.3", vidx.Pindex.Pdscs[0].Key())
+	})
+
+	t.Run("test reading a missing vidx file", func(t *testing.T) {
+		vidx := xml.NewVidxXML(filepath.Join(t.TempDir(), "missing.vidx"))
+		assert.NotNil(vidx.Read())
+	})
+}